@@ -0,0 +1,68 @@
+//go:build windows
+
+// Description: This file contains running the local HTTP/JSON control API of the tray application.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"windows-ram-cleaner/internal/api"
+	"windows-ram-cleaner/internal/config"
+	"windows-ram-cleaner/internal/history"
+	"windows-ram-cleaner/internal/inventory"
+	"windows-ram-cleaner/internal/memory"
+	"windows-ram-cleaner/internal/report"
+	"windows-ram-cleaner/internal/tray"
+	windowsapi "windows-ram-cleaner/internal/windows_api"
+)
+
+// apiBackend implements api.Backend with the clean service.
+type apiBackend struct{}
+
+func (apiBackend) Memory() (memory.Snapshot, error) {
+	return tray.MemoryProvider.Snapshot()
+}
+
+func (apiBackend) Busy() bool {
+	return cleanService.Busy()
+}
+
+func (apiBackend) CleanStandbyList(ctx context.Context) (*report.CleanReport, error) {
+	r, err := cleanService.CleanStandbyList(ctx, history.TriggerAPI)
+	tray.UpdateTooltip()
+	return r, err
+}
+
+func (apiBackend) CleanRAM(ctx context.Context, deep bool) (*report.CleanReport, error) {
+	r, err := cleanService.CleanRAM(ctx, history.TriggerAPI, deep)
+	tray.UpdateTooltip()
+	return r, err
+}
+
+func (apiBackend) History() ([]history.Entry, error) {
+	if cleanService.History() == nil {
+		return nil, nil
+	}
+	return cleanService.History().Entries()
+}
+
+func (apiBackend) Processes() ([]inventory.Process, error) {
+	return windowsapi.ProcessInventory()
+}
+
+// apiServer serves the API while it is enabled.
+var apiServer = optionalServer{name: "API"}
+
+// applyAPIConfig starts, restarts or stops the API server to match cfg.
+func applyAPIConfig(cfg config.APIConfig) error {
+	return apiServer.apply(cfg, cfg.Enabled, cfg.Address, func() http.Handler {
+		return api.Handler(apiBackend{}, cfg.Token)
+	})
+}
+
+// showAPIError reports that the API server could not be started.
+func showAPIError(err error) {
+	tray.Notifier.Error("Error starting API", fmt.Sprintf("Can't start the HTTP API, err: %s", err.Error()))
+}
@@ -0,0 +1,94 @@
+//go:build windows
+
+// Description: This file contains the WinAPI backends of the clean service and the command-line mode.
+
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"windows-ram-cleaner/internal/config"
+	"windows-ram-cleaner/internal/history"
+	"windows-ram-cleaner/internal/inventory"
+	"windows-ram-cleaner/internal/memlist"
+	"windows-ram-cleaner/internal/memory"
+	"windows-ram-cleaner/internal/report"
+	"windows-ram-cleaner/internal/service"
+	winstartup "windows-ram-cleaner/internal/win_startup"
+	windowsapi "windows-ram-cleaner/internal/windows_api"
+)
+
+// cleanService runs and records all cleans of this process.
+var cleanService *service.Service
+
+// newService creates the clean service recording into the history file next to the configuration.
+func newService() *service.Service {
+	backend := service.BackendFuncs{
+		Standby: windowsapi.CleanStandbyListContext,
+		RAM: func(ctx context.Context, deep bool) (*report.CleanReport, error) {
+			return windowsapi.CleanRAMContext(ctx, windowsapi.CleanOptions{IgnoreCritical: deep})
+		},
+		MemList:  windowsapi.RunMemoryListCommand,
+		Targeted: windowsapi.TrimTargetContext,
+	}
+
+	var store *history.Store
+	if dir, err := config.Dir(); err == nil {
+		store = history.Open(filepath.Join(dir, history.FileName), currentConfig.Load().History.Retention())
+	}
+	return service.New(backend, store)
+}
+
+// cliBackend implements cli.Backend with the clean service and the winstartup package.
+type cliBackend struct {
+	ctx      context.Context // Canceled by Ctrl+C to stop a running clean
+	provider memory.Provider
+	service  *service.Service
+}
+
+func (cliBackend) IsElevated() bool {
+	return windowsapi.IsRunAsAdmin()
+}
+
+func (b cliBackend) CleanStandbyList() (*report.CleanReport, error) {
+	return b.service.CleanStandbyList(b.ctx, history.TriggerCLI)
+}
+
+func (b cliBackend) CleanRAM(deep bool) (*report.CleanReport, error) {
+	return b.service.CleanRAM(b.ctx, history.TriggerCLI, deep)
+}
+
+func (b cliBackend) MemoryList(command memlist.Command) (*report.CleanReport, error) {
+	return b.service.MemoryList(b.ctx, history.TriggerCLI, command)
+}
+
+func (b cliBackend) Trim(target inventory.Target) (*report.CleanReport, error) {
+	return b.service.Trim(b.ctx, history.TriggerCLI, target)
+}
+
+func (b cliBackend) Memory() (memory.Snapshot, error) {
+	return b.provider.Snapshot()
+}
+
+func (cliBackend) AddStartup() error {
+	return winstartup.CreateStartupTask()
+}
+
+func (cliBackend) RemoveStartup() error {
+	return winstartup.DeleteStartupTask()
+}
+
+func (cliBackend) StartupStatus() (bool, error) {
+	return winstartup.IsStartupTaskExists()
+}
+
+func (b cliBackend) History() ([]history.Entry, error) {
+	if b.service.History() == nil {
+		return nil, nil
+	}
+	return b.service.History().Entries()
+}
+
+func (cliBackend) Processes() ([]inventory.Process, error) {
+	return windowsapi.ProcessInventory()
+}
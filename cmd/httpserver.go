@@ -0,0 +1,57 @@
+//go:build windows
+
+// Description: This file contains the optional HTTP servers restarted when their configuration changes.
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// optionalServer is an HTTP server that can be enabled, moved or stopped by the configuration.
+type optionalServer struct {
+	name string // Shown in the log and the errors
+
+	mu     sync.Mutex
+	server *http.Server // Nil while the server is stopped
+	cfg    any          // The configuration the server runs with
+}
+
+// apply starts, restarts or stops the server to match cfg, a comparable configuration.
+// The handler is only created when the server starts.
+func (s *optionalServer) apply(cfg any, enabled bool, addr string, handler func() http.Handler) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.server != nil && cfg == s.cfg {
+		return nil
+	}
+
+	if s.server != nil {
+		s.server.Close()
+		s.server = nil
+		slog.Info(s.name + " server stopped")
+	}
+	if !enabled {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to start the %s server: %v", s.name, err)
+	}
+	server := &http.Server{Handler: handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != http.ErrServerClosed {
+			slog.Error(s.name+" server failed", "err", err)
+		}
+	}()
+	s.server = server
+	s.cfg = cfg
+	slog.Info(s.name+" server started", "address", listener.Addr())
+	return nil
+}
@@ -0,0 +1,86 @@
+//go:build windows
+
+// Description: This file contains the single instance handling: a second tray application shows a notification
+// in the running one and exits, and clean commands run in the running tray application.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"windows-ram-cleaner/internal/cli"
+	"windows-ram-cleaner/internal/instance"
+	"windows-ram-cleaner/internal/tray"
+)
+
+// instanceName names the lock and the pipe of the running tray application.
+const instanceName = "WindowsRAMCleaner"
+
+// instanceListener receives the commands of other instances, nil until the tray application serves them.
+var instanceListener instance.Listener
+
+// forwardCommand runs args in the running tray application, if any. It reports whether the command
+// was forwarded and its exit code. Ctrl+C cancels the command in the tray application.
+func forwardCommand(args []string) (bool, int) {
+	conn, err := instance.Dial(instanceName)
+	if err != nil {
+		return false, 0
+	}
+	slog.Info("forwarding command to the running instance", "args", args)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	code, err := instance.Forward(ctx, conn, args, os.Stdout, os.Stderr)
+	if errors.Is(err, context.Canceled) {
+		fmt.Fprintln(os.Stderr, "error: canceled")
+		return true, cli.ExitError
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: the running instance didn't answer: %v\n", err)
+		return true, cli.ExitError
+	}
+	return true, code
+}
+
+// serveInstances runs the commands of other instances until stopInstances is called.
+func serveInstances() {
+	l, err := instance.Listen(instanceName)
+	if err != nil {
+		slog.Warn("can't receive commands of other instances", "err", err)
+		return
+	}
+	instanceListener = l
+	go func() {
+		err := instance.Serve(l, handleInstanceCommand)
+		slog.Debug("stopped receiving commands of other instances", "err", err)
+	}()
+}
+
+// stopInstances stops receiving the commands of other instances.
+func stopInstances() {
+	if instanceListener != nil {
+		instanceListener.Close()
+	}
+}
+
+// handleInstanceCommand runs a command received from another instance until it finishes or the
+// other instance goes away.
+func handleInstanceCommand(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if cli.IsTrayCommand(args) {
+		tray.Notifier.Info("Already running", "Windows RAM Cleaner is already running in the system tray.")
+		return cli.ExitOK
+	}
+	if !cli.Forwardable(args) {
+		fmt.Fprintf(stderr, "%s can't run in the running instance\n", args[0])
+		return cli.ExitUsage
+	}
+
+	slog.Info("running command of another instance", "args", args)
+	code := cli.Run(args, stdout, stderr, cliBackend{ctx: ctx, provider: tray.MemoryProvider, service: cleanService})
+	tray.UpdateTooltip()
+	return code
+}
@@ -0,0 +1,43 @@
+//go:build windows
+
+// Description: This file contains enforcing the working set limits of the configuration.
+
+package main
+
+import (
+	"fmt"
+	"time"
+	"windows-ram-cleaner/internal/tray"
+	windowsapi "windows-ram-cleaner/internal/windows_api"
+	"windows-ram-cleaner/internal/wslimit"
+)
+
+// limitManager applies the working set limits and restores the original limits on exit.
+var limitManager = wslimit.NewManager(windowsapi.WorkingSetLimits{})
+
+// enforceLimits applies the working set limits every interval until stopChan is closed.
+// New processes get their limits within one interval.
+func enforceLimits(stopChan chan struct{}) {
+	for {
+		if err := limitManager.Apply(); err != nil {
+			// Every process is reported once, a dialog would block the loop.
+			tray.Notifier.Warning("Can't apply working set limits", err.Error())
+		}
+
+		select {
+		case <-stopChan:
+			return
+		case <-time.After(currentConfig.Load().Limits.Interval.Std()):
+		}
+	}
+}
+
+// restoreLimits gives the limited processes their original working set limits back.
+func restoreLimits() {
+	if err := limitManager.Restore(); err != nil {
+		tray.Notifier.Error(
+			"Error restoring working set limits",
+			fmt.Sprintf("Can't restore working set limits, err: %s", err.Error()),
+		)
+	}
+}
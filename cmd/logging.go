@@ -0,0 +1,50 @@
+//go:build windows
+
+// Description: This file contains setting up the log file and applying the log configuration.
+
+package main
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"windows-ram-cleaner/internal/applog"
+	"windows-ram-cleaner/internal/config"
+	"windows-ram-cleaner/internal/tray"
+)
+
+// logLevel is the level of the application logger, changed when the configuration is applied.
+var logLevel slog.LevelVar
+
+// logFile is the rotated log file, nil if it could not be opened.
+var logFile *applog.File
+
+// setupLogging makes the log file called name in the application directory the default logger, rotated
+// with the default limits until the configuration is applied. If the file can't be opened,
+// records go to stderr, which is only visible for command-line commands.
+func setupLogging(name string) error {
+	slog.SetDefault(applog.New(os.Stderr, &logLevel))
+
+	dir, err := config.Dir()
+	if err != nil {
+		return err
+	}
+	limits := config.Default().Log
+	file, err := applog.OpenFile(filepath.Join(dir, name), limits.MaxSize(), limits.MaxFiles)
+	if err != nil {
+		return err
+	}
+
+	logFile = file
+	tray.LogPath = file.Path()
+	slog.SetDefault(applog.New(file, &logLevel))
+	return nil
+}
+
+// applyLogConfig applies the level and the rotation limits of the log file.
+func applyLogConfig(cfg config.LogConfig) {
+	logLevel.Set(cfg.SlogLevel())
+	if logFile != nil {
+		logFile.SetLimits(cfg.MaxSize(), cfg.MaxFiles)
+	}
+}
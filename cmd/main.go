@@ -1,10 +1,24 @@
+//go:build windows
+
 // cmd/main.go
 
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
 	"runtime"
 	"time"
+	"windows-ram-cleaner/internal/applog"
+	"windows-ram-cleaner/internal/autoclean"
+	"windows-ram-cleaner/internal/cli"
+	"windows-ram-cleaner/internal/history"
+	"windows-ram-cleaner/internal/instance"
+	"windows-ram-cleaner/internal/notify"
 	"windows-ram-cleaner/internal/tray"
 	windowsapi "windows-ram-cleaner/internal/windows_api"
 
@@ -17,37 +31,158 @@ var stopChan = make(chan struct{})
 
 //go:generate goversioninfo -icon=exe_icon.ico -manifest=app.manifest
 func main() {
+	// Run headless commands without the system tray
+	if args := os.Args[1:]; !cli.IsTrayCommand(args) {
+		windowsapi.AttachParentConsole()
+		// Headless commands have their own log, the tray application rotates app.log
+		if err := setupLogging(applog.CommandFileName); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: can't open log file: %v\n", err)
+		}
+		// Let the running tray application clean, it has the rights and serializes the cleans
+		if cli.Forwardable(args) {
+			if forwarded, code := forwardCommand(args); forwarded {
+				os.Exit(code)
+			}
+		}
+		if _, err := loadConfig(applyCommandConfig); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v, using defaults\n", err)
+		}
+		cleanService = newService()
+		cleanService.OnHistoryError = func(err error) {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		code := cli.Run(args, os.Stdout, os.Stderr, cliBackend{ctx: ctx, provider: tray.MemoryProvider, service: cleanService})
+		stop()
+		os.Exit(code)
+	}
+
+	logErr := setupLogging(applog.FileName)
+	slog.Info("starting", "pid", os.Getpid(), "elevated", windowsapi.IsRunAsAdmin())
+	if logErr != nil {
+		tray.Notifier.Warning("Can't open log file", logErr.Error())
+	}
+
+	// Only one tray application runs, a second one tells the running one and exits
+	unlock, err := instance.Lock(instanceName)
+	if errors.Is(err, instance.ErrRunning) {
+		slog.Info("another instance is running")
+		forwardCommand([]string{cli.TrayCommand})
+		return
+	}
+	if err != nil {
+		slog.Warn("can't check for a running instance", "err", err)
+		unlock = func() {}
+	}
+
 	// Request admin rights if not already granted
 	if !windowsapi.IsRunAsAdmin() {
+		// The elevated process takes the lock
+		unlock()
 		windowsapi.RequestAdminRights()
 		return
 	}
+	defer unlock()
 
 	// Wait for the taskbar to be visible before running the system tray
 	for !windowsapi.IsTaskbarVisible() {
 		time.Sleep(1 * time.Second)
 	}
 
-	go autoUpdateTooltip(stopChan)
+	configPath, err := loadConfig(applyConfig)
+	if err != nil {
+		tray.Notifier.Error(
+			"Error loading configuration",
+			fmt.Sprintf("Can't load configuration, using defaults, err: %s", err.Error()),
+		)
+	}
+	if configPath != "" {
+		go watchConfig(configPath, stopChan)
+	}
+
+	cleanService = newService()
+	cleanService.OnHistoryError = func(err error) {
+		tray.Notifier.Notify(notify.Notification{
+			Severity: notify.Error,
+			Title:    "Error saving clean history",
+			Message:  err.Error(),
+			Key:      "history",
+		})
+	}
+	cleanService.OnBusyChange = tray.SetBusy
+	cleanService.OnClean = observeClean
+	tray.Service = cleanService
+	tray.Scheduler = scheduler
+	serveInstances()
+	if err := applyAPIConfig(currentConfig.Load().API); err != nil {
+		showAPIError(err)
+	}
+	if err := applyMetricsConfig(currentConfig.Load().Metrics); err != nil {
+		showMetricsError(err)
+	}
+
+	go autoUpdateTooltip(stopChan, newAutoCleaner())
+	go enforceLimits(stopChan)
+	go runSchedule(stopChan)
+	tray.OnQuit = restoreLimits
 
 	systray.Run(tray.OnReady, onExit)
 	tray.UpdateTooltip()
 }
 
 func onExit() {
+	slog.Info("exiting")
 	close(stopChan)
+	stopInstances()
+	restoreLimits()
 	runtime.GC()
 }
 
-// autoUpdateTooltip periodically updates the tooltip text of the system tray icon.
-func autoUpdateTooltip(stopChan chan struct{}) {
+// newAutoCleaner creates the engine that cleans memory according to the auto_clean configuration.
+func newAutoCleaner() *autoclean.Engine {
+	cleaner := cleanService.AutoCleaner(history.TriggerAuto)
+	return autoclean.New(currentConfig.Load().AutoClean.Policy(), tray.MemoryProvider, cleaner, nil)
+}
+
+// autoUpdateTooltip periodically updates the tooltip text of the system tray icon
+// and lets the auto-clean engine react to the current memory state.
+func autoUpdateTooltip(stopChan chan struct{}, autoCleaner *autoclean.Engine) {
 	for {
 		select {
 		case <-stopChan:
 			return
 		default:
-			time.Sleep(2 * time.Second)
+			cfg := currentConfig.Load()
+			time.Sleep(cfg.RefreshInterval.Std())
+			sampled := time.Now()
 			tray.UpdateTooltip()
+			alertLowMemory(cfg.Notifications.LowMemoryPercent, sampled)
+
+			if !cfg.AutoClean.Enabled {
+				continue
+			}
+
+			// A manual clean is running, the memory state is about to change.
+			// Without memory statistics there is nothing to decide on, UpdateTooltip retries them.
+			if cleanService.Busy() || !tray.StatsAvailable() {
+				continue
+			}
+
+			autoCleaner.SetPolicy(cfg.AutoClean.Policy())
+			action, err := autoCleaner.Step()
+			if action == autoclean.ActionNone {
+				continue
+			}
+			if err != nil {
+				tray.Notifier.Notify(notify.Notification{
+					Severity: notify.Error,
+					Title:    "Error cleaning memory",
+					Message:  fmt.Sprintf("Automatic cleaning failed, err: %s", err.Error()),
+					Key:      "auto-clean",
+				})
+			} else {
+				tray.UpdateTooltip()
+			}
 		}
 	}
 }
@@ -0,0 +1,37 @@
+//go:build windows
+
+// Description: This file contains the Prometheus metrics of the tray application.
+
+package main
+
+import (
+	"fmt"
+	"windows-ram-cleaner/internal/config"
+	"windows-ram-cleaner/internal/history"
+	"windows-ram-cleaner/internal/metrics"
+	"windows-ram-cleaner/internal/report"
+	"windows-ram-cleaner/internal/tray"
+)
+
+// metricsCollector counts the cleans and reads the memory state from the samples of the tooltip.
+// It always collects, the metrics server only runs when enabled.
+var metricsCollector = metrics.New(metrics.Source{Latest: tray.Samples.Latest, SampleErrors: tray.Samples.Errors})
+
+// metricsServer serves /metrics while it is enabled.
+var metricsServer = optionalServer{name: "metrics"}
+
+// applyMetricsConfig starts, restarts or stops the metrics server to match cfg.
+func applyMetricsConfig(cfg config.MetricsConfig) error {
+	return metricsServer.apply(cfg, cfg.Enabled, cfg.Address, metricsCollector.Handler)
+}
+
+// showMetricsError reports that the metrics server could not be started.
+func showMetricsError(err error) {
+	tray.Notifier.Error("Error starting metrics", fmt.Sprintf("Can't start the metrics server, err: %s", err.Error()))
+}
+
+// observeClean feeds every clean to the metrics and the notifications.
+func observeClean(trigger history.Trigger, r *report.CleanReport, err error) {
+	metricsCollector.ObserveClean(trigger, r, err)
+	notifyClean(trigger, r, err)
+}
@@ -0,0 +1,55 @@
+//go:build windows
+
+// Description: This file contains the notifications about automatic cleans and the memory load.
+
+package main
+
+import (
+	"fmt"
+	"time"
+	"windows-ram-cleaner/internal/history"
+	"windows-ram-cleaner/internal/notify"
+	"windows-ram-cleaner/internal/report"
+	"windows-ram-cleaner/internal/tray"
+	"windows-ram-cleaner/internal/trim"
+)
+
+// notifyClean shows the result of an automatic or scheduled clean if enabled in the configuration.
+// Cleans started from the menu show their own result, failed automatic and scheduled cleans are
+// reported by autoUpdateTooltip and onScheduledRun. The report lists the processes that could not be trimmed.
+func notifyClean(trigger history.Trigger, r *report.CleanReport, err error) {
+	automatic := trigger == history.TriggerAuto || trigger == history.TriggerSchedule
+	if !automatic || r == nil || err != nil && !trim.IsPartial(err) {
+		return
+	}
+	if !currentConfig.Load().Notifications.AutoCleanResults {
+		return
+	}
+	title := "Memory cleaned automatically"
+	if trigger == history.TriggerSchedule {
+		title = "Scheduled clean finished"
+	}
+	tray.Notifier.Info(title, r.Summary())
+}
+
+// alertLowMemory warns when the memory load sampled after since exceeds limitPercent.
+// A zero limitPercent disables the alert. The alert is repeated at most once per dedup window.
+func alertLowMemory(limitPercent float64, since time.Time) {
+	if limitPercent <= 0 {
+		return
+	}
+	sample, ok := tray.Samples.Latest()
+	if !ok || sample.Time.Before(since) {
+		return
+	}
+	used := sample.Snapshot.UsedPercent()
+	if used < limitPercent {
+		return
+	}
+	tray.Notifier.Notify(notify.Notification{
+		Severity: notify.Warning,
+		Title:    "Low memory",
+		Message:  fmt.Sprintf("Memory load is %.0f%%, above the %.0f%% alert threshold.", used, limitPercent),
+		Key:      "low-memory",
+	})
+}
@@ -0,0 +1,61 @@
+//go:build windows
+
+// Description: This file contains running the scheduled cleans of the configuration.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"windows-ram-cleaner/internal/history"
+	"windows-ram-cleaner/internal/notify"
+	"windows-ram-cleaner/internal/schedule"
+	"windows-ram-cleaner/internal/service"
+	"windows-ram-cleaner/internal/tray"
+	"windows-ram-cleaner/internal/trim"
+)
+
+// scheduler runs the cleans of the schedule configuration.
+var scheduler = schedule.New(scheduleCleaner{}, nil)
+
+// scheduleCleaner implements schedule.Cleaner with the clean service.
+type scheduleCleaner struct{}
+
+func (scheduleCleaner) Busy() bool {
+	return cleanService.Busy()
+}
+
+func (scheduleCleaner) CleanStandbyList() error {
+	_, err := cleanService.CleanStandbyList(context.Background(), history.TriggerSchedule)
+	return err
+}
+
+func (scheduleCleaner) CleanRAM(deep bool) error {
+	_, err := cleanService.CleanRAM(context.Background(), history.TriggerSchedule, deep)
+	return err
+}
+
+// runSchedule runs the scheduled cleans until stopChan is closed.
+func runSchedule(stopChan chan struct{}) {
+	scheduler.Run(stopChan, onScheduledRun)
+}
+
+// onScheduledRun logs a scheduled run and reports its failure. A clean started at the same time
+// from elsewhere makes the service refuse the run, it is skipped as well.
+func onScheduledRun(r schedule.Run) {
+	if r.Skipped || errors.Is(r.Err, service.ErrBusy) {
+		slog.Info("scheduled clean skipped, another clean is running", "job", r.Job.When, "action", r.Job.Action)
+		return
+	}
+	tray.UpdateTooltip()
+	if r.Err != nil && !trim.IsPartial(r.Err) {
+		tray.Notifier.Notify(notify.Notification{
+			Severity: notify.Error,
+			Title:    "Error cleaning memory",
+			Message:  fmt.Sprintf("Scheduled %s clean failed, err: %s", r.Job, r.Err.Error()),
+			Key:      "schedule",
+		})
+	}
+}
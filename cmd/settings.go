@@ -0,0 +1,123 @@
+//go:build windows
+
+// Description: This file contains loading the configuration and applying it to the running application.
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+	"windows-ram-cleaner/internal/config"
+	"windows-ram-cleaner/internal/schedule"
+	"windows-ram-cleaner/internal/tray"
+	winstartup "windows-ram-cleaner/internal/win_startup"
+	windowsapi "windows-ram-cleaner/internal/windows_api"
+	"windows-ram-cleaner/internal/wslimit"
+)
+
+// configWatchInterval is how often the configuration file is checked for changes.
+const configWatchInterval = 2 * time.Second
+
+// currentConfig is the configuration the application is running with.
+var currentConfig atomic.Pointer[config.Config]
+
+// loadConfig loads the configuration file, creating it with defaults on the first run, and applies it
+// with apply. On failure the defaults are applied and the error is returned.
+func loadConfig(apply func(config.Config) error) (string, error) {
+	path, err := config.DefaultPath()
+	if err != nil {
+		apply(config.Default())
+		return "", err
+	}
+
+	cfg, err := config.LoadOrCreate(path)
+	if err != nil {
+		apply(config.Default())
+		return path, err
+	}
+
+	return path, apply(cfg)
+}
+
+// applyCommandConfig makes cfg the current configuration of a headless command. Commands don't
+// change the system when applying it: the startup entry is renamed and the working set limits are
+// applied by the tray application only.
+func applyCommandConfig(cfg config.Config) error {
+	if err := applyCleaningConfig(cfg); err != nil {
+		return err
+	}
+	// The startup command manages the entry under its configured name
+	winstartup.WinTaskName = cfg.Startup.TaskName
+	return nil
+}
+
+// applyConfig makes cfg the current configuration of the tray application. Settings read on every use,
+// such as the refresh interval and the auto-clean policy, are picked up by their loops.
+func applyConfig(cfg config.Config) error {
+	// The rename changes the system and may fail, so it comes first: on failure the entry keeps its
+	// old name, the next reload retries, and the other settings are still applied.
+	renameErr := winstartup.RenameStartupTask(cfg.Startup.TaskName)
+	if renameErr != nil {
+		renameErr = fmt.Errorf("failed to rename startup task: %v", renameErr)
+	}
+
+	if err := applyCleaningConfig(cfg); err != nil {
+		return err
+	}
+	tray.Notifier.SetOptions(cfg.Notifications.Options())
+	limits, err := wslimit.Compile(cfg.Limits.Processes)
+	if err != nil {
+		return fmt.Errorf("invalid working set limits: %v", err)
+	}
+	limitManager.SetLimits(limits)
+	jobs, err := schedule.Compile(cfg.Schedule, time.Now())
+	if err != nil {
+		return fmt.Errorf("invalid schedule: %v", err)
+	}
+	scheduler.SetJobs(jobs)
+	return renameErr
+}
+
+// applyCleaningConfig makes cfg the current configuration and applies the settings of the cleans
+// and the log, which both the tray application and the commands use.
+func applyCleaningConfig(cfg config.Config) error {
+	currentConfig.Store(&cfg)
+	applyLogConfig(cfg.Log)
+	if err := windowsapi.SetProcessRules(cfg.Cleaning.Rules, cfg.Cleaning.CriticalProcesses); err != nil {
+		return fmt.Errorf("invalid process rules: %v", err)
+	}
+	windowsapi.SetTrimOptions(cfg.Cleaning.TrimOptions())
+	if cleanService != nil && cleanService.History() != nil {
+		cleanService.History().SetRetention(cfg.History.Retention())
+	}
+	return nil
+}
+
+// watchConfig reloads the configuration file when it changes until stopChan is closed.
+func watchConfig(path string, stopChan chan struct{}) {
+	showConfigError := func(err error) {
+		tray.Notifier.Error(
+			"Error loading configuration",
+			fmt.Sprintf("Can't apply configuration, err: %s", err.Error()),
+		)
+	}
+
+	config.Watch(path, configWatchInterval, stopChan, func(cfg config.Config) {
+		err := applyConfig(cfg)
+		if err != nil {
+			showConfigError(err)
+		}
+		// The servers don't depend on the rest of the configuration
+		if err := applyAPIConfig(cfg.API); err != nil {
+			showAPIError(err)
+		}
+		if err := applyMetricsConfig(cfg.Metrics); err != nil {
+			showMetricsError(err)
+		}
+		if err == nil {
+			slog.Info("configuration reloaded", "path", path)
+		}
+	}, showConfigError)
+}
@@ -0,0 +1,197 @@
+// Package api Description: This package serves the local HTTP/JSON control API used by scripts and dashboards.
+// Every request needs the bearer token of the configuration. The work is done by a Backend,
+// so that the API can be tested without WinAPI.
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"windows-ram-cleaner/internal/history"
+	"windows-ram-cleaner/internal/inventory"
+	"windows-ram-cleaner/internal/memory"
+	"windows-ram-cleaner/internal/report"
+	"windows-ram-cleaner/internal/service"
+	"windows-ram-cleaner/internal/trim"
+)
+
+// MinTokenLength is the minimum length of the bearer token.
+const MinTokenLength = 16
+
+// Backend performs the work of the API.
+type Backend interface {
+	Memory() (memory.Snapshot, error)
+	// Busy reports whether a clean is running.
+	Busy() bool
+	CleanStandbyList(ctx context.Context) (*report.CleanReport, error)
+	CleanRAM(ctx context.Context, deep bool) (*report.CleanReport, error)
+	History() ([]history.Entry, error)
+	Processes() ([]inventory.Process, error)
+}
+
+// Status is the response of GET /status.
+type Status struct {
+	Memory      memory.Snapshot `json:"memory"`
+	UsedPercent float64         `json:"used_percent"`
+	Busy        bool            `json:"busy"`
+}
+
+// Error is the response of a failed request. A failed clean also returns its report.
+type Error struct {
+	Error  string              `json:"error"`
+	Report *report.CleanReport `json:"report,omitempty"`
+}
+
+// IsLoopback reports whether addr, a host:port listen address, only accepts local connections.
+func IsLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Handler returns the handler of the API. Requests without "Authorization: Bearer token" are rejected.
+func Handler(backend Backend, token string) http.Handler {
+	s := server{backend: backend}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", s.status)
+	mux.HandleFunc("POST /clean/standby", s.cleanStandby)
+	mux.HandleFunc("POST /clean/ram", s.cleanRAM)
+	mux.HandleFunc("GET /history", s.history)
+	mux.HandleFunc("GET /processes", s.processes)
+	return authorize(token, mux)
+}
+
+// authorize rejects requests without the bearer token. An empty token rejects every request.
+func authorize(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type server struct {
+	backend Backend
+}
+
+func (s server) status(w http.ResponseWriter, r *http.Request) {
+	snapshot, err := s.backend.Memory()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Status{Memory: snapshot, UsedPercent: snapshot.UsedPercent(), Busy: s.backend.Busy()})
+}
+
+func (s server) cleanStandby(w http.ResponseWriter, r *http.Request) {
+	cleanReport, err := s.backend.CleanStandbyList(r.Context())
+	writeClean(w, cleanReport, err)
+}
+
+func (s server) cleanRAM(w http.ResponseWriter, r *http.Request) {
+	deep := false
+	if value := r.URL.Query().Get("deep"); value != "" {
+		var err error
+		if deep, err = strconv.ParseBool(value); err != nil {
+			writeError(w, http.StatusBadRequest, errors.New("deep must be true or false"))
+			return
+		}
+	}
+	cleanReport, err := s.backend.CleanRAM(r.Context(), deep)
+	writeClean(w, cleanReport, err)
+}
+
+func (s server) history(w http.ResponseWriter, r *http.Request) {
+	limit, err := parseLimit(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	entries, err := s.backend.History()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	// The newest entries are the last ones
+	if limit > 0 && limit < len(entries) {
+		entries = entries[len(entries)-limit:]
+	}
+	if entries == nil {
+		entries = []history.Entry{}
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+func (s server) processes(w http.ResponseWriter, r *http.Request) {
+	limit, err := parseLimit(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	key := inventory.SortWorkingSet
+	if name := r.URL.Query().Get("sort"); name != "" {
+		if key, err = inventory.ParseSortKey(name); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	processes, err := s.backend.Processes()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, append([]inventory.Process{}, inventory.Top(processes, limit, key)...))
+}
+
+// parseLimit returns the limit query parameter, zero if it is missing.
+func parseLimit(r *http.Request) (int, error) {
+	value := r.URL.Query().Get("limit")
+	if value == "" {
+		return 0, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 0 {
+		return 0, errors.New("limit must be a non-negative number")
+	}
+	return limit, nil
+}
+
+// writeClean writes the report of a clean. Processes that could not be trimmed don't fail
+// the request: the report lists them.
+func writeClean(w http.ResponseWriter, cleanReport *report.CleanReport, err error) {
+	switch {
+	case err == nil || trim.IsPartial(err):
+		writeJSON(w, http.StatusOK, cleanReport)
+	case errors.Is(err, service.ErrBusy):
+		writeError(w, http.StatusConflict, err)
+	default:
+		writeJSON(w, http.StatusInternalServerError, Error{Error: err.Error(), Report: cleanReport})
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, Error{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"windows-ram-cleaner/internal/history"
+	"windows-ram-cleaner/internal/inventory"
+	"windows-ram-cleaner/internal/memory"
+	"windows-ram-cleaner/internal/report"
+	"windows-ram-cleaner/internal/service"
+	"windows-ram-cleaner/internal/trim"
+)
+
+const token = "0123456789abcdef"
+
+type fakeBackend struct {
+	snapshot memory.Snapshot
+	cleanErr error
+	deep     *bool
+	entries  []history.Entry
+	procs    []inventory.Process
+}
+
+func (b *fakeBackend) Memory() (memory.Snapshot, error) { return b.snapshot, nil }
+func (b *fakeBackend) Busy() bool                       { return false }
+
+func (b *fakeBackend) CleanStandbyList(ctx context.Context) (*report.CleanReport, error) {
+	return b.clean(report.ModeStandby)
+}
+
+func (b *fakeBackend) CleanRAM(ctx context.Context, deep bool) (*report.CleanReport, error) {
+	b.deep = &deep
+	if deep {
+		return b.clean(report.ModeDeep)
+	}
+	return b.clean(report.ModeBasic)
+}
+
+func (b *fakeBackend) clean(mode report.Mode) (*report.CleanReport, error) {
+	if errors.Is(b.cleanErr, service.ErrBusy) {
+		return nil, b.cleanErr
+	}
+	r := report.New(mode, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), b.snapshot)
+	r.Finish(r.Started.Add(time.Second), b.snapshot, b.cleanErr)
+	return r, b.cleanErr
+}
+
+func (b *fakeBackend) History() ([]history.Entry, error)       { return b.entries, nil }
+func (b *fakeBackend) Processes() ([]inventory.Process, error) { return b.procs, nil }
+
+func newFakeBackend() *fakeBackend {
+	var entries []history.Entry
+	for _, trigger := range []history.Trigger{history.TriggerAuto, history.TriggerManual, history.TriggerAPI} {
+		entries = append(entries, history.Entry{Trigger: trigger})
+	}
+	return &fakeBackend{
+		snapshot: memory.Snapshot{TotalSize: 100, AvailableSize: 25},
+		entries:  entries,
+		procs: []inventory.Process{
+			{PID: 1, Name: "small.exe", WorkingSet: 10, PrivateBytes: 300},
+			{PID: 2, Name: "large.exe", WorkingSet: 200, PrivateBytes: 100},
+			{PID: 3, Name: "medium.exe", WorkingSet: 50, PrivateBytes: 200},
+		},
+	}
+}
+
+func partialErr() error {
+	partial := &trim.Errors{Attempted: 2}
+	partial.Add(4, "csrss.exe", "open process", errors.New("access denied"))
+	return partial
+}
+
+func do(t *testing.T, backend Backend, method, target, auth string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, nil)
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	rec := httptest.NewRecorder()
+	Handler(backend, token).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRequests(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		auth     string
+		cleanErr error
+		code     int
+		contains string
+	}{
+		{name: "status", method: http.MethodGet, target: "/status", code: http.StatusOK, contains: `"used_percent":75`},
+		{name: "missing token", method: http.MethodGet, target: "/status", auth: "-", code: http.StatusUnauthorized},
+		{name: "wrong token", method: http.MethodGet, target: "/status", auth: "Bearer wrong", code: http.StatusUnauthorized},
+		{name: "clean standby", method: http.MethodPost, target: "/clean/standby", code: http.StatusOK, contains: `"mode":"standby"`},
+		{name: "clean standby with GET", method: http.MethodGet, target: "/clean/standby", code: http.StatusMethodNotAllowed},
+		{name: "deep clean", method: http.MethodPost, target: "/clean/ram?deep=true", code: http.StatusOK, contains: `"mode":"deep"`},
+		{name: "basic clean", method: http.MethodPost, target: "/clean/ram", code: http.StatusOK, contains: `"mode":"basic"`},
+		{name: "invalid deep", method: http.MethodPost, target: "/clean/ram?deep=maybe", code: http.StatusBadRequest},
+		{name: "busy", method: http.MethodPost, target: "/clean/ram", cleanErr: service.ErrBusy, code: http.StatusConflict},
+		{name: "partial clean", method: http.MethodPost, target: "/clean/ram", cleanErr: partialErr(), code: http.StatusOK},
+		{name: "failed clean", method: http.MethodPost, target: "/clean/standby", cleanErr: errors.New("access denied"), code: http.StatusInternalServerError, contains: `"report":`},
+		{name: "history", method: http.MethodGet, target: "/history?limit=1", code: http.StatusOK, contains: `"trigger":"api"`},
+		{name: "invalid limit", method: http.MethodGet, target: "/history?limit=-1", code: http.StatusBadRequest},
+		{name: "processes", method: http.MethodGet, target: "/processes?sort=private&limit=1", code: http.StatusOK, contains: `"small.exe"`},
+		{name: "invalid sort", method: http.MethodGet, target: "/processes?sort=colour", code: http.StatusBadRequest},
+		{name: "unknown path", method: http.MethodGet, target: "/metrics", code: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newFakeBackend()
+			backend.cleanErr = tt.cleanErr
+			auth := "Bearer " + token
+			if tt.auth != "" {
+				auth = strings.TrimPrefix(tt.auth, "-")
+			}
+
+			rec := do(t, backend, tt.method, tt.target, auth)
+			if rec.Code != tt.code {
+				t.Errorf("%s %s = %d, want %d: %s", tt.method, tt.target, rec.Code, tt.code, rec.Body)
+			}
+			if tt.contains != "" && !strings.Contains(rec.Body.String(), tt.contains) {
+				t.Errorf("%s %s = %s, want it to contain %s", tt.method, tt.target, rec.Body, tt.contains)
+			}
+			if ct := rec.Header().Get("Content-Type"); rec.Code != http.StatusNotFound && rec.Code != http.StatusMethodNotAllowed && ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+		})
+	}
+}
+
+func TestProcessesOrder(t *testing.T) {
+	rec := do(t, newFakeBackend(), http.MethodGet, "/processes", "Bearer "+token)
+	var processes []inventory.Process
+	if err := json.Unmarshal(rec.Body.Bytes(), &processes); err != nil {
+		t.Fatal(err)
+	}
+	if len(processes) != 3 || processes[0].Name != "large.exe" || processes[2].Name != "small.exe" {
+		t.Errorf("GET /processes = %+v, want sorted by working set", processes)
+	}
+}
+
+func TestEmptyTokenRejectsEverything(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/status", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	Handler(newFakeBackend(), "").ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("GET /status with an empty token = %d, want 401", rec.Code)
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	tests := []struct {
+		addr     string
+		expected bool
+	}{
+		{addr: "127.0.0.1:8765", expected: true},
+		{addr: "[::1]:8765", expected: true},
+		{addr: "localhost:8765", expected: true},
+		{addr: "0.0.0.0:8765", expected: false},
+		{addr: ":8765", expected: false},
+		{addr: "192.168.1.10:8765", expected: false},
+		{addr: "127.0.0.1", expected: false},
+	}
+
+	for _, tt := range tests {
+		if got := IsLoopback(tt.addr); got != tt.expected {
+			t.Errorf("IsLoopback(%q) = %v, want %v", tt.addr, got, tt.expected)
+		}
+	}
+}
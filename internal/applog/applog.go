@@ -0,0 +1,159 @@
+// Package applog Description: This package contains the structured logger of the application.
+// Records are written as text to a file that is rotated when it reaches a size limit,
+// and the level can be changed while the application is running.
+package applog
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// File names of the logs in the application directory. The tray application and the commands it runs
+// for other instances log to FileName, headless commands to CommandFileName: a file can't be rotated
+// on Windows while another process has it open.
+const (
+	FileName        = "app.log"
+	CommandFileName = "cli.log"
+)
+
+// Levels are the level names accepted by ParseLevel.
+var Levels = []string{"debug", "info", "warn", "error"}
+
+// ParseLevel converts a level name of Levels to a slog level.
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("unknown level %q, expected one of %s", name, strings.Join(Levels, ", "))
+	}
+}
+
+// New creates a logger writing text records to w. Records below level are dropped;
+// pass a *slog.LevelVar to change the level at runtime.
+func New(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: level}))
+}
+
+// File is a log file rotated when a write would make it larger than its maximum size:
+// the file is renamed to path.1, path.1 to path.2 and so on, dropping the oldest one.
+// It is safe for concurrent use.
+type File struct {
+	path string
+
+	mu      sync.Mutex
+	file    *os.File
+	size    int64
+	maxSize int64
+	backups int
+}
+
+// OpenFile opens the log file at path for appending, creating it and its directory if needed.
+// The file is rotated at maxSize bytes, keeping backups rotated files. A zero maxSize never rotates.
+func OpenFile(path string, maxSize int64, backups int) (*File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %v", err)
+	}
+	f := &File{path: path, maxSize: maxSize, backups: backups}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Path returns the path of the current log file.
+func (f *File) Path() string {
+	return f.path
+}
+
+// SetLimits changes the rotation limits, they apply from the next write.
+func (f *File) SetLimits(maxSize int64, backups int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.maxSize = maxSize
+	f.backups = backups
+}
+
+// Write appends p to the file, rotating it first if p would not fit.
+// If the rotation fails, e.g. because another process holds the file, p is appended anyway.
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		_ = f.rotate()
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close closes the file.
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+func (f *File) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to read log file size: %v", err)
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// rotate shifts the rotated files and starts a new file. Without backups the file is truncated.
+func (f *File) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	var err error
+	if f.backups > 0 {
+		_ = os.Remove(backupPath(f.path, f.backups))
+		for i := f.backups - 1; i >= 1; i-- {
+			_ = os.Rename(backupPath(f.path, i), backupPath(f.path, i+1))
+		}
+		err = os.Rename(f.path, backupPath(f.path, 1))
+	} else {
+		err = os.Truncate(f.path, 0)
+	}
+
+	// Keep logging to the same file if it could not be moved
+	if openErr := f.open(); openErr != nil {
+		return openErr
+	}
+	return err
+}
+
+// backupPath returns the path of the n-th rotated file.
+func backupPath(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
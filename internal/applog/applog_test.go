@@ -0,0 +1,108 @@
+package applog
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected slog.Level
+		wantErr  bool
+	}{
+		{name: "debug", expected: slog.LevelDebug},
+		{name: "INFO", expected: slog.LevelInfo},
+		{name: "warn", expected: slog.LevelWarn},
+		{name: "error", expected: slog.LevelError},
+		{name: "verbose", wantErr: true},
+		{name: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLevel(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLevel() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("ParseLevel() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRuntimeLevel(t *testing.T) {
+	var buf bytes.Buffer
+	var level slog.LevelVar
+	level.Set(slog.LevelWarn)
+	logger := New(&buf, &level)
+
+	logger.Info("hidden")
+	level.Set(slog.LevelDebug)
+	logger.Debug("shown", "pid", 42)
+
+	if out := buf.String(); strings.Contains(out, "hidden") || !strings.Contains(out, "msg=shown pid=42") {
+		t.Errorf("log = %q, want only the debug record", out)
+	}
+}
+
+func TestFileRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", FileName)
+	f, err := OpenFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	expected := map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third\n",
+		path + ".2": "second\n",
+	}
+	for p, content := range expected {
+		data, err := os.ReadFile(p)
+		if err != nil || string(data) != content {
+			t.Errorf("%s = %q, %v, want %q", filepath.Base(p), data, err, content)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("more rotated files than backups were kept")
+	}
+}
+
+func TestFileAppendsAndTruncates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := OpenFile(path, 8, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("new\n"))
+	if data, _ := os.ReadFile(path); string(data) != "old\nnew\n" {
+		t.Errorf("file = %q, want the record appended", data)
+	}
+
+	// Without backups a full file is truncated
+	f.Write([]byte("next\n"))
+	f.Close()
+	if data, _ := os.ReadFile(path); string(data) != "next\n" {
+		t.Errorf("file = %q, want it truncated", data)
+	}
+	if _, err := f.Write([]byte("closed\n")); err == nil {
+		t.Error("Write() after Close() expected an error")
+	}
+}
@@ -0,0 +1,17 @@
+// Description: This file contains the clock abstraction used by the engine.
+
+package autoclean
+
+import "time"
+
+// Clock provides the current time and timers, so tests can control time.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock is a Clock backed by the time package.
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time                         { return time.Now() }
+func (SystemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
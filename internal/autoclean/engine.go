@@ -0,0 +1,261 @@
+// Package autoclean Description: This package decides when memory should be cleaned automatically.
+// The decision logic is driven by an injectable clock and memory source, so it does not depend on WinAPI.
+package autoclean
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"windows-ram-cleaner/internal/memory"
+)
+
+// Action is the cleaning action chosen by the engine.
+type Action int
+
+const (
+	ActionNone Action = iota
+	ActionCleanStandby
+	ActionCleanRAM
+	ActionCleanLowStandby
+)
+
+// String returns a human readable name of the action.
+func (a Action) String() string {
+	switch a {
+	case ActionNone:
+		return "none"
+	case ActionCleanStandby:
+		return "clean-standby"
+	case ActionCleanRAM:
+		return "clean-ram"
+	case ActionCleanLowStandby:
+		return "clean-low-standby"
+	default:
+		return fmt.Sprintf("Action(%d)", int(a))
+	}
+}
+
+// ErrSkipped is wrapped by the errors of a Cleaner that can't clean now, e.g. because another
+// clean is running. The engine doesn't count a skipped clean: it is retried at the next step.
+var ErrSkipped = errors.New("clean skipped")
+
+// Cleaner performs the actual cleaning.
+type Cleaner interface {
+	CleanStandbyList() error
+	CleanLowPriorityStandby() error
+	CleanRAM() error
+}
+
+// CleanerFuncs adapts plain functions to the Cleaner interface.
+type CleanerFuncs struct {
+	Standby    func() error
+	LowStandby func() error
+	RAM        func() error
+}
+
+func (c CleanerFuncs) CleanStandbyList() error        { return c.Standby() }
+func (c CleanerFuncs) CleanLowPriorityStandby() error { return c.LowStandby() }
+func (c CleanerFuncs) CleanRAM() error                { return c.RAM() }
+
+// Policy describes when the engine triggers a clean.
+type Policy struct {
+	// FreePercent triggers CleanRAM when available memory drops below this percentage. Zero disables the trigger.
+	FreePercent float64
+	// FreeHysteresis is how many percent above FreePercent free memory must recover before the trigger re-arms.
+	FreeHysteresis float64
+	// StandbyBytes triggers CleanStandbyList when the standby list exceeds this size. Zero disables the trigger.
+	StandbyBytes uint64
+	// StandbyHysteresis is how many bytes below StandbyBytes the standby list must shrink before the trigger re-arms.
+	StandbyHysteresis uint64
+	// StandbyLowPriorityOnly makes the standby trigger measure and purge only the standby pages of
+	// priority 0, keeping the cached data of applications.
+	StandbyLowPriorityOnly bool
+	// MinInterval is the minimum time between two automatic cleans.
+	MinInterval time.Duration
+	// Cooldown is the pause after a clean that failed or did not bring memory out of the trigger zone.
+	Cooldown time.Duration
+}
+
+// DefaultPolicy returns a conservative policy that triggers on low free memory only.
+func DefaultPolicy() Policy {
+	return Policy{
+		FreePercent:    35,
+		FreeHysteresis: 5,
+		MinInterval:    time.Minute,
+		Cooldown:       10 * time.Minute,
+	}
+}
+
+// Engine samples memory and triggers cleans according to a Policy.
+// It is not safe for concurrent use.
+type Engine struct {
+	policy  Policy
+	source  memory.Provider
+	cleaner Cleaner
+	clock   Clock
+
+	lastClean     time.Time
+	cooldownUntil time.Time
+	freeArmed     bool
+	standbyArmed  bool
+}
+
+// New creates an Engine. A nil clock means the system clock.
+func New(policy Policy, source memory.Provider, cleaner Cleaner, clock Clock) *Engine {
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	return &Engine{
+		policy:       policy,
+		source:       source,
+		cleaner:      cleaner,
+		clock:        clock,
+		freeArmed:    true,
+		standbyArmed: true,
+	}
+}
+
+// Policy returns the policy the engine is using.
+func (e *Engine) Policy() Policy {
+	return e.policy
+}
+
+// SetPolicy replaces the policy, keeping the hysteresis and cooldown state.
+func (e *Engine) SetPolicy(policy Policy) {
+	e.policy = policy
+}
+
+// Decide returns the action for the given snapshot at the given time and updates the hysteresis state.
+// It does not perform any cleaning.
+func (e *Engine) Decide(s memory.Snapshot, now time.Time) Action {
+	e.rearm(s)
+
+	if now.Before(e.cooldownUntil) {
+		return ActionNone
+	}
+	if !e.lastClean.IsZero() && now.Sub(e.lastClean) < e.policy.MinInterval {
+		return ActionNone
+	}
+
+	if e.freeArmed && e.freeLow(s) {
+		return ActionCleanRAM
+	}
+	if e.standbyArmed && e.standbyHigh(s) {
+		if e.policy.StandbyLowPriorityOnly {
+			return ActionCleanLowStandby
+		}
+		return ActionCleanStandby
+	}
+	return ActionNone
+}
+
+// Step takes a snapshot, decides and runs the chosen clean. It returns the action that was attempted,
+// or ActionNone if the cleaner skipped it.
+func (e *Engine) Step() (Action, error) {
+	s, err := e.source.Snapshot()
+	if err != nil {
+		return ActionNone, fmt.Errorf("failed to sample memory: %v", err)
+	}
+
+	action := e.Decide(s, e.clock.Now())
+	if action == ActionNone {
+		return ActionNone, nil
+	}
+
+	switch action {
+	case ActionCleanRAM:
+		err = e.cleaner.CleanRAM()
+	case ActionCleanStandby:
+		err = e.cleaner.CleanStandbyList()
+	case ActionCleanLowStandby:
+		err = e.cleaner.CleanLowPriorityStandby()
+	}
+
+	if errors.Is(err, ErrSkipped) {
+		return ActionNone, nil
+	}
+
+	now := e.clock.Now()
+	e.lastClean = now
+	if err != nil {
+		e.cooldownUntil = now.Add(e.policy.Cooldown)
+		return action, fmt.Errorf("automatic %s failed: %w", action, err)
+	}
+
+	// A clean that did not help is retried only after the cooldown, an effective one
+	// disarms its trigger until memory recovers past the hysteresis band.
+	if after, err := e.source.Snapshot(); err == nil && e.ineffective(action, after) {
+		e.cooldownUntil = now.Add(e.policy.Cooldown)
+	} else {
+		e.disarm(action)
+	}
+	return action, nil
+}
+
+// Run calls Step every interval until stop is closed. onStep, if not nil, receives every result.
+func (e *Engine) Run(stop <-chan struct{}, interval time.Duration, onStep func(Action, error)) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-e.clock.After(interval):
+			action, err := e.Step()
+			if onStep != nil {
+				onStep(action, err)
+			}
+		}
+	}
+}
+
+// rearm re-enables triggers once memory has recovered past the hysteresis band.
+func (e *Engine) rearm(s memory.Snapshot) {
+	if !e.freeArmed && s.AvailablePercent() >= e.policy.FreePercent+e.policy.FreeHysteresis {
+		e.freeArmed = true
+	}
+	if !e.standbyArmed {
+		limit := uint64(0)
+		if e.policy.StandbyBytes > e.policy.StandbyHysteresis {
+			limit = e.policy.StandbyBytes - e.policy.StandbyHysteresis
+		}
+		if e.standbySize(s) <= limit {
+			e.standbyArmed = true
+		}
+	}
+}
+
+func (e *Engine) disarm(action Action) {
+	switch action {
+	case ActionCleanRAM:
+		e.freeArmed = false
+	case ActionCleanStandby, ActionCleanLowStandby:
+		e.standbyArmed = false
+	}
+}
+
+func (e *Engine) freeLow(s memory.Snapshot) bool {
+	return e.policy.FreePercent > 0 && s.TotalSize > 0 && s.AvailablePercent() < e.policy.FreePercent
+}
+
+func (e *Engine) standbyHigh(s memory.Snapshot) bool {
+	return e.policy.StandbyBytes > 0 && e.standbySize(s) > e.policy.StandbyBytes
+}
+
+// standbySize returns the part of the standby list the standby trigger watches.
+func (e *Engine) standbySize(s memory.Snapshot) uint64 {
+	if e.policy.StandbyLowPriorityOnly {
+		return s.StandbyLow()
+	}
+	return s.StandbySize
+}
+
+// ineffective reports whether memory is still in the trigger zone after a clean.
+func (e *Engine) ineffective(action Action, after memory.Snapshot) bool {
+	switch action {
+	case ActionCleanRAM:
+		return e.freeLow(after)
+	case ActionCleanStandby, ActionCleanLowStandby:
+		return e.standbyHigh(after)
+	}
+	return false
+}
@@ -0,0 +1,317 @@
+package autoclean
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"windows-ram-cleaner/internal/memory"
+)
+
+const gb = 1024 * 1024 * 1024
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+type fakeCleaner struct {
+	mem        *memory.Fake
+	afterClean memory.Snapshot
+	err        error
+	ram        int
+	standby    int
+	lowStandby int
+}
+
+func (c *fakeCleaner) CleanRAM() error {
+	c.ram++
+	if c.err == nil {
+		c.mem.Set(c.afterClean)
+	}
+	return c.err
+}
+
+func (c *fakeCleaner) CleanStandbyList() error {
+	c.standby++
+	if c.err == nil {
+		c.mem.Set(c.afterClean)
+	}
+	return c.err
+}
+
+func (c *fakeCleaner) CleanLowPriorityStandby() error {
+	c.lowStandby++
+	if c.err == nil {
+		c.mem.Set(c.afterClean)
+	}
+	return c.err
+}
+
+func sample(freePercent uint64, standbyGB uint64) memory.Snapshot {
+	return memory.Snapshot{TotalSize: 100 * gb, AvailableSize: freePercent * gb, StandbySize: standbyGB * gb}
+}
+
+func testPolicy() Policy {
+	return Policy{
+		FreePercent:       30,
+		FreeHysteresis:    10,
+		StandbyBytes:      8 * gb,
+		StandbyHysteresis: 2 * gb,
+		MinInterval:       time.Minute,
+		Cooldown:          10 * time.Minute,
+	}
+}
+
+func newTestEngine(start memory.Snapshot, after memory.Snapshot) (*Engine, *memory.Fake, *fakeCleaner, *fakeClock) {
+	mem := memory.NewFake(start)
+	cleaner := &fakeCleaner{mem: mem, afterClean: after}
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	return New(testPolicy(), mem, cleaner, clock), mem, cleaner, clock
+}
+
+func TestDecide(t *testing.T) {
+	tests := []struct {
+		name     string
+		sample   memory.Snapshot
+		expected Action
+	}{
+		{name: "plenty of memory", sample: sample(60, 1), expected: ActionNone},
+		{name: "low free memory", sample: sample(20, 1), expected: ActionCleanRAM},
+		{name: "large standby list", sample: sample(60, 10), expected: ActionCleanStandby},
+		{name: "low free wins over standby", sample: sample(20, 10), expected: ActionCleanRAM},
+		{name: "exactly at threshold", sample: sample(30, 8), expected: ActionNone},
+		{name: "unknown total", sample: memory.Snapshot{AvailableSize: gb}, expected: ActionNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, _, _, clock := newTestEngine(tt.sample, tt.sample)
+			if result := e.Decide(tt.sample, clock.Now()); result != tt.expected {
+				t.Errorf("Decide() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLowPriorityStandby(t *testing.T) {
+	// 10 GB of standby, of which only 3 GB have priority 0.
+	start := sample(60, 10)
+	start.StandbyByPriority[0] = 3 * gb
+	start.StandbyByPriority[6] = 7 * gb
+	after := sample(60, 7)
+	after.StandbyByPriority[6] = 7 * gb
+
+	e, mem, cleaner, clock := newTestEngine(start, after)
+	e.policy.StandbyLowPriorityOnly = true
+	e.policy.StandbyBytes = 2 * gb
+	e.policy.StandbyHysteresis = gb
+
+	if action, err := e.Step(); action != ActionCleanLowStandby || err != nil {
+		t.Fatalf("Step() = %v, %v, want %v, nil", action, err, ActionCleanLowStandby)
+	}
+	if cleaner.lowStandby != 1 || cleaner.standby != 0 {
+		t.Errorf("cleaner calls = %d low, %d full, want only a low priority purge", cleaner.lowStandby, cleaner.standby)
+	}
+
+	// Application data at high priority never triggers the low priority policy.
+	clock.now = clock.now.Add(time.Hour)
+	mem.Set(after)
+	if action, _ := e.Step(); action != ActionNone {
+		t.Errorf("Step() = %v with only high priority standby, want %v", action, ActionNone)
+	}
+}
+
+func TestDisabledTriggers(t *testing.T) {
+	e, _, _, clock := newTestEngine(sample(5, 50), sample(5, 50))
+	e.policy.FreePercent = 0
+	e.policy.StandbyBytes = 0
+
+	if result := e.Decide(sample(5, 50), clock.Now()); result != ActionNone {
+		t.Errorf("Decide() = %v, want %v", result, ActionNone)
+	}
+}
+
+func TestHysteresis(t *testing.T) {
+	e, mem, cleaner, clock := newTestEngine(sample(20, 1), sample(35, 1))
+
+	if action, err := e.Step(); action != ActionCleanRAM || err != nil {
+		t.Fatalf("Step() = %v, %v, want %v, nil", action, err, ActionCleanRAM)
+	}
+
+	// Free memory dropped again, but never recovered past threshold + hysteresis.
+	clock.now = clock.now.Add(time.Hour)
+	mem.Set(sample(25, 1))
+	if action, _ := e.Step(); action != ActionNone {
+		t.Fatalf("Step() = %v inside hysteresis band, want %v", action, ActionNone)
+	}
+
+	// Recovery above 40% re-arms the trigger.
+	clock.now = clock.now.Add(time.Hour)
+	mem.Set(sample(45, 1))
+	if action, _ := e.Step(); action != ActionNone {
+		t.Fatalf("Step() = %v after recovery, want %v", action, ActionNone)
+	}
+
+	clock.now = clock.now.Add(time.Hour)
+	mem.Set(sample(25, 1))
+	if action, _ := e.Step(); action != ActionCleanRAM {
+		t.Fatalf("Step() = %v after re-arm, want %v", action, ActionCleanRAM)
+	}
+	if cleaner.ram != 2 {
+		t.Errorf("CleanRAM called %d times, want 2", cleaner.ram)
+	}
+}
+
+func TestStandbyHysteresis(t *testing.T) {
+	e, mem, cleaner, clock := newTestEngine(sample(60, 10), sample(60, 7))
+
+	if action, _ := e.Step(); action != ActionCleanStandby {
+		t.Fatalf("Step() = %v, want %v", action, ActionCleanStandby)
+	}
+
+	clock.now = clock.now.Add(time.Hour)
+	mem.Set(sample(60, 9))
+	if action, _ := e.Step(); action != ActionNone {
+		t.Fatalf("Step() = %v inside hysteresis band, want %v", action, ActionNone)
+	}
+
+	clock.now = clock.now.Add(time.Hour)
+	mem.Set(sample(60, 5))
+	e.Step()
+	clock.now = clock.now.Add(time.Hour)
+	mem.Set(sample(60, 9))
+	if action, _ := e.Step(); action != ActionCleanStandby {
+		t.Fatalf("Step() = %v after re-arm, want %v", action, ActionCleanStandby)
+	}
+	if cleaner.standby != 2 {
+		t.Errorf("CleanStandbyList called %d times, want 2", cleaner.standby)
+	}
+}
+
+func TestMinInterval(t *testing.T) {
+	e, mem, cleaner, clock := newTestEngine(sample(60, 10), sample(60, 1))
+
+	e.Step()
+	// Standby is drained, so the trigger re-arms, but the RAM trigger fires too early.
+	mem.Set(sample(10, 1))
+	clock.now = clock.now.Add(30 * time.Second)
+	if action, _ := e.Step(); action != ActionNone {
+		t.Fatalf("Step() = %v before MinInterval, want %v", action, ActionNone)
+	}
+
+	clock.now = clock.now.Add(31 * time.Second)
+	if action, _ := e.Step(); action != ActionCleanRAM {
+		t.Fatalf("Step() = %v after MinInterval, want %v", action, ActionCleanRAM)
+	}
+	if cleaner.standby != 1 || cleaner.ram != 1 {
+		t.Errorf("cleans = %d standby, %d ram, want 1 and 1", cleaner.standby, cleaner.ram)
+	}
+}
+
+func TestCooldownAfterIneffectiveClean(t *testing.T) {
+	// The clean does not move free memory out of the trigger zone.
+	e, _, cleaner, clock := newTestEngine(sample(20, 1), sample(22, 1))
+
+	e.Step()
+	clock.now = clock.now.Add(5 * time.Minute)
+	if action, _ := e.Step(); action != ActionNone {
+		t.Fatalf("Step() = %v during cooldown, want %v", action, ActionNone)
+	}
+
+	clock.now = clock.now.Add(6 * time.Minute)
+	if action, _ := e.Step(); action != ActionCleanRAM {
+		t.Fatalf("Step() = %v after cooldown, want %v", action, ActionCleanRAM)
+	}
+	if cleaner.ram != 2 {
+		t.Errorf("CleanRAM called %d times, want 2", cleaner.ram)
+	}
+}
+
+func TestCooldownAfterFailure(t *testing.T) {
+	e, _, cleaner, clock := newTestEngine(sample(20, 1), sample(50, 1))
+	cleaner.err = errors.New("access denied")
+
+	if action, err := e.Step(); action != ActionCleanRAM || err == nil {
+		t.Fatalf("Step() = %v, %v, want %v and an error", action, err, ActionCleanRAM)
+	}
+
+	clock.now = clock.now.Add(9 * time.Minute)
+	if action, err := e.Step(); action != ActionNone || err != nil {
+		t.Fatalf("Step() = %v, %v during cooldown, want %v, nil", action, err, ActionNone)
+	}
+
+	clock.now = clock.now.Add(2 * time.Minute)
+	if action, _ := e.Step(); action != ActionCleanRAM {
+		t.Fatalf("Step() = %v after cooldown, want %v", action, ActionCleanRAM)
+	}
+}
+
+func TestSkippedClean(t *testing.T) {
+	e, _, cleaner, clock := newTestEngine(sample(20, 1), sample(50, 1))
+	cleaner.err = fmt.Errorf("%w: a clean is already running", ErrSkipped)
+
+	if action, err := e.Step(); action != ActionNone || err != nil {
+		t.Errorf("Step() = %v, %v, want %v without an error", action, err, ActionNone)
+	}
+
+	// No cooldown or minimum interval, the clean is retried at the next step
+	cleaner.err = nil
+	clock.now = clock.now.Add(time.Second)
+	if action, err := e.Step(); action != ActionCleanRAM || err != nil {
+		t.Errorf("Step() after a skipped clean = %v, %v, want %v", action, err, ActionCleanRAM)
+	}
+}
+
+func TestCleanErrorIsWrapped(t *testing.T) {
+	e, _, cleaner, _ := newTestEngine(sample(20, 1), sample(50, 1))
+	cleaner.err = errors.New("access denied")
+
+	if _, err := e.Step(); !errors.Is(err, cleaner.err) {
+		t.Errorf("Step() error = %v, want it to wrap the cleaner error", err)
+	}
+}
+
+func TestSourceError(t *testing.T) {
+	cleaner := &fakeCleaner{}
+	source := memory.NewFake(sample(10, 50))
+	source.SetError(errors.New("unavailable"))
+	e := New(testPolicy(), source, cleaner, &fakeClock{})
+
+	if action, err := e.Step(); action != ActionNone || err == nil {
+		t.Errorf("Step() = %v, %v, want %v and an error", action, err, ActionNone)
+	}
+	if cleaner.ram+cleaner.standby != 0 {
+		t.Errorf("cleaner was called after a sampling error")
+	}
+}
+
+func TestRun(t *testing.T) {
+	e, _, cleaner, _ := newTestEngine(sample(20, 1), sample(50, 1))
+	stop := make(chan struct{})
+
+	var actions []Action
+	e.Run(stop, 2*time.Second, func(action Action, _ error) {
+		actions = append(actions, action)
+		if len(actions) == 3 {
+			close(stop)
+		}
+	})
+
+	// The stop channel and the next tick may race once, so only the first three steps are checked.
+	if len(actions) < 3 || actions[0] != ActionCleanRAM || actions[1] != ActionNone || actions[2] != ActionNone {
+		t.Errorf("Run() actions = %v, want [clean-ram none none]", actions)
+	}
+	if cleaner.ram != 1 {
+		t.Errorf("CleanRAM called %d times, want 1", cleaner.ram)
+	}
+}
@@ -0,0 +1,76 @@
+// Package backoff Description: This package spaces out the retries of a failing operation.
+// The delay doubles with every consecutive failure up to a maximum and is reset by a success,
+// so that a persistent failure is retried rarely while a transient one recovers quickly.
+package backoff
+
+import (
+	"sync"
+	"time"
+)
+
+// Backoff tracks the consecutive failures of an operation. It is safe for concurrent use.
+type Backoff struct {
+	min, max time.Duration
+
+	mu       sync.Mutex
+	failures int
+	next     time.Time // No attempt before this time
+}
+
+// New creates a Backoff waiting min after the first failure and at most max after the following ones.
+func New(min, max time.Duration) *Backoff {
+	return &Backoff{min: min, max: max}
+}
+
+// Delay returns the wait after the given number of consecutive failures.
+func (b *Backoff) Delay(failures int) time.Duration {
+	if failures <= 0 {
+		return 0
+	}
+	delay := b.min
+	for i := 1; i < failures && delay < b.max; i++ {
+		delay *= 2
+	}
+	return min(delay, b.max)
+}
+
+// Ready reports whether the operation may be attempted at now.
+func (b *Backoff) Ready(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !now.Before(b.next)
+}
+
+// Failure records a failed attempt at now and returns the wait before the next one.
+func (b *Backoff) Failure(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	delay := b.Delay(b.failures)
+	b.next = now.Add(delay)
+	return delay
+}
+
+// Success records a successful attempt and reports whether it ends a series of failures.
+func (b *Backoff) Success() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	recovered := b.failures > 0
+	b.failures = 0
+	b.next = time.Time{}
+	return recovered
+}
+
+// Failures returns the number of consecutive failures.
+func (b *Backoff) Failures() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures
+}
+
+// Next returns the time of the next attempt, zero after a success.
+func (b *Backoff) Next() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.next
+}
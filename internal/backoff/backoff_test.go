@@ -0,0 +1,60 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDelay(t *testing.T) {
+	b := New(2*time.Second, time.Minute)
+	tests := []struct {
+		failures int
+		expected time.Duration
+	}{
+		{failures: 0, expected: 0},
+		{failures: 1, expected: 2 * time.Second},
+		{failures: 2, expected: 4 * time.Second},
+		{failures: 5, expected: 32 * time.Second},
+		{failures: 6, expected: time.Minute},
+		{failures: 1000, expected: time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := b.Delay(tt.failures); got != tt.expected {
+			t.Errorf("Delay(%d) = %v, want %v", tt.failures, got, tt.expected)
+		}
+	}
+}
+
+func TestFailureAndSuccess(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	b := New(time.Second, 10*time.Second)
+
+	if !b.Ready(now) || b.Success() {
+		t.Fatal("a new Backoff should be ready and not recovering")
+	}
+
+	if delay := b.Failure(now); delay != time.Second {
+		t.Errorf("Failure() = %v, want 1s", delay)
+	}
+	now = now.Add(time.Second)
+	if delay := b.Failure(now); delay != 2*time.Second {
+		t.Errorf("second Failure() = %v, want 2s", delay)
+	}
+	if b.Ready(now.Add(time.Second)) {
+		t.Error("Ready() = true before the delay")
+	}
+	if !b.Ready(now.Add(2 * time.Second)) {
+		t.Error("Ready() = false after the delay")
+	}
+	if b.Failures() != 2 || !b.Next().Equal(now.Add(2*time.Second)) {
+		t.Errorf("Failures() = %d, Next() = %v", b.Failures(), b.Next())
+	}
+
+	if !b.Success() {
+		t.Error("Success() = false after failures")
+	}
+	if b.Failures() != 0 || !b.Ready(now) {
+		t.Error("Success() did not reset the backoff")
+	}
+}
@@ -0,0 +1,203 @@
+// Package cli Description: This package implements the headless command-line mode of the application.
+// Commands work through the Backend interface, so they can run from Task Scheduler or a login script
+// without the system tray, and can be tested without WinAPI.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"windows-ram-cleaner/internal/history"
+	"windows-ram-cleaner/internal/inventory"
+	"windows-ram-cleaner/internal/memlist"
+	"windows-ram-cleaner/internal/memory"
+	"windows-ram-cleaner/internal/report"
+)
+
+// Exit codes returned by Run.
+const (
+	ExitOK          = 0 // The command succeeded
+	ExitError       = 1 // The command failed
+	ExitUsage       = 2 // The command line is invalid
+	ExitNotElevated = 3 // The command requires administrator privileges
+	ExitPartial     = 4 // The clean finished but some processes could not be trimmed
+)
+
+// TrayCommand is the default command starting the system tray application.
+const TrayCommand = "tray"
+
+// Backend performs the actual work of the commands.
+type Backend interface {
+	IsElevated() bool
+	CleanStandbyList() (*report.CleanReport, error)
+	CleanRAM(deep bool) (*report.CleanReport, error)
+	MemoryList(command memlist.Command) (*report.CleanReport, error)
+	Memory() (memory.Snapshot, error)
+	AddStartup() error
+	RemoveStartup() error
+	StartupStatus() (bool, error)
+	History() ([]history.Entry, error)
+	Processes() ([]inventory.Process, error)
+	Trim(target inventory.Target) (*report.CleanReport, error)
+}
+
+// env is passed to every command.
+type env struct {
+	stdout  io.Writer
+	stderr  io.Writer
+	backend Backend
+}
+
+type command struct {
+	usage    string
+	summary  string
+	elevated bool
+	run      func(e *env, args []string) int
+}
+
+var commands = map[string]command{
+	"clean-standby": {
+		usage:    "clean-standby [--json]",
+		summary:  "Purge the standby list",
+		elevated: true,
+		run:      runCleanStandby,
+	},
+	"clean-ram": {
+		usage:    "clean-ram [--deep] [--json]",
+		summary:  "Trim working sets of processes (--deep includes critical processes)",
+		elevated: true,
+		run:      runCleanRAM,
+	},
+	"memlist": {
+		usage:    "memlist COMMAND [--json]",
+		summary:  "Run a memory list command: " + strings.Join(memlistNames(), ", "),
+		elevated: true,
+		run:      runMemList,
+	},
+	"trim": {
+		usage:    "trim --pid PID|--name NAME [--tree] [--json]",
+		summary:  "Trim the working set of one process, every process with a name, or a process tree",
+		elevated: true,
+		run:      runTrim,
+	},
+	"ps": {
+		usage:   "ps [--sort KEY] [--limit N] [--json]",
+		summary: "Print the memory usage of processes (sort by ws, private, faults, pid or name)",
+		run:     runPs,
+	},
+	"status": {
+		usage:   "status [--json]",
+		summary: "Print memory statistics",
+		run:     runStatus,
+	},
+	"history": {
+		usage:   "history [--limit N] [--json]",
+		summary: "Print past cleans and statistics",
+		run:     runHistory,
+	},
+	"startup": {
+		usage:   "startup add|remove|status",
+		summary: "Manage starting the application with Windows",
+		run:     runStartup,
+	},
+	TrayCommand: {
+		usage:   TrayCommand,
+		summary: "Run in the system tray (default)",
+	},
+}
+
+// IsTrayCommand reports whether args ask for the system tray mode.
+func IsTrayCommand(args []string) bool {
+	return len(args) == 0 || args[0] == TrayCommand
+}
+
+// Forwardable reports whether the command given by args may run in the running tray application
+// instead of this process: the commands changing memory, which need administrator privileges
+// and must not run at the same time as the cleans of the tray application.
+func Forwardable(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	cmd, ok := commands[args[0]]
+	return ok && cmd.run != nil && cmd.elevated
+}
+
+// Run executes the command given by args and returns the process exit code.
+// The tray command is not handled here, see IsTrayCommand.
+func Run(args []string, stdout, stderr io.Writer, backend Backend) int {
+	e := &env{stdout: stdout, stderr: stderr, backend: backend}
+
+	if len(args) == 0 {
+		printUsage(stderr)
+		return ExitUsage
+	}
+	name, args := args[0], args[1:]
+
+	switch name {
+	case "help", "-h", "-help", "--help":
+		printUsage(stdout)
+		return ExitOK
+	}
+
+	cmd, ok := commands[name]
+	if !ok || cmd.run == nil {
+		fmt.Fprintf(stderr, "unknown headless command %q\n\n", name)
+		printUsage(stderr)
+		return ExitUsage
+	}
+
+	if cmd.elevated && !backend.IsElevated() {
+		fmt.Fprintf(stderr, "%s requires administrator privileges\n", name)
+		return ExitNotElevated
+	}
+
+	return cmd.run(e, args)
+}
+
+// printUsage writes the list of commands to w.
+func printUsage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "Usage: windows-ram-cleaner <command> [options]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-28s %s\n", commands[name].usage, commands[name].summary)
+	}
+}
+
+// newFlagSet creates a flag set writing its errors to the command's stderr.
+func (e *env) newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	return fs
+}
+
+// parse parses args and maps parse errors to exit codes. It returns -1 when the command should continue.
+func (e *env) parse(fs *flag.FlagSet, args []string) int {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK
+		}
+		return ExitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(e.stderr, "unexpected arguments: %s\n", strings.Join(fs.Args(), " "))
+		return ExitUsage
+	}
+	return -1
+}
+
+// fail prints err and returns ExitError.
+func (e *env) fail(format string, err error) int {
+	fmt.Fprintf(e.stderr, format+": %v\n", err)
+	return ExitError
+}
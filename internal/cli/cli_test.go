@@ -0,0 +1,418 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"windows-ram-cleaner/internal/history"
+	"windows-ram-cleaner/internal/inventory"
+	"windows-ram-cleaner/internal/memlist"
+	"windows-ram-cleaner/internal/memory"
+	"windows-ram-cleaner/internal/report"
+	"windows-ram-cleaner/internal/trim"
+)
+
+type fakeBackend struct {
+	elevated bool
+	err      error
+	snapshot memory.Snapshot
+	startup  bool
+	entries  []history.Entry
+	procs    []inventory.Process
+	target   inventory.Target
+	calls    []string
+}
+
+func (b *fakeBackend) IsElevated() bool { return b.elevated }
+
+func (b *fakeBackend) CleanStandbyList() (*report.CleanReport, error) {
+	b.calls = append(b.calls, "clean-standby")
+	r := report.New(report.ModeStandby, time.Time{}, b.snapshot)
+	r.AddStage(report.StageStandby, 2*mb, 0, b.err)
+	r.Finish(time.Time{}, b.snapshot, b.err)
+	return r, b.err
+}
+
+func (b *fakeBackend) CleanRAM(deep bool) (*report.CleanReport, error) {
+	mode := report.ModeBasic
+	if deep {
+		mode = report.ModeDeep
+		b.calls = append(b.calls, "clean-ram-deep")
+	} else {
+		b.calls = append(b.calls, "clean-ram")
+	}
+	r := report.New(mode, time.Time{}, b.snapshot)
+	r.AddProcess(report.ProcessResult{PID: 10, Name: "a.exe", Status: report.ProcessTrimmed, FreedBytes: mb})
+	r.AddStage(report.StageProcesses, mb, 0, b.err)
+	r.Finish(time.Time{}, b.snapshot, b.err)
+	return r, b.err
+}
+
+func (b *fakeBackend) MemoryList(command memlist.Command) (*report.CleanReport, error) {
+	b.calls = append(b.calls, "memlist-"+command.String())
+	r := report.New(report.ModeMemoryList, time.Time{}, b.snapshot)
+	r.AddStage(command.String(), 3*mb, 0, b.err)
+	r.Finish(time.Time{}, b.snapshot, b.err)
+	return r, b.err
+}
+
+func (b *fakeBackend) Memory() (memory.Snapshot, error) {
+	b.calls = append(b.calls, "memory")
+	return b.snapshot, b.err
+}
+
+func (b *fakeBackend) AddStartup() error {
+	b.calls = append(b.calls, "startup-add")
+	return b.err
+}
+
+func (b *fakeBackend) RemoveStartup() error {
+	b.calls = append(b.calls, "startup-remove")
+	return b.err
+}
+
+func (b *fakeBackend) StartupStatus() (bool, error) {
+	b.calls = append(b.calls, "startup-status")
+	return b.startup, b.err
+}
+
+func (b *fakeBackend) History() ([]history.Entry, error) {
+	b.calls = append(b.calls, "history")
+	return b.entries, b.err
+}
+
+func (b *fakeBackend) Processes() ([]inventory.Process, error) {
+	b.calls = append(b.calls, "ps")
+	return b.procs, b.err
+}
+
+func (b *fakeBackend) Trim(target inventory.Target) (*report.CleanReport, error) {
+	b.calls = append(b.calls, "trim")
+	b.target = target
+	r := report.New(report.ModeTargeted, time.Time{}, b.snapshot)
+	r.Target = target.String()
+	r.AddStage(report.StageProcesses, 3*mb, 0, b.err)
+	r.AddProcess(report.ProcessResult{PID: 100, Name: "chrome.exe", Status: report.ProcessTrimmed, FreedBytes: 3 * mb, WorkingSetBefore: 4 * mb, WorkingSetAfter: mb})
+	r.Finish(time.Time{}, b.snapshot, b.err)
+	return r, b.err
+}
+
+func run(b *fakeBackend, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := Run(args, &stdout, &stderr, b)
+	return code, stdout.String(), stderr.String()
+}
+
+// partialErr returns the error of a clean that could not trim one process.
+func partialErr() error {
+	errs := &trim.Errors{Attempted: 2}
+	errs.Add(4, "csrss.exe", "open process", errors.New("access denied"))
+	return errs
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		backend  fakeBackend
+		code     int
+		calls    string
+		contains string
+	}{
+		{name: "clean standby", args: []string{"clean-standby"}, backend: fakeBackend{elevated: true}, code: ExitOK, calls: "clean-standby", contains: "Freed 2.0 MB"},
+		{name: "clean ram", args: []string{"clean-ram"}, backend: fakeBackend{elevated: true}, code: ExitOK, calls: "clean-ram", contains: "1 trimmed"},
+		{name: "clean ram deep", args: []string{"clean-ram", "--deep"}, backend: fakeBackend{elevated: true}, code: ExitOK, calls: "clean-ram-deep"},
+		{name: "clean without admin", args: []string{"clean-standby"}, code: ExitNotElevated, contains: "administrator"},
+		{name: "clean failure", args: []string{"clean-ram"}, backend: fakeBackend{elevated: true, err: errors.New("denied")}, code: ExitError, calls: "clean-ram", contains: "denied"},
+		{name: "clean partial failure", args: []string{"clean-ram"}, backend: fakeBackend{elevated: true, err: partialErr()}, code: ExitPartial, calls: "clean-ram", contains: "1 trimmed"},
+		{name: "memlist", args: []string{"memlist", "flush-modified"}, backend: fakeBackend{elevated: true}, code: ExitOK, calls: "memlist-flush-modified", contains: "Freed 3.0 MB"},
+		{name: "memlist json", args: []string{"memlist", "combine-pages", "--json"}, backend: fakeBackend{elevated: true}, code: ExitOK, calls: "memlist-combine-pages", contains: `"mode": "memory-list"`},
+		{name: "memlist without admin", args: []string{"memlist", "purge-standby"}, code: ExitNotElevated},
+		{name: "memlist unknown command", args: []string{"memlist", "defrag"}, backend: fakeBackend{elevated: true}, code: ExitUsage, contains: "unknown memory list command"},
+		{name: "memlist missing command", args: []string{"memlist", "--json"}, backend: fakeBackend{elevated: true}, code: ExitUsage, contains: "empty-working-sets|"},
+		{name: "unknown flag", args: []string{"clean-ram", "--fast"}, backend: fakeBackend{elevated: true}, code: ExitUsage},
+		{name: "extra argument", args: []string{"clean-standby", "now"}, backend: fakeBackend{elevated: true}, code: ExitUsage},
+		{name: "status text", args: []string{"status"}, backend: fakeBackend{snapshot: memory.Snapshot{StandbySize: 512 * mb}}, code: ExitOK, calls: "memory", contains: "512 MB"},
+		{name: "status standby priorities", args: []string{"status"}, backend: fakeBackend{snapshot: memory.Snapshot{StandbyByPriority: [memory.StandbyPriorities]uint64{7: 300 * mb}}}, code: ExitOK, calls: "memory", contains: "High 5-7   : 300 MB"},
+		{name: "status failure", args: []string{"status"}, backend: fakeBackend{err: errors.New("unavailable")}, code: ExitError, calls: "memory"},
+		{name: "startup add", args: []string{"startup", "add"}, code: ExitOK, calls: "startup-add"},
+		{name: "startup remove", args: []string{"startup", "remove"}, code: ExitOK, calls: "startup-remove"},
+		{name: "startup status enabled", args: []string{"startup", "status"}, backend: fakeBackend{startup: true}, code: ExitOK, calls: "startup-status", contains: "enabled"},
+		{name: "startup status disabled", args: []string{"startup", "status"}, code: ExitOK, calls: "startup-status", contains: "disabled"},
+		{name: "startup unknown action", args: []string{"startup", "toggle"}, code: ExitUsage},
+		{name: "startup missing action", args: []string{"startup"}, code: ExitUsage},
+		{name: "unknown command", args: []string{"defrag"}, code: ExitUsage, contains: "unknown"},
+		{name: "tray is not headless", args: []string{"tray"}, code: ExitUsage},
+		{name: "no command", args: nil, code: ExitUsage, contains: "Usage"},
+		{name: "help", args: []string{"help"}, code: ExitOK, contains: "clean-ram [--deep]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := tt.backend
+			code, stdout, stderr := run(&backend, tt.args...)
+
+			if code != tt.code {
+				t.Errorf("Run() = %d, want %d (stderr: %q)", code, tt.code, stderr)
+			}
+			if calls := strings.Join(backend.calls, ","); calls != tt.calls {
+				t.Errorf("backend calls = %q, want %q", calls, tt.calls)
+			}
+			if !strings.Contains(stdout+stderr, tt.contains) {
+				t.Errorf("output %q does not contain %q", stdout+stderr, tt.contains)
+			}
+		})
+	}
+}
+
+func TestStatusJSON(t *testing.T) {
+	backend := &fakeBackend{snapshot: memory.Snapshot{TotalSize: 100, AvailableSize: 40, StandbySize: 20}}
+	code, stdout, _ := run(backend, "status", "--json")
+	if code != ExitOK {
+		t.Fatalf("Run() = %d, want %d", code, ExitOK)
+	}
+
+	var s memory.Snapshot
+	if err := json.Unmarshal([]byte(stdout), &s); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if s != backend.snapshot {
+		t.Errorf("decoded snapshot = %+v, want %+v", s, backend.snapshot)
+	}
+	if !strings.Contains(stdout, `"standby_bytes": 20`) {
+		t.Errorf("output %q does not contain standby_bytes", stdout)
+	}
+}
+
+func TestCleanJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		err  error
+		code int
+		mode report.Mode
+	}{
+		{name: "standby", args: []string{"clean-standby", "--json"}, code: ExitOK, mode: report.ModeStandby},
+		{name: "deep", args: []string{"clean-ram", "--deep", "--json"}, code: ExitOK, mode: report.ModeDeep},
+		{name: "failed clean still prints the report", args: []string{"clean-ram", "--json"}, err: errors.New("denied"), code: ExitError, mode: report.ModeBasic},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, _ := run(&fakeBackend{elevated: true, err: tt.err}, tt.args...)
+			if code != tt.code {
+				t.Errorf("Run() = %d, want %d", code, tt.code)
+			}
+
+			var r report.CleanReport
+			if err := json.Unmarshal([]byte(stdout), &r); err != nil {
+				t.Fatalf("output is not a JSON report: %v", err)
+			}
+			if r.Mode != tt.mode || len(r.Stages) != 1 {
+				t.Errorf("decoded report = %+v", r)
+			}
+		})
+	}
+}
+
+func historyEntries() []history.Entry {
+	var entries []history.Entry
+	for i, trigger := range []history.Trigger{history.TriggerAuto, history.TriggerManual, history.TriggerCLI} {
+		at := time.Date(2024, 3, 10, 12, i, 0, 0, time.Local)
+		r := report.New(report.ModeBasic, at, memory.Snapshot{})
+		r.AddStage(report.StageProcesses, uint64(i+1)*mb, 0, nil)
+		r.Finish(at, memory.Snapshot{}, nil)
+		entries = append(entries, history.NewEntry(trigger, r))
+	}
+	return entries
+}
+
+func TestHistory(t *testing.T) {
+	backend := &fakeBackend{entries: historyEntries()}
+	code, stdout, _ := run(backend, "history", "--limit", "2")
+	if code != ExitOK {
+		t.Fatalf("Run() = %d, want %d", code, ExitOK)
+	}
+
+	for _, expected := range []string{"Cleans       : 3", "Average gain : 2.0 MB", "Last clean   : 2024-03-10 12:02:00 (cli, 3.0 MB)", "manual", "2024-03-10 12:02:00  cli"} {
+		if !strings.Contains(stdout, expected) {
+			t.Errorf("output does not contain %q:\n%s", expected, stdout)
+		}
+	}
+	if strings.Contains(stdout, "auto") {
+		t.Errorf("--limit 2 should not print the oldest clean:\n%s", stdout)
+	}
+}
+
+func TestHistoryJSON(t *testing.T) {
+	backend := &fakeBackend{entries: historyEntries()}
+	code, stdout, _ := run(backend, "history", "--json", "--limit", "0")
+	if code != ExitOK {
+		t.Fatalf("Run() = %d, want %d", code, ExitOK)
+	}
+
+	var decoded struct {
+		Stats   history.Stats   `json:"stats"`
+		Entries []history.Entry `json:"entries"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &decoded); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if decoded.Stats.Cleans != 3 || len(decoded.Entries) != 3 || decoded.Stats.TotalFreed != 6*mb {
+		t.Errorf("decoded history = %+v", decoded)
+	}
+}
+
+func TestHistoryErrors(t *testing.T) {
+	if code, _, _ := run(&fakeBackend{}, "history", "--limit", "-1"); code != ExitUsage {
+		t.Errorf("negative limit: Run() = %d, want %d", code, ExitUsage)
+	}
+	if code, _, _ := run(&fakeBackend{err: errors.New("locked")}, "history"); code != ExitError {
+		t.Errorf("read failure: Run() = %d, want %d", code, ExitError)
+	}
+}
+
+func processes() []inventory.Process {
+	return []inventory.Process{
+		{PID: 4, Name: "System", WorkingSet: 1 * mb, Critical: true},
+		{PID: 100, ParentPID: 4, Name: "chrome.exe", WorkingSet: 300 * mb, PrivateBytes: 200 * mb, PageFaults: 5000},
+		{PID: 200, ParentPID: 100, Name: "code.exe", WorkingSet: 150 * mb, PrivateBytes: 400 * mb, PageFaults: 100},
+	}
+}
+
+func TestPs(t *testing.T) {
+	backend := &fakeBackend{procs: processes()}
+	code, stdout, _ := run(backend, "ps", "--limit", "2")
+	if code != ExitOK {
+		t.Fatalf("Run() = %d, want %d", code, ExitOK)
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want a header and 2 processes:\n%s", len(lines), stdout)
+	}
+	if !strings.HasPrefix(lines[1], "100 ") || !strings.Contains(lines[1], "300.0 MB") {
+		t.Errorf("first process = %q, want chrome.exe", lines[1])
+	}
+	if strings.Contains(stdout, "System") {
+		t.Errorf("--limit 2 should not print the smallest process:\n%s", stdout)
+	}
+}
+
+func TestPsJSON(t *testing.T) {
+	backend := &fakeBackend{procs: processes()}
+	code, stdout, _ := run(backend, "ps", "--json", "--sort", "private", "--limit", "0")
+	if code != ExitOK {
+		t.Fatalf("Run() = %d, want %d", code, ExitOK)
+	}
+
+	var decoded []inventory.Process
+	if err := json.Unmarshal([]byte(stdout), &decoded); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if len(decoded) != 3 || decoded[0].Name != "code.exe" || !decoded[2].Critical {
+		t.Errorf("decoded processes = %+v", decoded)
+	}
+}
+
+func TestPsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend *fakeBackend
+		args    []string
+		want    int
+	}{
+		{"unknown sort key", &fakeBackend{}, []string{"ps", "--sort", "size"}, ExitUsage},
+		{"negative limit", &fakeBackend{}, []string{"ps", "--limit", "-1"}, ExitUsage},
+		{"listing failure", &fakeBackend{err: errors.New("access denied")}, []string{"ps"}, ExitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code, _, _ := run(tt.backend, tt.args...); code != tt.want {
+				t.Errorf("Run() = %d, want %d", code, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrim(t *testing.T) {
+	backend := &fakeBackend{elevated: true}
+	code, stdout, _ := run(backend, "trim", "--name", "chrome.exe", "--tree")
+	if code != ExitOK {
+		t.Fatalf("Run() = %d, want %d", code, ExitOK)
+	}
+	if expected := (inventory.Target{Name: "chrome.exe", Tree: true}); backend.target != expected {
+		t.Errorf("target = %+v, want %+v", backend.target, expected)
+	}
+	for _, expected := range []string{"chrome.exe and children trimmed", "chrome.exe (100): 4.0 MB -> 1.0 MB"} {
+		if !strings.Contains(stdout, expected) {
+			t.Errorf("output does not contain %q:\n%s", expected, stdout)
+		}
+	}
+}
+
+func TestTrimErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend *fakeBackend
+		args    []string
+		want    int
+	}{
+		{"no target", &fakeBackend{elevated: true}, []string{"trim"}, ExitUsage},
+		{"pid and name", &fakeBackend{elevated: true}, []string{"trim", "--pid", "4", "--name", "a.exe"}, ExitUsage},
+		{"pid overflow", &fakeBackend{elevated: true}, []string{"trim", "--pid", "4294967296"}, ExitUsage},
+		{"not elevated", &fakeBackend{}, []string{"trim", "--pid", "4"}, ExitNotElevated},
+		{"no process", &fakeBackend{elevated: true, err: inventory.ErrNoProcess}, []string{"trim", "--pid", "4"}, ExitError},
+		{"partial", &fakeBackend{elevated: true, err: partialErr()}, []string{"trim", "--name", "a.exe"}, ExitPartial},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code, _, _ := run(tt.backend, tt.args...); code != tt.want {
+				t.Errorf("Run() = %d, want %d", code, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsTrayCommand(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected bool
+	}{
+		{args: nil, expected: true},
+		{args: []string{"tray"}, expected: true},
+		{args: []string{"status"}, expected: false},
+	}
+
+	for _, tt := range tests {
+		if result := IsTrayCommand(tt.args); result != tt.expected {
+			t.Errorf("IsTrayCommand(%v) = %v, want %v", tt.args, result, tt.expected)
+		}
+	}
+}
+
+func TestForwardable(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected bool
+	}{
+		{args: nil, expected: false},
+		{args: []string{"tray"}, expected: false},
+		{args: []string{"status"}, expected: false},
+		{args: []string{"startup", "add"}, expected: false},
+		{args: []string{"unknown"}, expected: false},
+		{args: []string{"clean-standby"}, expected: true},
+		{args: []string{"clean-ram", "--deep"}, expected: true},
+		{args: []string{"trim", "--pid", "42"}, expected: true},
+	}
+
+	for _, tt := range tests {
+		if result := Forwardable(tt.args); result != tt.expected {
+			t.Errorf("Forwardable(%v) = %v, want %v", tt.args, result, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+// Description: This file contains the implementations of the command-line commands.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"windows-ram-cleaner/internal/history"
+	"windows-ram-cleaner/internal/inventory"
+	"windows-ram-cleaner/internal/memlist"
+	"windows-ram-cleaner/internal/memory"
+	"windows-ram-cleaner/internal/report"
+	"windows-ram-cleaner/internal/trim"
+)
+
+const mb = 1024 * 1024
+
+// timeLayout is used for timestamps in text output.
+const timeLayout = "2006-01-02 15:04:05"
+
+// runCleanStandby purges the standby list.
+func runCleanStandby(e *env, args []string) int {
+	fs := e.newFlagSet("clean-standby")
+	asJSON := fs.Bool("json", false, "print the clean report as JSON")
+	if code := e.parse(fs, args); code >= 0 {
+		return code
+	}
+
+	r, err := e.backend.CleanStandbyList()
+	return e.printReport("standby list cleaned", r, err, *asJSON)
+}
+
+// runCleanRAM trims working sets, including critical processes with --deep.
+func runCleanRAM(e *env, args []string) int {
+	fs := e.newFlagSet("clean-ram")
+	deep := fs.Bool("deep", false, "also trim critical processes")
+	asJSON := fs.Bool("json", false, "print the clean report as JSON")
+	if code := e.parse(fs, args); code >= 0 {
+		return code
+	}
+
+	r, err := e.backend.CleanRAM(*deep)
+	return e.printReport("RAM cleaned", r, err, *asJSON)
+}
+
+// runTrim trims the processes selected by PID or name.
+func runTrim(e *env, args []string) int {
+	fs := e.newFlagSet("trim")
+	pid := fs.Uint("pid", 0, "PID of the process to trim")
+	name := fs.String("name", "", "name of the processes to trim, e.g. chrome.exe")
+	tree := fs.Bool("tree", false, "also trim the child processes")
+	asJSON := fs.Bool("json", false, "print the clean report as JSON")
+	if code := e.parse(fs, args); code >= 0 {
+		return code
+	}
+	if *pid > math.MaxUint32 {
+		fmt.Fprintf(e.stderr, "invalid PID %d\n", *pid)
+		return ExitUsage
+	}
+	target := inventory.Target{PID: uint32(*pid), Name: *name, Tree: *tree}
+	if err := target.Validate(); err != nil {
+		fmt.Fprintln(e.stderr, err)
+		return ExitUsage
+	}
+
+	r, err := e.backend.Trim(target)
+	return e.printReport(target.String()+" trimmed", r, err, *asJSON)
+}
+
+// runMemList runs the memory list command named by the first argument.
+func runMemList(e *env, args []string) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintf(e.stderr, "usage: memlist %s [--json]\n", strings.Join(memlistNames(), "|"))
+		return ExitUsage
+	}
+	command, err := memlist.Parse(args[0])
+	if err != nil {
+		fmt.Fprintln(e.stderr, err)
+		return ExitUsage
+	}
+
+	fs := e.newFlagSet("memlist " + command.String())
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	if code := e.parse(fs, args[1:]); code >= 0 {
+		return code
+	}
+
+	r, err := e.backend.MemoryList(command)
+	return e.printReport(command.Title()+" done", r, err, *asJSON)
+}
+
+// memlistNames returns the command-line names of the memory list commands.
+func memlistNames() []string {
+	names := make([]string, len(memlist.Commands))
+	for i, c := range memlist.Commands {
+		names[i] = c.String()
+	}
+	return names
+}
+
+// printReport prints the outcome of a clean. The report is printed even if the clean failed,
+// so that scripts can see which stage and processes failed.
+func (e *env) printReport(title string, r *report.CleanReport, err error, asJSON bool) int {
+	partial := trim.IsPartial(err)
+	switch {
+	case r != nil && asJSON:
+		if code := e.writeJSON(r); code != ExitOK {
+			return code
+		}
+	case r != nil && (err == nil || partial):
+		fmt.Fprintln(e.stdout, title)
+		fmt.Fprintln(e.stdout, r.Summary())
+	}
+
+	switch {
+	case partial:
+		fmt.Fprintf(e.stderr, "some processes could not be trimmed: %v\n", err)
+		return ExitPartial
+	case err != nil:
+		return e.fail("clean failed", err)
+	}
+	return ExitOK
+}
+
+// runStatus prints the memory statistics as text or JSON.
+func runStatus(e *env, args []string) int {
+	fs := e.newFlagSet("status")
+	asJSON := fs.Bool("json", false, "print machine-readable JSON")
+	if code := e.parse(fs, args); code >= 0 {
+		return code
+	}
+
+	s, err := e.backend.Memory()
+	if err != nil {
+		return e.fail("can't get memory info", err)
+	}
+
+	if *asJSON {
+		return e.writeJSON(s)
+	}
+	printSnapshot(e, s)
+	return ExitOK
+}
+
+// runPs prints the memory usage of processes as a table or JSON.
+func runPs(e *env, args []string) int {
+	fs := e.newFlagSet("ps")
+	sortBy := fs.String("sort", string(inventory.SortWorkingSet), "sort order: ws, private, faults, pid or name")
+	limit := fs.Int("limit", 20, "number of processes to print, 0 for all")
+	asJSON := fs.Bool("json", false, "print machine-readable JSON")
+	if code := e.parse(fs, args); code >= 0 {
+		return code
+	}
+	key, err := inventory.ParseSortKey(*sortBy)
+	if err != nil {
+		fmt.Fprintln(e.stderr, err)
+		return ExitUsage
+	}
+	if *limit < 0 {
+		fmt.Fprintln(e.stderr, "--limit must not be negative")
+		return ExitUsage
+	}
+
+	processes, err := e.backend.Processes()
+	if err != nil {
+		return e.fail("can't list processes", err)
+	}
+	processes = inventory.Top(processes, *limit, key)
+
+	if *asJSON {
+		return e.writeJSON(processes)
+	}
+
+	w := tabwriter.NewWriter(e.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PID\tPPID\tNAME\tWORKING SET\tPRIVATE\tPAGE FAULTS\tFLAGS")
+	for _, p := range processes {
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%d\t%s\n",
+			p.PID, p.ParentPID, p.Name, report.FormatBytes(p.WorkingSet), report.FormatBytes(p.PrivateBytes),
+			p.PageFaults, p.Flags())
+	}
+	if err := w.Flush(); err != nil {
+		return e.fail("can't write output", err)
+	}
+	return ExitOK
+}
+
+// runStartup manages the startup registry entry.
+func runStartup(e *env, args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(e.stderr, "usage: startup add|remove|status")
+		return ExitUsage
+	}
+
+	switch args[0] {
+	case "add":
+		if err := e.backend.AddStartup(); err != nil {
+			return e.fail("can't create startup task", err)
+		}
+		fmt.Fprintln(e.stdout, "added to startup")
+	case "remove":
+		if err := e.backend.RemoveStartup(); err != nil {
+			return e.fail("can't delete startup task", err)
+		}
+		fmt.Fprintln(e.stdout, "removed from startup")
+	case "status":
+		exists, err := e.backend.StartupStatus()
+		if err != nil {
+			return e.fail("can't check startup task", err)
+		}
+		if exists {
+			fmt.Fprintln(e.stdout, "enabled")
+		} else {
+			fmt.Fprintln(e.stdout, "disabled")
+		}
+	default:
+		fmt.Fprintf(e.stderr, "unknown startup action %q\n", args[0])
+		return ExitUsage
+	}
+	return ExitOK
+}
+
+// runHistory prints the statistics and the latest cleans.
+func runHistory(e *env, args []string) int {
+	fs := e.newFlagSet("history")
+	limit := fs.Int("limit", 10, "number of cleans to print, 0 for all")
+	asJSON := fs.Bool("json", false, "print machine-readable JSON")
+	if code := e.parse(fs, args); code >= 0 {
+		return code
+	}
+	if *limit < 0 {
+		fmt.Fprintln(e.stderr, "--limit must not be negative")
+		return ExitUsage
+	}
+
+	entries, err := e.backend.History()
+	if err != nil {
+		return e.fail("can't read history", err)
+	}
+	stats := history.ComputeStats(entries, time.Now())
+	if *limit > 0 && len(entries) > *limit {
+		entries = entries[len(entries)-*limit:]
+	}
+
+	if *asJSON {
+		return e.writeJSON(struct {
+			Stats   history.Stats   `json:"stats"`
+			Entries []history.Entry `json:"entries"`
+		}{stats, entries})
+	}
+
+	fmt.Fprintf(e.stdout, "Cleans       : %d (%d today)\n", stats.Cleans, stats.CleansToday)
+	fmt.Fprintf(e.stdout, "Freed today  : %s\n", report.FormatBytes(stats.FreedToday))
+	fmt.Fprintf(e.stdout, "Average gain : %s\n", report.FormatBytes(stats.AverageFreed))
+	if stats.Cleans > 0 {
+		fmt.Fprintf(e.stdout, "Last clean   : %s (%s, %s)\n",
+			stats.LastClean.Format(timeLayout), stats.LastTrigger, report.FormatBytes(stats.LastFreed))
+	}
+	if len(entries) == 0 {
+		return ExitOK
+	}
+
+	w := tabwriter.NewWriter(e.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "TIME\tTRIGGER\tMODE\tFREED\tTRIMMED/SKIPPED/FAILED\tERROR")
+	for _, entry := range entries {
+		var errText string
+		if entry.Report != nil {
+			errText = entry.Report.Error
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d/%d/%d\t%s\n",
+			entry.Time.Format(timeLayout), entry.Trigger, entry.Mode, report.FormatBytes(entry.FreedBytes()),
+			entry.Trimmed, entry.Skipped, entry.Failed, errText)
+	}
+	w.Flush()
+	return ExitOK
+}
+
+// writeJSON prints v as indented JSON.
+func (e *env) writeJSON(v any) int {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return e.fail("can't encode JSON", err)
+	}
+	fmt.Fprintln(e.stdout, string(data))
+	return ExitOK
+}
+
+// printSnapshot prints the memory statistics as an aligned table.
+func printSnapshot(e *env, s memory.Snapshot) {
+	fmt.Fprintf(e.stdout, "Total        : %d MB\n", s.TotalSize/mb)
+	fmt.Fprintf(e.stdout, "Available    : %d MB (%.1f%%)\n", s.AvailableSize/mb, s.AvailablePercent())
+	fmt.Fprintf(e.stdout, "Free         : %d MB\n", s.FreeSize/mb)
+	fmt.Fprintf(e.stdout, "Standby List : %d MB\n", s.StandbySize/mb)
+	if s.HasStandbyPriorities() {
+		fmt.Fprintf(e.stdout, "  Low 0      : %d MB\n", s.StandbyLow()/mb)
+		fmt.Fprintf(e.stdout, "  Normal 1-4 : %d MB\n", s.StandbyNormal()/mb)
+		fmt.Fprintf(e.stdout, "  High 5-7   : %d MB\n", s.StandbyHigh()/mb)
+		priorities := make([]string, len(s.StandbyByPriority))
+		for i, size := range s.StandbyByPriority {
+			priorities[i] = fmt.Sprintf("%d=%d", i, size/mb)
+		}
+		fmt.Fprintf(e.stdout, "  Priorities : %s MB\n", strings.Join(priorities, " "))
+	}
+	fmt.Fprintf(e.stdout, "Modified     : %d MB\n", s.ModifiedSize/mb)
+	fmt.Fprintf(e.stdout, "Commit       : %d / %d MB\n", s.CommitSize/mb, s.CommitLimit/mb)
+	fmt.Fprintf(e.stdout, "Page File    : %d / %d MB free\n", s.PageFileFree/mb, s.PageFileSize/mb)
+}
@@ -0,0 +1,346 @@
+// Package config Description: This package contains the persistent, versioned configuration of the application.
+// The configuration is stored as JSON in the user config directory and reloaded when the file changes.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"windows-ram-cleaner/internal/api"
+	"windows-ram-cleaner/internal/applog"
+	"windows-ram-cleaner/internal/autoclean"
+	"windows-ram-cleaner/internal/history"
+	"windows-ram-cleaner/internal/notify"
+	"windows-ram-cleaner/internal/procrules"
+	"windows-ram-cleaner/internal/schedule"
+	"windows-ram-cleaner/internal/trim"
+	"windows-ram-cleaner/internal/wslimit"
+)
+
+// CurrentVersion is the schema version written by this build.
+const CurrentVersion = 1
+
+const mb = 1024 * 1024
+
+// Config is the root of the configuration file.
+type Config struct {
+	Version         int             `json:"version"`
+	RefreshInterval Duration        `json:"refresh_interval"`
+	AutoClean       AutoCleanConfig `json:"auto_clean"`
+	Cleaning        CleaningConfig  `json:"cleaning"`
+	Startup         StartupConfig   `json:"startup"`
+	History         HistoryConfig   `json:"history"`
+	Limits          LimitsConfig    `json:"working_set_limits"`
+	Notifications   NotifyConfig    `json:"notifications"`
+	Log             LogConfig       `json:"log"`
+	API             APIConfig       `json:"api"`
+	Metrics         MetricsConfig   `json:"metrics"`
+	// Schedule is the cleans run at scheduled times, independent of auto_clean.
+	Schedule []schedule.Job `json:"schedule"`
+}
+
+// AutoCleanConfig configures the automatic cleaning engine.
+type AutoCleanConfig struct {
+	Enabled bool `json:"enabled"`
+	// MemoryLoadPercent triggers a RAM clean when the memory load exceeds it. Zero disables the trigger.
+	MemoryLoadPercent float64 `json:"memory_load_percent"`
+	// HysteresisPercent is how far below MemoryLoadPercent the load must drop before the trigger re-arms.
+	HysteresisPercent float64 `json:"hysteresis_percent"`
+	// StandbyMB triggers a standby list purge when the standby list exceeds it. Zero disables the trigger.
+	StandbyMB uint64 `json:"standby_mb"`
+	// StandbyHysteresisMB is how far below StandbyMB the standby list must shrink before the trigger re-arms.
+	StandbyHysteresisMB uint64 `json:"standby_hysteresis_mb"`
+	// StandbyLowPriorityOnly makes the standby trigger watch and purge only the lowest priority
+	// standby pages, keeping the cached data of applications.
+	StandbyLowPriorityOnly bool     `json:"standby_low_priority_only"`
+	MinInterval            Duration `json:"min_interval"`
+	Cooldown               Duration `json:"cooldown"`
+}
+
+// CleaningConfig configures how processes are trimmed.
+type CleaningConfig struct {
+	// TrimDelay is the average time between starting two trims. It paces the cleaning to limit
+	// the load on the system. Zero disables pacing.
+	TrimDelay Duration `json:"trim_delay"`
+	// Concurrency is the number of processes trimmed at the same time.
+	Concurrency int `json:"concurrency"`
+	// ProcessTimeout is how long trimming one process may take before it is recorded as failed.
+	ProcessTimeout Duration `json:"process_timeout"`
+	// CriticalProcesses are executable names (case-insensitive globs) that are skipped by a basic clean.
+	CriticalProcesses []string `json:"critical_processes"`
+	// Rules allow or deny trimming processes. They are evaluated in order before the critical
+	// processes and also apply to a deep clean.
+	Rules []procrules.Rule `json:"rules"`
+}
+
+// StartupConfig configures starting the application with Windows.
+type StartupConfig struct {
+	// TaskName is the name of the startup registry entry.
+	TaskName string `json:"task_name"`
+}
+
+// HistoryConfig configures the history of cleans.
+type HistoryConfig struct {
+	// MaxEntries is the number of cleans kept. Zero means no limit.
+	MaxEntries int `json:"max_entries"`
+	// MaxAge drops cleans older than it. Zero means no limit.
+	MaxAge Duration `json:"max_age"`
+}
+
+// LimitsConfig configures the working set limits of processes.
+type LimitsConfig struct {
+	// Interval is how often new processes get their limits and the limits are re-applied,
+	// since applications may change their own limits.
+	Interval Duration `json:"interval"`
+	// Processes are the limits, the first matching one applies to a process.
+	Processes []wslimit.Limit `json:"processes"`
+}
+
+// NotifyConfig configures the tray notifications.
+type NotifyConfig struct {
+	// LowMemoryPercent shows an alert when the memory load exceeds it. Zero disables the alert.
+	LowMemoryPercent float64 `json:"low_memory_percent"`
+	// AutoCleanResults shows the result of every automatic clean, failures are always shown.
+	AutoCleanResults bool `json:"auto_clean_results"`
+	// MaxPerMinute is the number of notifications shown per minute. Zero means no limit.
+	MaxPerMinute int `json:"max_per_minute"`
+	// DedupWindow hides a notification repeating one shown less than DedupWindow ago.
+	DedupWindow Duration `json:"dedup_window"`
+}
+
+// LogConfig configures the log file.
+type LogConfig struct {
+	// Level is the minimum level written: debug, info, warn or error.
+	Level string `json:"level"`
+	// MaxSizeMB is the size at which the log file is rotated.
+	MaxSizeMB int `json:"max_size_mb"`
+	// MaxFiles is the number of rotated files kept besides the current one.
+	MaxFiles int `json:"max_files"`
+}
+
+// APIConfig configures the local HTTP/JSON control API.
+type APIConfig struct {
+	Enabled bool `json:"enabled"`
+	// Address is the loopback host:port the API listens on.
+	Address string `json:"address"`
+	// Token is the bearer token every request must send.
+	Token string `json:"token"`
+}
+
+// MetricsConfig configures the Prometheus metrics endpoint.
+type MetricsConfig struct {
+	Enabled bool `json:"enabled"`
+	// Address is the host:port serving /metrics. A non-loopback address lets a remote Prometheus
+	// scrape the metrics, which are read-only and need no token.
+	Address string `json:"address"`
+}
+
+// Default returns the configuration used when no file exists.
+func Default() Config {
+	return Config{
+		Version:         CurrentVersion,
+		RefreshInterval: Duration(2 * time.Second),
+		AutoClean: AutoCleanConfig{
+			Enabled:             true,
+			MemoryLoadPercent:   65,
+			HysteresisPercent:   5,
+			StandbyMB:           0,
+			StandbyHysteresisMB: 256,
+			MinInterval:         Duration(time.Minute),
+			Cooldown:            Duration(10 * time.Minute),
+		},
+		Cleaning: CleaningConfig{
+			TrimDelay:         Duration(trim.DefaultOptions().Interval),
+			Concurrency:       trim.DefaultOptions().Concurrency,
+			ProcessTimeout:    Duration(trim.DefaultOptions().Timeout),
+			CriticalProcesses: procrules.DefaultCritical(),
+			Rules:             []procrules.Rule{},
+		},
+		Startup: StartupConfig{
+			TaskName: "WindowsRAMCleaner",
+		},
+		History: HistoryConfig{
+			MaxEntries: 1000,
+			MaxAge:     Duration(30 * 24 * time.Hour),
+		},
+		Limits: LimitsConfig{
+			Interval:  Duration(5 * time.Second),
+			Processes: []wslimit.Limit{},
+		},
+		Notifications: NotifyConfig{
+			LowMemoryPercent: 90,
+			AutoCleanResults: false,
+			MaxPerMinute:     notify.DefaultOptions().MaxPerMinute,
+			DedupWindow:      Duration(notify.DefaultOptions().DedupWindow),
+		},
+		Log: LogConfig{
+			Level:     "info",
+			MaxSizeMB: 5,
+			MaxFiles:  3,
+		},
+		API: APIConfig{
+			Enabled: false,
+			Address: "127.0.0.1:8765",
+		},
+		Metrics: MetricsConfig{
+			Enabled: false,
+			Address: "127.0.0.1:9182",
+		},
+		Schedule: []schedule.Job{},
+	}
+}
+
+// Validate checks the configuration and returns all problems at once.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Version == CurrentVersion, "version: expected %d, got %d", CurrentVersion, c.Version)
+	check(c.RefreshInterval.Std() >= 500*time.Millisecond && c.RefreshInterval.Std() <= time.Hour,
+		"refresh_interval: must be between 500ms and 1h, got %s", c.RefreshInterval)
+
+	a := c.AutoClean
+	check(a.MemoryLoadPercent >= 0 && a.MemoryLoadPercent < 100,
+		"auto_clean.memory_load_percent: must be between 0 and 100, got %v", a.MemoryLoadPercent)
+	check(a.HysteresisPercent >= 0 && a.HysteresisPercent <= a.MemoryLoadPercent,
+		"auto_clean.hysteresis_percent: must be between 0 and memory_load_percent, got %v", a.HysteresisPercent)
+	check(a.StandbyMB == 0 || a.StandbyHysteresisMB < a.StandbyMB,
+		"auto_clean.standby_hysteresis_mb: must be less than standby_mb, got %d", a.StandbyHysteresisMB)
+	check(a.MinInterval >= 0, "auto_clean.min_interval: must not be negative, got %s", a.MinInterval)
+	check(a.Cooldown >= 0, "auto_clean.cooldown: must not be negative, got %s", a.Cooldown)
+
+	check(c.Cleaning.TrimDelay >= 0 && c.Cleaning.TrimDelay.Std() <= time.Second,
+		"cleaning.trim_delay: must be between 0 and 1s, got %s", c.Cleaning.TrimDelay)
+	check(c.Cleaning.Concurrency >= 1 && c.Cleaning.Concurrency <= 64,
+		"cleaning.concurrency: must be between 1 and 64, got %d", c.Cleaning.Concurrency)
+	check(c.Cleaning.ProcessTimeout.Std() >= 100*time.Millisecond && c.Cleaning.ProcessTimeout.Std() <= time.Minute,
+		"cleaning.process_timeout: must be between 100ms and 1m, got %s", c.Cleaning.ProcessTimeout)
+	for i, name := range c.Cleaning.CriticalProcesses {
+		check(strings.TrimSpace(name) != "", "cleaning.critical_processes[%d]: must not be empty", i)
+	}
+	if _, err := procrules.New(c.Cleaning.Rules, nil); err != nil {
+		errs = append(errs, fmt.Errorf("cleaning.rules: %v", err))
+	}
+
+	check(strings.TrimSpace(c.Startup.TaskName) != "" && !strings.ContainsAny(c.Startup.TaskName, `\/`),
+		"startup.task_name: must be a non-empty name without slashes, got %q", c.Startup.TaskName)
+
+	check(c.History.MaxEntries >= 0, "history.max_entries: must not be negative, got %d", c.History.MaxEntries)
+	check(c.History.MaxAge >= 0, "history.max_age: must not be negative, got %s", c.History.MaxAge)
+
+	check(c.Limits.Interval.Std() >= time.Second && c.Limits.Interval.Std() <= time.Hour,
+		"working_set_limits.interval: must be between 1s and 1h, got %s", c.Limits.Interval)
+	if _, err := wslimit.Compile(c.Limits.Processes); err != nil {
+		errs = append(errs, fmt.Errorf("working_set_limits.processes: %v", err))
+	}
+
+	n := c.Notifications
+	check(n.LowMemoryPercent >= 0 && n.LowMemoryPercent < 100,
+		"notifications.low_memory_percent: must be between 0 and 100, got %v", n.LowMemoryPercent)
+	check(n.MaxPerMinute >= 0, "notifications.max_per_minute: must not be negative, got %d", n.MaxPerMinute)
+	check(n.DedupWindow >= 0, "notifications.dedup_window: must not be negative, got %s", n.DedupWindow)
+
+	if _, err := applog.ParseLevel(c.Log.Level); err != nil {
+		errs = append(errs, fmt.Errorf("log.level: %v", err))
+	}
+	check(c.Log.MaxSizeMB >= 1 && c.Log.MaxSizeMB <= 100, "log.max_size_mb: must be between 1 and 100, got %d", c.Log.MaxSizeMB)
+	check(c.Log.MaxFiles >= 0 && c.Log.MaxFiles <= 20, "log.max_files: must be between 0 and 20, got %d", c.Log.MaxFiles)
+
+	check(api.IsLoopback(c.API.Address), "api.address: must be a loopback host:port such as 127.0.0.1:8765, got %q", c.API.Address)
+	check(!c.API.Enabled || len(c.API.Token) >= api.MinTokenLength,
+		"api.token: must be at least %d characters when the API is enabled", api.MinTokenLength)
+	_, port, err := net.SplitHostPort(c.Metrics.Address)
+	check(err == nil && port != "", "metrics.address: must be a host:port such as 127.0.0.1:9182, got %q", c.Metrics.Address)
+	if _, err := schedule.Compile(c.Schedule, time.Now()); err != nil {
+		errs = append(errs, fmt.Errorf("schedule: %v", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Policy converts the auto-clean settings to an engine policy.
+func (a AutoCleanConfig) Policy() autoclean.Policy {
+	p := autoclean.Policy{
+		StandbyBytes:           a.StandbyMB * mb,
+		StandbyHysteresis:      a.StandbyHysteresisMB * mb,
+		StandbyLowPriorityOnly: a.StandbyLowPriorityOnly,
+		MinInterval:            a.MinInterval.Std(),
+		Cooldown:               a.Cooldown.Std(),
+	}
+	if a.MemoryLoadPercent > 0 {
+		p.FreePercent = 100 - a.MemoryLoadPercent
+		p.FreeHysteresis = a.HysteresisPercent
+	}
+	return p
+}
+
+// TrimOptions converts the cleaning settings to trimming options.
+func (c CleaningConfig) TrimOptions() trim.Options {
+	return trim.Options{
+		Concurrency: c.Concurrency,
+		Timeout:     c.ProcessTimeout.Std(),
+		Interval:    c.TrimDelay.Std(),
+	}
+}
+
+// Retention converts the history settings to store retention limits.
+func (h HistoryConfig) Retention() history.Retention {
+	return history.Retention{MaxEntries: h.MaxEntries, MaxAge: h.MaxAge.Std()}
+}
+
+// Options converts the notification settings to notifier limits.
+func (n NotifyConfig) Options() notify.Options {
+	return notify.Options{MaxPerMinute: n.MaxPerMinute, DedupWindow: n.DedupWindow.Std()}
+}
+
+// SlogLevel returns the log level, info if it is invalid.
+func (l LogConfig) SlogLevel() slog.Level {
+	level, err := applog.ParseLevel(l.Level)
+	if err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// MaxSize returns the size at which the log file is rotated in bytes.
+func (l LogConfig) MaxSize() int64 {
+	return int64(l.MaxSizeMB) * mb
+}
+
+// Duration is a time.Duration stored as a string such as "2s" or "10m".
+type Duration time.Duration
+
+// Std returns the duration as a time.Duration.
+func (d Duration) Std() time.Duration {
+	return time.Duration(d)
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"2s\": %v", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
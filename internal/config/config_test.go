@@ -0,0 +1,206 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"windows-ram-cleaner/internal/procrules"
+	"windows-ram-cleaner/internal/schedule"
+	"windows-ram-cleaner/internal/wslimit"
+)
+
+func TestDefaultIsValid(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Errorf("Default().Validate() = %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		field  string
+	}{
+		{name: "wrong version", modify: func(c *Config) { c.Version = 7 }, field: "version"},
+		{name: "refresh too fast", modify: func(c *Config) { c.RefreshInterval = Duration(time.Millisecond) }, field: "refresh_interval"},
+		{name: "load above 100", modify: func(c *Config) { c.AutoClean.MemoryLoadPercent = 120 }, field: "memory_load_percent"},
+		{name: "hysteresis above load", modify: func(c *Config) { c.AutoClean.HysteresisPercent = 80 }, field: "hysteresis_percent"},
+		{name: "standby hysteresis too large", modify: func(c *Config) { c.AutoClean.StandbyMB = 100 }, field: "standby_hysteresis_mb"},
+		{name: "negative cooldown", modify: func(c *Config) { c.AutoClean.Cooldown = Duration(-time.Second) }, field: "cooldown"},
+		{name: "trim delay too long", modify: func(c *Config) { c.Cleaning.TrimDelay = Duration(time.Minute) }, field: "trim_delay"},
+		{name: "no concurrency", modify: func(c *Config) { c.Cleaning.Concurrency = 0 }, field: "concurrency"},
+		{name: "process timeout too short", modify: func(c *Config) { c.Cleaning.ProcessTimeout = Duration(time.Millisecond) }, field: "process_timeout"},
+		{name: "empty process name", modify: func(c *Config) { c.Cleaning.CriticalProcesses = []string{" "} }, field: "critical_processes[0]"},
+		{name: "invalid rule", modify: func(c *Config) { c.Cleaning.Rules = []procrules.Rule{{Action: "skip", Name: "a.exe"}} }, field: "cleaning.rules"},
+		{name: "negative history size", modify: func(c *Config) { c.History.MaxEntries = -1 }, field: "history.max_entries"},
+		{name: "limits interval too short", modify: func(c *Config) { c.Limits.Interval = Duration(time.Millisecond) }, field: "working_set_limits.interval"},
+		{name: "low memory alert at 100%", modify: func(c *Config) { c.Notifications.LowMemoryPercent = 100 }, field: "notifications.low_memory_percent"},
+		{name: "negative notification rate", modify: func(c *Config) { c.Notifications.MaxPerMinute = -1 }, field: "notifications.max_per_minute"},
+		{name: "unknown log level", modify: func(c *Config) { c.Log.Level = "verbose" }, field: "log.level"},
+		{name: "log file too small", modify: func(c *Config) { c.Log.MaxSizeMB = 0 }, field: "log.max_size_mb"},
+		{name: "API on all interfaces", modify: func(c *Config) { c.API.Address = "0.0.0.0:8765" }, field: "api.address"},
+		{name: "API without token", modify: func(c *Config) { c.API.Enabled = true }, field: "api.token"},
+		{name: "metrics without port", modify: func(c *Config) { c.Metrics.Address = "0.0.0.0" }, field: "metrics.address"},
+		{name: "limit without max", modify: func(c *Config) { c.Limits.Processes = []wslimit.Limit{{Name: "a.exe"}} }, field: "working_set_limits.processes"},
+		{name: "invalid schedule", modify: func(c *Config) { c.Schedule = []schedule.Job{{When: "@every 1s", Action: schedule.ActionCleanRAM}} }, field: "schedule"},
+		{name: "empty task name", modify: func(c *Config) { c.Startup.TaskName = "" }, field: "task_name"},
+		{name: "task name with slash", modify: func(c *Config) { c.Startup.TaskName = `a\b` }, field: "task_name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.field) {
+				t.Errorf("Validate() = %v, want an error about %s", err, tt.field)
+			}
+		})
+	}
+}
+
+func TestValidateReportsAllErrors(t *testing.T) {
+	cfg := Default()
+	cfg.RefreshInterval = 0
+	cfg.Startup.TaskName = ""
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "refresh_interval") || !strings.Contains(err.Error(), "task_name") {
+		t.Errorf("Validate() = %v, want both errors", err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		migrated bool
+		wantErr  string
+		check    func(c Config) bool
+	}{
+		{
+			name: "partial file keeps defaults",
+			data: `{"version": 1, "refresh_interval": "5s"}`,
+			check: func(c Config) bool {
+				return c.RefreshInterval.Std() == 5*time.Second && c.AutoClean.MemoryLoadPercent == 65
+			},
+		},
+		{
+			name:     "unversioned file is migrated",
+			data:     `{"startup": {"task_name": "MyCleaner"}}`,
+			migrated: true,
+			check:    func(c Config) bool { return c.Version == CurrentVersion && c.Startup.TaskName == "MyCleaner" },
+		},
+		{
+			name:  "rules",
+			data:  `{"version": 1, "cleaning": {"rules": [{"action": "deny", "name": "*.exe", "parent_pid": 4}]}}`,
+			check: func(c Config) bool { return len(c.Cleaning.Rules) == 1 && c.Cleaning.Rules[0].ParentPID == 4 },
+		},
+		{name: "newer version", data: `{"version": 99}`, wantErr: "newer"},
+		{name: "invalid version", data: `{"version": "one"}`, wantErr: "version"},
+		{name: "unknown field", data: `{"version": 1, "colour": "red"}`, wantErr: "colour"},
+		{name: "numeric duration", data: `{"version": 1, "refresh_interval": 2}`, wantErr: "duration"},
+		{name: "invalid value", data: `{"version": 1, "auto_clean": {"memory_load_percent": 150}}`, wantErr: "memory_load_percent"},
+		{name: "not JSON", data: `refresh_interval = 2`, wantErr: "invalid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, migrated, err := Parse([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if migrated != tt.migrated {
+				t.Errorf("Parse() migrated = %v, want %v", migrated, tt.migrated)
+			}
+			if !tt.check(cfg) {
+				t.Errorf("Parse() = %+v, unexpected values", cfg)
+			}
+		})
+	}
+}
+
+func TestLoadOrCreate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), AppDirName, FileName)
+
+	cfg, err := LoadOrCreate(path)
+	if err != nil {
+		t.Fatalf("LoadOrCreate() error = %v", err)
+	}
+	if cfg.Startup.TaskName != Default().Startup.TaskName {
+		t.Errorf("LoadOrCreate() did not return defaults: %+v", cfg)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("config file was not created: %v", err)
+	}
+
+	cfg.RefreshInterval = Duration(3 * time.Second)
+	if err := Save(path, cfg); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := LoadOrCreate(path)
+	if err != nil {
+		t.Fatalf("LoadOrCreate() error = %v", err)
+	}
+	if loaded.RefreshInterval != cfg.RefreshInterval {
+		t.Errorf("RefreshInterval = %s, want %s", loaded.RefreshInterval, cfg.RefreshInterval)
+	}
+}
+
+func TestLoadRewritesMigratedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, []byte(`{"refresh_interval": "4s"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(path); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"version": 1`) {
+		t.Errorf("migrated file was not rewritten: %s", data)
+	}
+}
+
+func TestSaveRejectsInvalid(t *testing.T) {
+	cfg := Default()
+	cfg.Version = 0
+	if err := Save(filepath.Join(t.TempDir(), FileName), cfg); err == nil {
+		t.Errorf("Save() expected an error")
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	a := Default().AutoClean
+	a.StandbyMB = 2048
+	p := a.Policy()
+
+	if p.FreePercent != 35 || p.FreeHysteresis != 5 {
+		t.Errorf("Policy() free = %v/%v, want 35/5", p.FreePercent, p.FreeHysteresis)
+	}
+	if p.StandbyBytes != 2048*mb || p.StandbyHysteresis != 256*mb {
+		t.Errorf("Policy() standby = %d/%d", p.StandbyBytes, p.StandbyHysteresis)
+	}
+
+	a.StandbyLowPriorityOnly = true
+	if p := a.Policy(); !p.StandbyLowPriorityOnly {
+		t.Errorf("Policy() StandbyLowPriorityOnly = false, want true")
+	}
+
+	a.MemoryLoadPercent = 0
+	if p := a.Policy(); p.FreePercent != 0 {
+		t.Errorf("Policy() with disabled trigger has FreePercent = %v", p.FreePercent)
+	}
+}
@@ -0,0 +1,152 @@
+// Description: This file contains loading, saving and migrating the configuration file.
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// AppDirName is the directory of the application under the user config directory.
+const AppDirName = "WindowsRAMCleaner"
+
+// FileName is the name of the configuration file.
+const FileName = "config.json"
+
+// migrations upgrade a raw configuration from the version of their index to the next one.
+var migrations = []func(raw map[string]any) error{
+	// 0 -> 1: files written by hand without a version field use the first schema.
+	func(raw map[string]any) error { return nil },
+}
+
+// Dir returns the application directory: %APPDATA% on Windows, $XDG_CONFIG_HOME on Linux.
+func Dir() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user config directory: %v", err)
+	}
+	return filepath.Join(base, AppDirName), nil
+}
+
+// DefaultPath returns the path of the configuration file.
+func DefaultPath() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, FileName), nil
+}
+
+// LoadOrCreate loads the configuration from path, writing the defaults if the file does not exist.
+func LoadOrCreate(path string) (Config, error) {
+	cfg, err := Load(path)
+	if errors.Is(err, os.ErrNotExist) {
+		cfg = Default()
+		return cfg, Save(path, cfg)
+	}
+	return cfg, err
+}
+
+// Load reads, migrates and validates the configuration at path.
+// A file written by an older version is rewritten in the current schema.
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg, migrated, err := Parse(data)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+
+	if migrated {
+		if err := Save(path, cfg); err != nil {
+			return Config{}, err
+		}
+	}
+	return cfg, nil
+}
+
+// Parse decodes, migrates and validates a configuration. It reports whether a migration happened.
+// Settings missing from data keep their default values.
+func Parse(data []byte) (Config, bool, error) {
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return Config{}, false, err
+	}
+
+	version, err := rawVersion(raw)
+	if err != nil {
+		return Config{}, false, err
+	}
+	if version > CurrentVersion {
+		return Config{}, false, fmt.Errorf("version %d is newer than supported version %d", version, CurrentVersion)
+	}
+
+	for v := version; v < CurrentVersion; v++ {
+		if err := migrations[v](raw); err != nil {
+			return Config{}, false, fmt.Errorf("failed to migrate from version %d: %v", v, err)
+		}
+	}
+	raw["version"] = CurrentVersion
+
+	migratedData, err := json.Marshal(raw)
+	if err != nil {
+		return Config{}, false, err
+	}
+
+	cfg := Default()
+	decoder := json.NewDecoder(bytes.NewReader(migratedData))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&cfg); err != nil {
+		return Config{}, false, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, false, err
+	}
+	return cfg, version != CurrentVersion, nil
+}
+
+// Save validates cfg and writes it to path atomically.
+func Save(path string, cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("refusing to save invalid config: %w", err)
+	}
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create config directory: %v", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write config: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace config: %v", err)
+	}
+	return nil
+}
+
+// rawVersion returns the version field of a raw configuration, zero if it is missing.
+func rawVersion(raw map[string]any) (int, error) {
+	value, ok := raw["version"]
+	if !ok {
+		return 0, nil
+	}
+	number, ok := value.(float64)
+	if !ok || number < 0 || number != float64(int(number)) {
+		return 0, fmt.Errorf("version must be a non-negative integer, got %v", value)
+	}
+	return int(number), nil
+}
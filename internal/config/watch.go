@@ -0,0 +1,51 @@
+// Description: This file contains the watcher that reloads the configuration when the file changes.
+
+package config
+
+import (
+	"os"
+	"time"
+)
+
+// Watch polls path every interval until stop is closed. When the file changes, it is reloaded and
+// passed to onChange; a file that fails to load is passed to onError and the previous config stays active.
+func Watch(path string, interval time.Duration, stop <-chan struct{}, onChange func(Config), onError func(error)) {
+	last, _ := fileVersion(path)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			current, err := fileVersion(path)
+			if err != nil || current == last {
+				continue
+			}
+			last = current
+
+			cfg, err := Load(path)
+			if err != nil {
+				onError(err)
+				continue
+			}
+			onChange(cfg)
+		}
+	}
+}
+
+// stamp identifies a version of the file.
+type stamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func fileVersion(path string) (stamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return stamp{}, err
+	}
+	return stamp{modTime: info.ModTime(), size: info.Size()}, nil
+}
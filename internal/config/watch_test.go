@@ -0,0 +1,50 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := Save(path, Default()); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := make(chan Config, 1)
+	errs := make(chan error, 1)
+	stop := make(chan struct{})
+	defer close(stop)
+	go Watch(path, 10*time.Millisecond, stop, func(c Config) { changes <- c }, func(err error) { errs <- err })
+
+	// Make sure the modification time changes even on coarse file systems.
+	time.Sleep(20 * time.Millisecond)
+	if err := os.WriteFile(path, []byte(`{"version": 1, "refresh_interval": "7s"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case c := <-changes:
+		if c.RefreshInterval.Std() != 7*time.Second {
+			t.Errorf("reloaded RefreshInterval = %s, want 7s", c.RefreshInterval)
+		}
+	case err := <-errs:
+		t.Fatalf("unexpected reload error: %v", err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("change was not detected")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"version": 1, "refresh_interval": "broken"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case c := <-changes:
+		t.Fatalf("invalid config was applied: %+v", c)
+	case <-errs:
+	case <-time.After(2 * time.Second):
+		t.Fatal("invalid change was not reported")
+	}
+}
@@ -0,0 +1,123 @@
+// Package graph Description: This package serves the local web page with the memory graphs of the last hour.
+// The page draws the free, standby, modified and commit sizes from the sampler with a marker for every clean.
+package graph
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"windows-ram-cleaner/internal/history"
+	"windows-ram-cleaner/internal/report"
+	"windows-ram-cleaner/internal/sampler"
+)
+
+// Window is the time span shown by the graphs.
+const Window = time.Hour
+
+//go:embed index.html
+var page []byte
+
+// Source provides the data of the graphs.
+type Source struct {
+	// Samples returns the samples taken at or after a time, oldest first.
+	Samples func(since time.Time) []sampler.Sample
+	// Cleans returns the clean history. Nil means there is no history.
+	Cleans func() ([]history.Entry, error)
+	// Now returns the current time. Nil means time.Now.
+	Now func() time.Time
+}
+
+// Point is one sample of the graphs. Sizes are in bytes.
+type Point struct {
+	Time     int64  `json:"t"` // Unix milliseconds
+	Free     uint64 `json:"free"`
+	Standby  uint64 `json:"standby"`
+	Modified uint64 `json:"modified"`
+	Commit   uint64 `json:"commit"`
+}
+
+// Marker is a clean shown on the graphs.
+type Marker struct {
+	Time    int64           `json:"t"` // Unix milliseconds
+	Mode    report.Mode     `json:"mode"`
+	Trigger history.Trigger `json:"trigger"`
+	Freed   uint64          `json:"freed"`
+}
+
+// Data is the JSON document the page polls.
+type Data struct {
+	Total       uint64   `json:"total"`
+	CommitLimit uint64   `json:"commit_limit"`
+	Samples     []Point  `json:"samples"`
+	Cleans      []Marker `json:"cleans"`
+	// Error tells why the cleans are missing, the samples are still valid.
+	Error string `json:"error,omitempty"`
+}
+
+// Handler returns the handler serving the page at / and its data at /data.
+func Handler(src Source) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(page)
+	})
+	mux.HandleFunc("GET /data", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(src.data())
+	})
+	return mux
+}
+
+// HostOnly returns a handler serving h only to requests for host, the host:port the server listens on.
+// Pages of other sites that resolve their own name to the loopback address (DNS rebinding) send
+// their name as the host and are rejected.
+func HostOnly(host string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != host {
+			http.Error(w, "invalid host", http.StatusMisdirectedRequest)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// data collects the samples and cleans of the last Window.
+func (src Source) data() Data {
+	now := time.Now
+	if src.Now != nil {
+		now = src.Now
+	}
+	since := now().Add(-Window)
+
+	d := Data{Samples: []Point{}, Cleans: []Marker{}}
+	for _, s := range src.Samples(since) {
+		d.Total = s.Snapshot.TotalSize
+		d.CommitLimit = s.Snapshot.CommitLimit
+		d.Samples = append(d.Samples, Point{
+			Time:     s.Time.UnixMilli(),
+			Free:     s.Snapshot.FreeSize,
+			Standby:  s.Snapshot.StandbySize,
+			Modified: s.Snapshot.ModifiedSize,
+			Commit:   s.Snapshot.CommitSize,
+		})
+	}
+
+	if src.Cleans == nil {
+		return d
+	}
+	entries, err := src.Cleans()
+	if err != nil {
+		d.Error = err.Error()
+		return d
+	}
+	for _, e := range entries {
+		if e.Time.Before(since) {
+			continue
+		}
+		d.Cleans = append(d.Cleans, Marker{Time: e.Time.UnixMilli(), Mode: e.Mode, Trigger: e.Trigger, Freed: e.FreedBytes()})
+	}
+	return d
+}
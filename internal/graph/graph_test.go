@@ -0,0 +1,126 @@
+package graph
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"windows-ram-cleaner/internal/history"
+	"windows-ram-cleaner/internal/memory"
+	"windows-ram-cleaner/internal/report"
+	"windows-ram-cleaner/internal/sampler"
+)
+
+var now = time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+func testSource(cleansErr error) Source {
+	ring := sampler.NewRing(10)
+	for _, age := range []time.Duration{2 * time.Hour, 30 * time.Minute, time.Minute} {
+		ring.Add(sampler.Sample{Time: now.Add(-age), Snapshot: memory.Snapshot{
+			TotalSize: 16, FreeSize: uint64(age / time.Minute), StandbySize: 2, ModifiedSize: 3, CommitSize: 4, CommitLimit: 32,
+		}})
+	}
+
+	cleans := func() ([]history.Entry, error) {
+		var entries []history.Entry
+		for _, age := range []time.Duration{3 * time.Hour, 10 * time.Minute} {
+			r := report.New(report.ModeStandby, now.Add(-age), memory.Snapshot{})
+			r.AddStage(report.StageStandby, 100, 0, nil)
+			entries = append(entries, history.NewEntry(history.TriggerAuto, r))
+		}
+		return entries, cleansErr
+	}
+	return Source{Samples: ring.Since, Cleans: cleans, Now: func() time.Time { return now }}
+}
+
+func getData(t *testing.T, src Source) Data {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	Handler(src).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/data", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /data = %d", rec.Code)
+	}
+
+	var d Data
+	if err := json.Unmarshal(rec.Body.Bytes(), &d); err != nil {
+		t.Fatalf("GET /data is not valid JSON: %v", err)
+	}
+	return d
+}
+
+func TestData(t *testing.T) {
+	d := getData(t, testSource(nil))
+
+	if len(d.Samples) != 2 || d.Samples[0].Free != 30 || d.Samples[1].Free != 1 {
+		t.Errorf("samples = %+v, want the last hour only", d.Samples)
+	}
+	if d.Total != 16 || d.CommitLimit != 32 {
+		t.Errorf("total = %d, commit limit = %d", d.Total, d.CommitLimit)
+	}
+	expected := Marker{Time: now.Add(-10 * time.Minute).UnixMilli(), Mode: report.ModeStandby, Trigger: history.TriggerAuto, Freed: 100}
+	if len(d.Cleans) != 1 || d.Cleans[0] != expected {
+		t.Errorf("cleans = %+v, want [%+v]", d.Cleans, expected)
+	}
+}
+
+func TestDataWithoutHistory(t *testing.T) {
+	src := testSource(nil)
+	src.Cleans = nil
+	if d := getData(t, src); len(d.Cleans) != 0 || len(d.Samples) != 2 {
+		t.Errorf("data = %+v", d)
+	}
+
+	if d := getData(t, testSource(errors.New("locked"))); d.Error != "locked" || len(d.Samples) != 2 {
+		t.Errorf("data = %+v, want the samples and the history error", d)
+	}
+}
+
+func TestPage(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		code   int
+	}{
+		{method: http.MethodGet, path: "/", code: http.StatusOK},
+		{method: http.MethodGet, path: "/missing", code: http.StatusNotFound},
+		{method: http.MethodPost, path: "/data", code: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		Handler(testSource(nil)).ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		if rec.Code != tt.code {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, rec.Code, tt.code)
+		}
+		if tt.path == "/" && !strings.Contains(rec.Body.String(), "<canvas") {
+			t.Errorf("GET / does not serve the page")
+		}
+	}
+}
+
+func TestHostOnly(t *testing.T) {
+	handler := HostOnly("127.0.0.1:8080", Handler(testSource(nil)))
+	tests := []struct {
+		host string
+		code int
+	}{
+		{host: "127.0.0.1:8080", code: http.StatusOK},
+		{host: "attacker.example:8080", code: http.StatusMisdirectedRequest},
+		{host: "localhost:8080", code: http.StatusMisdirectedRequest},
+		{host: "127.0.0.1:9090", code: http.StatusMisdirectedRequest},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/data", nil)
+		req.Host = tt.host
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.code {
+			t.Errorf("GET /data with host %s = %d, want %d", tt.host, rec.Code, tt.code)
+		}
+	}
+}
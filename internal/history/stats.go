@@ -0,0 +1,55 @@
+// Description: This file contains the rolling statistics computed from the history.
+
+package history
+
+import (
+	"time"
+)
+
+// Stats are aggregates over the history.
+type Stats struct {
+	Cleans       int       `json:"cleans"`
+	TotalFreed   uint64    `json:"total_freed_bytes"`
+	AverageFreed uint64    `json:"average_freed_bytes"`
+	CleansToday  int       `json:"cleans_today"`
+	FreedToday   uint64    `json:"freed_today_bytes"`
+	LastClean    time.Time `json:"last_clean,omitempty"`
+	LastTrigger  Trigger   `json:"last_trigger,omitempty"`
+	LastFreed    uint64    `json:"last_freed_bytes"`
+}
+
+// ComputeStats aggregates entries. "Today" starts at local midnight of now.
+func ComputeStats(entries []Entry, now time.Time) Stats {
+	var stats Stats
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	for _, e := range entries {
+		freed := e.FreedBytes()
+		stats.Cleans++
+		stats.TotalFreed += freed
+
+		if !e.Time.Before(midnight) {
+			stats.CleansToday++
+			stats.FreedToday += freed
+		}
+		if !e.Time.Before(stats.LastClean) {
+			stats.LastClean = e.Time
+			stats.LastTrigger = e.Trigger
+			stats.LastFreed = freed
+		}
+	}
+
+	if stats.Cleans > 0 {
+		stats.AverageFreed = stats.TotalFreed / uint64(stats.Cleans)
+	}
+	return stats
+}
+
+// Stats computes the aggregates over the stored entries.
+func (s *Store) Stats() (Stats, error) {
+	entries, err := s.Entries()
+	if err != nil {
+		return Stats{}, err
+	}
+	return ComputeStats(entries, s.now()), nil
+}
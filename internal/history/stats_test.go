@@ -0,0 +1,51 @@
+package history
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputeStats(t *testing.T) {
+	entries := []Entry{
+		testEntry(baseTime.Add(-48*time.Hour), TriggerAuto, 600),
+		testEntry(baseTime.Add(-2*time.Hour), TriggerManual, 300),
+		testEntry(baseTime.Add(-time.Hour), TriggerCLI, 100),
+	}
+
+	stats := ComputeStats(entries, baseTime)
+	expected := Stats{
+		Cleans:       3,
+		TotalFreed:   1000,
+		AverageFreed: 333,
+		CleansToday:  2,
+		FreedToday:   400,
+		LastClean:    baseTime.Add(-time.Hour),
+		LastTrigger:  TriggerCLI,
+		LastFreed:    100,
+	}
+	if stats != expected {
+		t.Errorf("ComputeStats() = %+v, want %+v", stats, expected)
+	}
+}
+
+func TestComputeStatsEmpty(t *testing.T) {
+	stats := ComputeStats(nil, baseTime)
+	if stats != (Stats{}) {
+		t.Errorf("ComputeStats(nil) = %+v, want zero", stats)
+	}
+}
+
+func TestStoreStats(t *testing.T) {
+	s := newTestStore(t, Retention{})
+	if err := s.Append(testEntry(baseTime.Add(-time.Minute), TriggerManual, 50)); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := s.Stats()
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if stats.CleansToday != 1 || stats.FreedToday != 50 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+}
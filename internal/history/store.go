@@ -0,0 +1,249 @@
+// Package history Description: This package contains the append-only history of cleans.
+// Every clean is stored as one JSON line with its trigger, mode and report; old entries are
+// pruned according to the retention limits.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"windows-ram-cleaner/internal/report"
+)
+
+// FileName is the name of the history file in the application directory.
+const FileName = "history.jsonl"
+
+// Trigger is what started a clean.
+type Trigger string
+
+const (
+	TriggerManual   Trigger = "manual"   // Tray menu
+	TriggerAuto     Trigger = "auto"     // Auto-clean engine
+	TriggerCLI      Trigger = "cli"      // Command line
+	TriggerAPI      Trigger = "api"      // HTTP control API
+	TriggerSchedule Trigger = "schedule" // Scheduled clean
+)
+
+// Entry is one clean in the history.
+type Entry struct {
+	Time    time.Time   `json:"time"`
+	Trigger Trigger     `json:"trigger"`
+	Mode    report.Mode `json:"mode"`
+	// Trimmed, Skipped and Failed count the processes of the report, whose per-process
+	// results are not stored to keep the file small.
+	Trimmed int                 `json:"trimmed"`
+	Skipped int                 `json:"skipped"`
+	Failed  int                 `json:"failed"`
+	Report  *report.CleanReport `json:"report"`
+}
+
+// NewEntry creates an entry for a finished clean.
+func NewEntry(trigger Trigger, r *report.CleanReport) Entry {
+	trimmed, skipped, failed := r.Counts()
+	stored := *r
+	stored.Processes = nil
+
+	return Entry{
+		Time:    r.Started,
+		Trigger: trigger,
+		Mode:    r.Mode,
+		Trimmed: trimmed,
+		Skipped: skipped,
+		Failed:  failed,
+		Report:  &stored,
+	}
+}
+
+// FreedBytes returns the memory released by the clean.
+func (e Entry) FreedBytes() uint64 {
+	if e.Report == nil {
+		return 0
+	}
+	return e.Report.FreedBytes()
+}
+
+// Retention limits how much history is kept. Zero values mean no limit.
+type Retention struct {
+	MaxEntries int
+	MaxAge     time.Duration
+}
+
+// Store is a history file. It is safe for concurrent use; the file may also be appended
+// by other processes, such as the command line, and is re-read when it changes.
+type Store struct {
+	path string
+	now  func() time.Time
+
+	mu        sync.Mutex
+	retention Retention
+	cached    []Entry
+	stamp     fileStamp
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// Open creates a Store for the file at path. The file is created on the first Append.
+func Open(path string, retention Retention) *Store {
+	return &Store{path: path, now: time.Now, retention: retention}
+}
+
+// SetRetention changes the retention limits, applied on the next Append.
+func (s *Store) SetRetention(retention Retention) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retention = retention
+}
+
+// Append adds an entry. Entries beyond the retention limits are pruned from the file once they
+// exceed a tenth of the kept entries: a rewrite replaces the file and may lose an entry appended
+// at the same time by another process, so it is rare rather than on every append.
+func (s *Store) Append(e Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return err
+	}
+	entries = append(entries, e)
+
+	kept := prune(entries, s.retention, s.now())
+	if len(entries)-len(kept) > pruneSlack(len(kept)) {
+		err = s.rewrite(kept)
+	} else {
+		err = s.appendLine(e)
+	}
+	if err != nil {
+		return err
+	}
+
+	// The next read picks up the new file state.
+	s.stamp = fileStamp{}
+	return nil
+}
+
+// Entries returns the entries within the retention limits, oldest first.
+func (s *Store) Entries() ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return append([]Entry(nil), prune(entries, s.retention, s.now())...), nil
+}
+
+// load returns the entries, re-reading the file only when it changed. Lines that can't be
+// decoded, e.g. a line cut short by a crash, are skipped.
+func (s *Store) load() ([]Entry, error) {
+	info, err := os.Stat(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		s.cached, s.stamp = nil, fileStamp{}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %v", err)
+	}
+
+	stamp := fileStamp{modTime: info.ModTime(), size: info.Size()}
+	if stamp == s.stamp {
+		return s.cached, nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %v", err)
+	}
+
+	var entries []Entry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+
+	s.cached, s.stamp = entries, stamp
+	return entries, nil
+}
+
+func (s *Store) appendLine(e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode history entry: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create history directory: %v", err)
+	}
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %v", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write history: %v", err)
+	}
+	return nil
+}
+
+func (s *Store) rewrite(entries []Entry) error {
+	var buf bytes.Buffer
+	for _, e := range entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("failed to encode history entry: %v", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create history directory: %v", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write history: %v", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to replace history: %v", err)
+	}
+	return nil
+}
+
+// pruneSlack returns how many entries beyond the retention limits the file may hold when kept
+// entries are within them.
+func pruneSlack(kept int) int {
+	return max(kept/10, 1)
+}
+
+// prune drops entries older than MaxAge and the oldest entries beyond MaxEntries.
+func prune(entries []Entry, retention Retention, now time.Time) []Entry {
+	if retention.MaxAge > 0 {
+		cutoff := now.Add(-retention.MaxAge)
+		first := 0
+		for first < len(entries) && entries[first].Time.Before(cutoff) {
+			first++
+		}
+		entries = entries[first:]
+	}
+	if retention.MaxEntries > 0 && len(entries) > retention.MaxEntries {
+		entries = entries[len(entries)-retention.MaxEntries:]
+	}
+	return entries
+}
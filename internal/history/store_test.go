@@ -0,0 +1,176 @@
+package history
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"windows-ram-cleaner/internal/memory"
+	"windows-ram-cleaner/internal/report"
+)
+
+var baseTime = time.Date(2024, 3, 10, 15, 0, 0, 0, time.Local)
+
+func testEntry(at time.Time, trigger Trigger, freed uint64) Entry {
+	r := report.New(report.ModeBasic, at, memory.Snapshot{})
+	r.AddProcess(report.ProcessResult{PID: 1, Status: report.ProcessTrimmed, FreedBytes: freed})
+	r.AddProcess(report.ProcessResult{PID: 2, Status: report.ProcessSkipped})
+	r.AddStage(report.StageProcesses, freed, time.Second, nil)
+	r.Finish(at.Add(time.Second), memory.Snapshot{}, nil)
+	return NewEntry(trigger, r)
+}
+
+func newTestStore(t *testing.T, retention Retention) *Store {
+	s := Open(filepath.Join(t.TempDir(), FileName), retention)
+	s.now = func() time.Time { return baseTime }
+	return s
+}
+
+func TestNewEntry(t *testing.T) {
+	e := testEntry(baseTime, TriggerAuto, 100)
+
+	if e.Trimmed != 1 || e.Skipped != 1 || e.Failed != 0 {
+		t.Errorf("counts = %d/%d/%d, want 1/1/0", e.Trimmed, e.Skipped, e.Failed)
+	}
+	if e.Report.Processes != nil {
+		t.Errorf("per-process results should not be stored")
+	}
+	if e.FreedBytes() != 100 || e.Mode != report.ModeBasic || !e.Time.Equal(baseTime) {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+}
+
+func TestAppendAndReload(t *testing.T) {
+	s := newTestStore(t, Retention{})
+
+	if entries, err := s.Entries(); err != nil || len(entries) != 0 {
+		t.Fatalf("Entries() of a missing file = %v, %v", entries, err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := s.Append(testEntry(baseTime.Add(time.Duration(i)*time.Minute), TriggerManual, 10)); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	reopened := Open(s.path, Retention{})
+	entries, err := reopened.Entries()
+	if err != nil {
+		t.Fatalf("Entries() error = %v", err)
+	}
+	if len(entries) != 3 || entries[2].Trigger != TriggerManual || entries[2].FreedBytes() != 10 {
+		t.Errorf("reloaded entries = %+v", entries)
+	}
+}
+
+func TestSkipsCorruptLines(t *testing.T) {
+	s := newTestStore(t, Retention{})
+	if err := s.Append(testEntry(baseTime, TriggerCLI, 10)); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("{\"time\": \"2024-03\n")
+	f.Close()
+
+	if err := s.Append(testEntry(baseTime, TriggerCLI, 20)); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := s.Entries()
+	if err != nil || len(entries) != 2 {
+		t.Errorf("Entries() = %d entries, %v, want 2", len(entries), err)
+	}
+}
+
+func TestSeesAppendsFromOtherStores(t *testing.T) {
+	s := newTestStore(t, Retention{})
+	other := Open(s.path, Retention{})
+
+	if err := s.Append(testEntry(baseTime, TriggerManual, 10)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Entries(); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Append(testEntry(baseTime, TriggerCLI, 20)); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := s.Entries()
+	if err != nil || len(entries) != 2 {
+		t.Errorf("Entries() = %d entries, %v, want 2", len(entries), err)
+	}
+}
+
+func TestRetention(t *testing.T) {
+	tests := []struct {
+		name      string
+		retention Retention
+		ages      []time.Duration
+		expected  int
+	}{
+		{name: "no limits", retention: Retention{}, ages: []time.Duration{72 * time.Hour, time.Hour, 0}, expected: 3},
+		{name: "max entries", retention: Retention{MaxEntries: 2}, ages: []time.Duration{3 * time.Hour, 2 * time.Hour, time.Hour, 0}, expected: 2},
+		{name: "max age", retention: Retention{MaxAge: 24 * time.Hour}, ages: []time.Duration{72 * time.Hour, 48 * time.Hour, time.Hour, 0}, expected: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStore(t, tt.retention)
+			for _, age := range tt.ages {
+				if err := s.Append(testEntry(baseTime.Add(-age), TriggerAuto, 1)); err != nil {
+					t.Fatalf("Append() error = %v", err)
+				}
+			}
+
+			entries, err := Open(s.path, Retention{}).Entries()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != tt.expected {
+				t.Errorf("kept %d entries, want %d", len(entries), tt.expected)
+			}
+			if len(entries) > 0 && !entries[len(entries)-1].Time.Equal(baseTime) {
+				t.Errorf("newest entry was pruned")
+			}
+		})
+	}
+}
+
+func TestPruneSlack(t *testing.T) {
+	s := newTestStore(t, Retention{MaxEntries: 10})
+	lines := func() int {
+		data, err := os.ReadFile(s.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return bytes.Count(data, []byte("\n"))
+	}
+
+	tests := []struct {
+		appends  int
+		expected int
+	}{
+		{appends: 10, expected: 10},
+		{appends: 1, expected: 11}, // Within the slack, appended
+		{appends: 1, expected: 10}, // Beyond the slack, rewritten
+	}
+	for _, tt := range tests {
+		for i := 0; i < tt.appends; i++ {
+			if err := s.Append(testEntry(baseTime, TriggerAuto, 1)); err != nil {
+				t.Fatalf("Append() error = %v", err)
+			}
+		}
+		if n := lines(); n != tt.expected {
+			t.Errorf("history file has %d entries, want %d", n, tt.expected)
+		}
+		if entries, err := s.Entries(); err != nil || len(entries) != 10 {
+			t.Errorf("Entries() = %d entries, %v, want 10", len(entries), err)
+		}
+	}
+}
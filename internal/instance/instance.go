@@ -0,0 +1,130 @@
+// Package instance Description: This package keeps a single instance of the application running and lets
+// later invocations control it. The running instance holds a lock (a named mutex on Windows, a file lock
+// on Linux) and serves commands over a local channel (a named pipe on Windows, a Unix socket on Linux).
+// A command is sent as one JSON request and answered with one JSON response holding its output.
+package instance
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// ProtocolVersion is the version of the requests and responses. Instances of another
+// version reject the request instead of misreading it.
+const ProtocolVersion = 1
+
+// ErrRunning is returned by Lock when another instance holds the lock.
+var ErrRunning = errors.New("another instance is running")
+
+// Request asks the running instance to run a command.
+type Request struct {
+	Version int      `json:"version"`
+	Args    []string `json:"args"`
+}
+
+// Response is the result of a command.
+type Response struct {
+	Code   int    `json:"code"`
+	Stdout string `json:"stdout"`
+	Stderr string `json:"stderr"`
+}
+
+// Handler runs a command and returns its exit code. ctx is canceled when the client goes away,
+// e.g. on Ctrl+C.
+type Handler func(ctx context.Context, args []string, stdout, stderr io.Writer) int
+
+// Listener accepts the connections of other instances.
+type Listener interface {
+	Accept() (io.ReadWriteCloser, error)
+	Close() error
+}
+
+// Serve runs every request received by l with handle until l is closed.
+// Connections are served concurrently.
+func Serve(l Listener, handle Handler) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go serveConn(conn, handle)
+	}
+}
+
+// serveConn answers the request of one connection.
+func serveConn(conn io.ReadWriteCloser, handle Handler) {
+	defer conn.Close()
+
+	var req Request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		slog.Warn("invalid instance request", "err", err)
+		return
+	}
+
+	var resp Response
+	if req.Version != ProtocolVersion {
+		resp = Response{Code: 1, Stderr: fmt.Sprintf("the running instance speaks protocol %d, got %d\n", ProtocolVersion, req.Version)}
+	} else {
+		ctx, cancel := context.WithCancel(context.Background())
+		stopWatching := watchClient(conn, cancel)
+		var stdout, stderr bytes.Buffer
+		resp.Code = handle(ctx, req.Args, &stdout, &stderr)
+		resp.Stdout, resp.Stderr = stdout.String(), stderr.String()
+		stopWatching()
+		cancel()
+	}
+	if err := json.NewEncoder(conn).Encode(resp); err != nil {
+		slog.Warn("failed to answer instance request", "args", req.Args, "err", err)
+	}
+}
+
+// clientWatcher is implemented by connections that can't be read while the response is written,
+// such as synchronous named pipes, and detect a client that went away otherwise.
+type clientWatcher interface {
+	// watchClient calls gone when the client goes away until stop returns.
+	watchClient(gone func()) (stop func())
+}
+
+// watchClient calls gone when the client of conn goes away. The client sends nothing after its
+// request, so a read returns only when it closes the connection.
+func watchClient(conn io.ReadWriteCloser, gone func()) (stop func()) {
+	if w, ok := conn.(clientWatcher); ok {
+		return w.watchClient(gone)
+	}
+	go func() {
+		io.Copy(io.Discard, conn)
+		gone()
+	}()
+	return func() {}
+}
+
+// Forward sends args to the instance at the other end of conn, copies the output of
+// the command to stdout and stderr and returns its exit code. It closes conn, also when
+// ctx is canceled, which cancels the command, and then returns ctx.Err().
+func Forward(ctx context.Context, conn io.ReadWriteCloser, args []string, stdout, stderr io.Writer) (int, error) {
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if err := json.NewEncoder(conn).Encode(Request{Version: ProtocolVersion, Args: args}); err != nil {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		return 0, fmt.Errorf("failed to send the command: %v", err)
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		return 0, fmt.Errorf("failed to read the result: %v", err)
+	}
+	io.WriteString(stdout, resp.Stdout)
+	io.WriteString(stderr, resp.Stderr)
+	return resp.Code, nil
+}
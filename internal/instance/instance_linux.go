@@ -0,0 +1,79 @@
+//go:build linux
+
+// Description: This file contains the Linux lock and channel: a locked file and a Unix socket
+// in the runtime directory of the user.
+
+package instance
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// runtimeDir returns the directory of the lock file and the socket.
+func runtimeDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return dir
+	}
+	return os.TempDir()
+}
+
+// socketPath returns the path of the socket of the instance called name.
+func socketPath(name string) string {
+	return filepath.Join(runtimeDir(), fmt.Sprintf("%s-%d.sock", name, os.Getuid()))
+}
+
+// Lock makes this process the running instance called name. It returns ErrRunning if
+// another process is, otherwise a function releasing the lock.
+func Lock(name string) (func(), error) {
+	path := filepath.Join(runtimeDir(), fmt.Sprintf("%s-%d.lock", name, os.Getuid()))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %v", err)
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, ErrRunning
+		}
+		return nil, fmt.Errorf("failed to lock %s: %v", path, err)
+	}
+	return func() { file.Close() }, nil
+}
+
+// Listen creates the channel of the instance called name. Call it while holding the lock:
+// a socket left by an instance that crashed is replaced.
+func Listen(name string) (Listener, error) {
+	path := socketPath(name)
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to remove stale socket: %v", err)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("failed to restrict socket: %v", err)
+	}
+	return unixListener{l}, nil
+}
+
+// Dial connects to the running instance called name.
+func Dial(name string) (io.ReadWriteCloser, error) {
+	return net.Dial("unix", socketPath(name))
+}
+
+// unixListener adapts a net.Listener to Listener.
+type unixListener struct {
+	net.Listener
+}
+
+func (l unixListener) Accept() (io.ReadWriteCloser, error) {
+	return l.Listener.Accept()
+}
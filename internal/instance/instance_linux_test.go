@@ -0,0 +1,46 @@
+package instance
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestLockListenDial(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	const name = "instance-test"
+
+	release, err := Lock(name)
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	if _, err := Lock(name); err != ErrRunning {
+		t.Errorf("second Lock() = %v, want %v", err, ErrRunning)
+	}
+	if _, err := Dial(name); err == nil {
+		t.Error("Dial() without a listener expected an error")
+	}
+
+	l, err := Listen(name)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer l.Close()
+	go Serve(l, echo)
+
+	conn, err := Dial(name)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	var stdout bytes.Buffer
+	if code, err := Forward(context.Background(), conn, []string{"status"}, &stdout, &stdout); err != nil || code != 0 || stdout.String() != "status\n" {
+		t.Errorf("Forward() = %d, %v, output %q", code, err, stdout.String())
+	}
+
+	release()
+	release, err = Lock(name)
+	if err != nil {
+		t.Fatalf("Lock() after release error = %v", err)
+	}
+	release()
+}
@@ -0,0 +1,25 @@
+//go:build !windows && !linux
+
+// Description: This file contains the fallback for unsupported platforms, where every process runs alone.
+
+package instance
+
+import (
+	"errors"
+	"io"
+)
+
+// Lock always succeeds, instances are not detected.
+func Lock(name string) (func(), error) {
+	return func() {}, nil
+}
+
+// Listen is not supported.
+func Listen(name string) (Listener, error) {
+	return nil, errors.ErrUnsupported
+}
+
+// Dial is not supported.
+func Dial(name string) (io.ReadWriteCloser, error) {
+	return nil, errors.ErrUnsupported
+}
@@ -0,0 +1,148 @@
+package instance
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memoryListener hands out the server ends of in-memory connections.
+type memoryListener struct {
+	conns chan net.Conn
+}
+
+func newMemoryListener() *memoryListener {
+	return &memoryListener{conns: make(chan net.Conn)}
+}
+
+func (l *memoryListener) Accept() (io.ReadWriteCloser, error) {
+	conn, ok := <-l.conns
+	if !ok {
+		return nil, net.ErrClosed
+	}
+	return conn, nil
+}
+
+func (l *memoryListener) Close() error {
+	close(l.conns)
+	return nil
+}
+
+// dial returns the client end of a new connection.
+func (l *memoryListener) dial() net.Conn {
+	client, server := net.Pipe()
+	l.conns <- server
+	return client
+}
+
+func echo(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fmt.Fprintln(stdout, strings.Join(args, " "))
+	if len(args) > 0 && args[0] == "fail" {
+		fmt.Fprintln(stderr, "failed")
+		return 1
+	}
+	return 0
+}
+
+func TestForward(t *testing.T) {
+	l := newMemoryListener()
+	done := make(chan error)
+	go func() { done <- Serve(l, echo) }()
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStdout string
+		wantStderr string
+	}{
+		{"success", []string{"clean-standby", "--json"}, 0, "clean-standby --json\n", ""},
+		{"failure", []string{"fail"}, 1, "fail\n", "failed\n"},
+		{"no arguments", nil, 0, "\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code, err := Forward(context.Background(), l.dial(), tt.args, &stdout, &stderr)
+			if err != nil {
+				t.Fatalf("Forward() error = %v", err)
+			}
+			if code != tt.wantCode || stdout.String() != tt.wantStdout || stderr.String() != tt.wantStderr {
+				t.Errorf("Forward() = %d, %q, %q, want %d, %q, %q",
+					code, stdout.String(), stderr.String(), tt.wantCode, tt.wantStdout, tt.wantStderr)
+			}
+		})
+	}
+
+	l.Close()
+	if err := <-done; err != net.ErrClosed {
+		t.Errorf("Serve() = %v, want %v", err, net.ErrClosed)
+	}
+}
+
+func TestProtocolMismatch(t *testing.T) {
+	l := newMemoryListener()
+	defer l.Close()
+	called := false
+	go Serve(l, func(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+		called = true
+		return 0
+	})
+
+	conn := l.dial()
+	defer conn.Close()
+	if err := json.NewEncoder(conn).Encode(Request{Version: ProtocolVersion + 1, Args: []string{"status"}}); err != nil {
+		t.Fatal(err)
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code == 0 || resp.Stderr == "" || called {
+		t.Errorf("response = %+v, handler called %v, want a rejected request", resp, called)
+	}
+}
+
+func TestForwardToClosedConnection(t *testing.T) {
+	client, server := net.Pipe()
+	server.Close()
+	if _, err := Forward(context.Background(), client, []string{"status"}, io.Discard, io.Discard); err == nil {
+		t.Error("Forward() expected an error")
+	}
+}
+
+func TestForwardCanceled(t *testing.T) {
+	l := newMemoryListener()
+	defer l.Close()
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	go Serve(l, func(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+		close(started)
+		select {
+		case <-ctx.Done():
+			close(canceled)
+		case <-time.After(5 * time.Second):
+		}
+		return 1
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	if _, err := Forward(ctx, l.dial(), []string{"clean-ram"}, io.Discard, io.Discard); err != context.Canceled {
+		t.Errorf("Forward() error = %v, want %v", err, context.Canceled)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Error("the command was not canceled when the client went away")
+	}
+}
@@ -0,0 +1,243 @@
+//go:build windows
+
+// Description: This file contains the Windows lock and channel: a named mutex in the session namespace
+// and a named pipe local to the computer. The elevated tray application lets the same user connect
+// from processes that are not elevated, such as a shortcut or a normal command prompt.
+
+package instance
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// pipeSecurity allows the system and administrators everything and the user read and write access,
+// without creating pipe instances (FILE_GENERIC_READ|FILE_WRITE_DATA|FILE_WRITE_ATTRIBUTES).
+// The medium integrity label lets processes that are not elevated write to the pipe.
+const pipeSecurity = "D:P(A;;GA;;;SY)(A;;GA;;;BA)(A;;0x12018b;;;%s)S:(ML;;NW;;;ME)"
+
+// clientAccess is the access of Dial. GENERIC_WRITE includes the right to create pipe instances,
+// which the user doesn't have.
+const clientAccess = windows.GENERIC_READ | windows.FILE_WRITE_DATA | windows.FILE_WRITE_ATTRIBUTES
+
+// dialTimeout is how long Dial waits for a busy pipe
+const dialTimeout = 2 * time.Second
+
+// watchInterval is how often a served pipe checks that its client is still connected
+const watchInterval = 250 * time.Millisecond
+
+// procPeekNamedPipe is missing from golang.org/x/sys/windows
+var procPeekNamedPipe = windows.NewLazySystemDLL("kernel32.dll").NewProc("PeekNamedPipe")
+
+// pipePath returns the path of the pipe of the instance called name.
+func pipePath(name string) string {
+	return `\\.\pipe\` + name
+}
+
+// Lock makes this process the running instance called name. It returns ErrRunning if
+// another process is, otherwise a function releasing the lock.
+func Lock(name string) (func(), error) {
+	mutexName, err := windows.UTF16PtrFromString(`Local\` + name)
+	if err != nil {
+		return nil, err
+	}
+	handle, err := windows.CreateMutex(nil, false, mutexName)
+	switch {
+	case errors.Is(err, windows.ERROR_ALREADY_EXISTS):
+		windows.CloseHandle(handle)
+		return nil, ErrRunning
+	case errors.Is(err, windows.ERROR_ACCESS_DENIED):
+		// The mutex was created by a process with more rights
+		return nil, ErrRunning
+	case err != nil:
+		return nil, fmt.Errorf("failed to create mutex: %v", err)
+	}
+	return func() { windows.CloseHandle(handle) }, nil
+}
+
+// Listen creates the pipe of the instance called name. It fails if another process created it.
+func Listen(name string) (Listener, error) {
+	user, err := windows.GetCurrentProcessToken().GetTokenUser()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current user: %v", err)
+	}
+	sd, err := windows.SecurityDescriptorFromString(fmt.Sprintf(pipeSecurity, user.User.Sid.String()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create security descriptor: %v", err)
+	}
+	path, err := windows.UTF16PtrFromString(pipePath(name))
+	if err != nil {
+		return nil, err
+	}
+
+	l := &pipeListener{name: name, path: path}
+	l.sa.Length = uint32(unsafe.Sizeof(l.sa))
+	l.sa.SecurityDescriptor = sd
+	if l.handle, err = l.createPipe(true); err != nil {
+		return nil, fmt.Errorf("failed to create pipe: %v", err)
+	}
+	return l, nil
+}
+
+// Dial connects to the running instance called name.
+func Dial(name string) (io.ReadWriteCloser, error) {
+	path, err := windows.UTF16PtrFromString(pipePath(name))
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(dialTimeout)
+	for {
+		handle, err := windows.CreateFile(
+			path,
+			clientAccess,
+			0,
+			nil,
+			windows.OPEN_EXISTING,
+			// The instance may only identify the client, not act as it
+			windows.SECURITY_SQOS_PRESENT|windows.SECURITY_IDENTIFICATION,
+			0,
+		)
+		if err == nil {
+			return os.NewFile(uintptr(handle), pipePath(name)), nil
+		}
+		// Every instance of the pipe is serving another client
+		if errors.Is(err, windows.ERROR_PIPE_BUSY) && time.Now().Before(deadline) {
+			time.Sleep(50 * time.Millisecond)
+			continue
+		}
+		return nil, err
+	}
+}
+
+// pipeListener accepts clients on a named pipe. Every client gets its own pipe instance,
+// the next instance is created when a client connects.
+type pipeListener struct {
+	name string
+	path *uint16
+	sa   windows.SecurityAttributes
+
+	mu     sync.Mutex
+	handle windows.Handle // Instance waiting for the next client
+	closed bool
+}
+
+// createPipe creates a pipe instance. The first instance fails if the pipe exists already.
+func (l *pipeListener) createPipe(first bool) (windows.Handle, error) {
+	flags := uint32(windows.PIPE_ACCESS_DUPLEX)
+	if first {
+		flags |= windows.FILE_FLAG_FIRST_PIPE_INSTANCE
+	}
+	return windows.CreateNamedPipe(
+		l.path,
+		flags,
+		windows.PIPE_TYPE_BYTE|windows.PIPE_READMODE_BYTE|windows.PIPE_WAIT|windows.PIPE_REJECT_REMOTE_CLIENTS,
+		windows.PIPE_UNLIMITED_INSTANCES,
+		4096,
+		4096,
+		0,
+		&l.sa,
+	)
+}
+
+// Accept waits for the next client. Close unblocks it.
+func (l *pipeListener) Accept() (io.ReadWriteCloser, error) {
+	for {
+		l.mu.Lock()
+		handle, closed := l.handle, l.closed
+		l.mu.Unlock()
+		if closed {
+			return nil, net.ErrClosed
+		}
+
+		err := windows.ConnectNamedPipe(handle, nil)
+
+		l.mu.Lock()
+		if l.closed {
+			l.mu.Unlock()
+			windows.CloseHandle(handle)
+			return nil, net.ErrClosed
+		}
+		if err != nil && !errors.Is(err, windows.ERROR_PIPE_CONNECTED) {
+			// The client went away before it was accepted, wait for another one
+			l.mu.Unlock()
+			windows.DisconnectNamedPipe(handle)
+			continue
+		}
+		next, err := l.createPipe(false)
+		if err != nil {
+			l.mu.Unlock()
+			windows.CloseHandle(handle)
+			return nil, fmt.Errorf("failed to create pipe: %v", err)
+		}
+		l.handle = next
+		l.mu.Unlock()
+
+		return pipeConn{os.NewFile(uintptr(handle), pipePath(l.name))}, nil
+	}
+}
+
+// Close stops accepting clients. Served clients are not affected.
+func (l *pipeListener) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	l.mu.Unlock()
+
+	// Connect to the waiting instance to unblock Accept
+	conn, err := Dial(l.name)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// pipeConn is the server end of a pipe instance.
+type pipeConn struct {
+	*os.File
+}
+
+// watchClient polls the pipe instead of reading it: a read pending on a synchronous pipe would
+// block writing the response.
+func (c pipeConn) watchClient(gone func()) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(watchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			// Fails with ERROR_BROKEN_PIPE once the client closed its end
+			if r, _, _ := procPeekNamedPipe.Call(c.Fd(), 0, 0, 0, 0, 0); r == 0 {
+				gone()
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// Close waits for the client to read everything written before closing the instance.
+func (c pipeConn) Close() error {
+	c.File.Sync()
+	return c.File.Close()
+}
@@ -0,0 +1,103 @@
+// Package inventory Description: This package contains the memory inventory of the running processes
+// and its ordering, shared by the tray menu and the command line.
+package inventory
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Process is the memory usage of one running process.
+type Process struct {
+	PID          uint32 `json:"pid"`
+	ParentPID    uint32 `json:"parent_pid"`
+	Name         string `json:"name"`
+	Path         string `json:"path,omitempty"`
+	ParentName   string `json:"parent_name,omitempty"`
+	WorkingSet   uint64 `json:"working_set_bytes"`
+	PrivateBytes uint64 `json:"private_bytes"`
+	PageFaults   uint64 `json:"page_faults"`
+	// Critical is set for processes of the critical list, which a basic clean skips.
+	Critical bool `json:"critical"`
+	// Excluded is set for processes denied by a process rule, which no clean trims.
+	Excluded bool `json:"excluded"`
+	// Error tells why the memory counters are missing, e.g. for protected processes.
+	Error string `json:"error,omitempty"`
+}
+
+// Flags returns the critical and excluded flags as a short text, "-" if none is set.
+func (p Process) Flags() string {
+	var flags []string
+	if p.Critical {
+		flags = append(flags, "critical")
+	}
+	if p.Excluded {
+		flags = append(flags, "excluded")
+	}
+	if len(flags) == 0 {
+		return "-"
+	}
+	return strings.Join(flags, ",")
+}
+
+// SortKey is the order of an inventory.
+type SortKey string
+
+const (
+	SortWorkingSet SortKey = "ws"      // Largest working set first
+	SortPrivate    SortKey = "private" // Largest private bytes first
+	SortPageFaults SortKey = "faults"  // Most page faults first
+	SortPID        SortKey = "pid"     // Lowest PID first
+	SortName       SortKey = "name"    // Alphabetical, case-insensitive
+)
+
+// SortKeys lists the supported orders.
+var SortKeys = []SortKey{SortWorkingSet, SortPrivate, SortPageFaults, SortPID, SortName}
+
+// ParseSortKey returns the order with the given name.
+func ParseSortKey(name string) (SortKey, error) {
+	for _, key := range SortKeys {
+		if string(key) == strings.ToLower(name) {
+			return key, nil
+		}
+	}
+	return "", fmt.Errorf("unknown sort key %q", name)
+}
+
+// Sort orders processes by key. Ties are ordered by PID, so the result is deterministic.
+func Sort(processes []Process, key SortKey) {
+	less := func(a, b Process) bool {
+		switch key {
+		case SortWorkingSet:
+			if a.WorkingSet != b.WorkingSet {
+				return a.WorkingSet > b.WorkingSet
+			}
+		case SortPrivate:
+			if a.PrivateBytes != b.PrivateBytes {
+				return a.PrivateBytes > b.PrivateBytes
+			}
+		case SortPageFaults:
+			if a.PageFaults != b.PageFaults {
+				return a.PageFaults > b.PageFaults
+			}
+		case SortName:
+			if an, bn := strings.ToLower(a.Name), strings.ToLower(b.Name); an != bn {
+				return an < bn
+			}
+		}
+		return a.PID < b.PID
+	}
+	sort.Slice(processes, func(i, j int) bool { return less(processes[i], processes[j]) })
+}
+
+// Top returns the first n processes in the order of key, without modifying processes.
+// A non-positive n returns all of them.
+func Top(processes []Process, n int, key SortKey) []Process {
+	sorted := append([]Process(nil), processes...)
+	Sort(sorted, key)
+	if n > 0 && n < len(sorted) {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
@@ -0,0 +1,82 @@
+package inventory
+
+import "testing"
+
+func testProcesses() []Process {
+	return []Process{
+		{PID: 4, Name: "System", WorkingSet: 100, PrivateBytes: 10, PageFaults: 5, Critical: true},
+		{PID: 100, Name: "chrome.exe", WorkingSet: 900, PrivateBytes: 700, PageFaults: 50},
+		{PID: 200, Name: "Code.exe", WorkingSet: 500, PrivateBytes: 800, PageFaults: 500},
+		{PID: 300, Name: "chrome.exe", WorkingSet: 900, PrivateBytes: 100, PageFaults: 1, Excluded: true},
+	}
+}
+
+func pids(processes []Process) []uint32 {
+	result := make([]uint32, len(processes))
+	for i, p := range processes {
+		result[i] = p.PID
+	}
+	return result
+}
+
+func TestSort(t *testing.T) {
+	tests := []struct {
+		key      SortKey
+		expected []uint32
+	}{
+		{key: SortWorkingSet, expected: []uint32{100, 300, 200, 4}},
+		{key: SortPrivate, expected: []uint32{200, 100, 300, 4}},
+		{key: SortPageFaults, expected: []uint32{200, 100, 4, 300}},
+		{key: SortPID, expected: []uint32{4, 100, 200, 300}},
+		{key: SortName, expected: []uint32{100, 300, 200, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.key), func(t *testing.T) {
+			processes := testProcesses()
+			Sort(processes, tt.key)
+			result := pids(processes)
+			for i := range tt.expected {
+				if result[i] != tt.expected[i] {
+					t.Fatalf("Sort() = %v, want %v", result, tt.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestTop(t *testing.T) {
+	processes := testProcesses()
+
+	top := Top(processes, 2, SortPrivate)
+	if len(top) != 2 || top[0].PID != 200 || top[1].PID != 100 {
+		t.Errorf("Top() = %v, want [200 100]", pids(top))
+	}
+	if processes[0].PID != 4 {
+		t.Errorf("Top() modified its input")
+	}
+	if all := Top(processes, 0, SortPID); len(all) != len(processes) {
+		t.Errorf("Top() with n = 0 returned %d processes, want %d", len(all), len(processes))
+	}
+}
+
+func TestParseSortKey(t *testing.T) {
+	for _, key := range SortKeys {
+		if parsed, err := ParseSortKey(string(key)); err != nil || parsed != key {
+			t.Errorf("ParseSortKey(%q) = %q, %v", key, parsed, err)
+		}
+	}
+	if _, err := ParseSortKey("size"); err == nil {
+		t.Errorf("ParseSortKey() of an unknown key expected an error")
+	}
+}
+
+func TestFlags(t *testing.T) {
+	processes := testProcesses()
+	expected := []string{"critical", "-", "-", "excluded"}
+	for i, p := range processes {
+		if result := p.Flags(); result != expected[i] {
+			t.Errorf("Flags() of %d = %q, want %q", p.PID, result, expected[i])
+		}
+	}
+}
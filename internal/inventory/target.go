@@ -0,0 +1,85 @@
+// Description: This file contains the selection of the processes of a targeted trim.
+
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoProcess is returned when no running process matches a target.
+var ErrNoProcess = errors.New("no matching process")
+
+// Target selects one process by PID, or every process with a name.
+type Target struct {
+	PID  uint32 `json:"pid,omitempty"`
+	Name string `json:"name,omitempty"` // Case-insensitive, e.g. "chrome.exe"
+	// Tree also selects the children of the matching processes, and their children.
+	Tree bool `json:"tree,omitempty"`
+}
+
+// Validate checks that exactly one of PID and Name is set.
+func (t Target) Validate() error {
+	switch {
+	case t.PID == 0 && t.Name == "":
+		return errors.New("a PID or a process name is required")
+	case t.PID != 0 && t.Name != "":
+		return errors.New("a PID and a process name can't be used together")
+	}
+	return nil
+}
+
+// String returns a short description, e.g. "chrome.exe and children".
+func (t Target) String() string {
+	text := t.Name
+	if t.PID != 0 {
+		text = fmt.Sprintf("PID %d", t.PID)
+	}
+	if t.Tree {
+		text += " and children"
+	}
+	return text
+}
+
+// matches reports whether p is one of the processes named by t, not counting the children.
+func (t Target) matches(p Process) bool {
+	if t.PID != 0 {
+		return p.PID == t.PID
+	}
+	return strings.EqualFold(p.Name, t.Name)
+}
+
+// Select returns the processes matching t, in the order of processes. Parent PIDs may refer
+// to exited processes whose PID was reused, so a process is never selected twice.
+func Select(processes []Process, t Target) []Process {
+	selected := make(map[uint32]bool)
+	for _, p := range processes {
+		if t.matches(p) {
+			selected[p.PID] = true
+		}
+	}
+
+	// Add children until no new process is found; each pass selects at least one
+	// process or stops, so this ends after at most len(processes) passes.
+	for t.Tree {
+		added := false
+		for _, p := range processes {
+			if !selected[p.PID] && p.PID != p.ParentPID && selected[p.ParentPID] {
+				selected[p.PID] = true
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+
+	var result []Process
+	for _, p := range processes {
+		if selected[p.PID] {
+			result = append(result, p)
+		}
+	}
+	return result
+}
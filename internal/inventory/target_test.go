@@ -0,0 +1,84 @@
+package inventory
+
+import (
+	"reflect"
+	"testing"
+)
+
+func treeProcesses() []Process {
+	return []Process{
+		{PID: 0, ParentPID: 0, Name: "System Idle Process"},
+		{PID: 4, ParentPID: 0, Name: "System"},
+		{PID: 100, ParentPID: 4, Name: "explorer.exe"},
+		{PID: 200, ParentPID: 100, Name: "chrome.exe"},
+		{PID: 210, ParentPID: 200, Name: "chrome.exe"},
+		{PID: 220, ParentPID: 210, Name: "crashpad.exe"},
+		{PID: 300, ParentPID: 100, Name: "Code.exe"},
+		// The parent exited and its PID was reused by the child
+		{PID: 400, ParentPID: 410, Name: "a.exe"},
+		{PID: 410, ParentPID: 400, Name: "b.exe"},
+	}
+}
+
+func TestSelect(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   Target
+		expected []uint32
+	}{
+		{name: "pid", target: Target{PID: 200}, expected: []uint32{200}},
+		{name: "pid tree", target: Target{PID: 200, Tree: true}, expected: []uint32{200, 210, 220}},
+		{name: "name", target: Target{Name: "CHROME.EXE"}, expected: []uint32{200, 210}},
+		{name: "name tree", target: Target{Name: "chrome.exe", Tree: true}, expected: []uint32{200, 210, 220}},
+		{name: "deep tree", target: Target{Name: "explorer.exe", Tree: true}, expected: []uint32{100, 200, 210, 220, 300}},
+		{name: "parent cycle", target: Target{PID: 400, Tree: true}, expected: []uint32{400, 410}},
+		{name: "no match", target: Target{Name: "missing.exe", Tree: true}, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Select(treeProcesses(), tt.target)
+			var gotPIDs []uint32
+			if got != nil {
+				gotPIDs = pids(got)
+			}
+			if !reflect.DeepEqual(gotPIDs, tt.expected) {
+				t.Errorf("Select(%v) = %v, want %v", tt.target, gotPIDs, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTargetValidate(t *testing.T) {
+	tests := []struct {
+		target  Target
+		wantErr bool
+	}{
+		{target: Target{PID: 1}, wantErr: false},
+		{target: Target{Name: "a.exe", Tree: true}, wantErr: false},
+		{target: Target{}, wantErr: true},
+		{target: Target{PID: 1, Name: "a.exe"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := tt.target.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) error = %v, wantErr %v", tt.target, err, tt.wantErr)
+		}
+	}
+}
+
+func TestTargetString(t *testing.T) {
+	tests := []struct {
+		target   Target
+		expected string
+	}{
+		{target: Target{PID: 42}, expected: "PID 42"},
+		{target: Target{Name: "chrome.exe", Tree: true}, expected: "chrome.exe and children"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.target.String(); got != tt.expected {
+			t.Errorf("String() = %q, want %q", got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Package memlist Description: This package contains the operations on the memory lists of the
+// Windows memory manager and their encoding for NtSetSystemInformation. The encoding is
+// platform-neutral so that it can be tested without calling the system.
+package memlist
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"windows-ram-cleaner/internal/memory"
+)
+
+// Information classes of NtSetSystemInformation.
+const (
+	SystemMemoryListInformationClass     = 0x50
+	SystemCombinePhysicalMemoryInfoClass = 0x82
+)
+
+// Command is an operation on the memory lists. The values up to PurgeLowPriorityStandbyList
+// are the SYSTEM_MEMORY_LIST_COMMAND values sent to SystemMemoryListInformationClass.
+type Command uint32
+
+const (
+	CaptureAccessedBits         Command = 0 // Record which pages were accessed
+	CaptureAndResetAccessedBits Command = 1 // Record and clear the accessed bits
+	EmptyWorkingSets            Command = 2 // Move the pages of all working sets to the standby and modified lists
+	FlushModifiedList           Command = 3 // Write the modified pages to disk, moving them to the standby list
+	PurgeStandbyList            Command = 4 // Free the whole standby list
+	PurgeLowPriorityStandbyList Command = 5 // Free the standby pages of priority 0 only
+
+	// CombinePageLists merges identical pages. It is not part of SYSTEM_MEMORY_LIST_COMMAND
+	// and is sent to SystemCombinePhysicalMemoryInfoClass.
+	CombinePageLists Command = 0x100
+)
+
+// Commands lists every command in menu order.
+var Commands = []Command{
+	EmptyWorkingSets, FlushModifiedList, PurgeStandbyList, PurgeLowPriorityStandbyList,
+	CombinePageLists, CaptureAccessedBits, CaptureAndResetAccessedBits,
+}
+
+var names = map[Command]string{
+	CaptureAccessedBits:         "capture-accessed-bits",
+	CaptureAndResetAccessedBits: "reset-accessed-bits",
+	EmptyWorkingSets:            "empty-working-sets",
+	FlushModifiedList:           "flush-modified",
+	PurgeStandbyList:            "purge-standby",
+	PurgeLowPriorityStandbyList: "purge-low-priority-standby",
+	CombinePageLists:            "combine-pages",
+}
+
+var titles = map[Command]string{
+	CaptureAccessedBits:         "Capture Accessed Bits",
+	CaptureAndResetAccessedBits: "Capture and Reset Accessed Bits",
+	EmptyWorkingSets:            "Empty Working Sets",
+	FlushModifiedList:           "Flush Modified List",
+	PurgeStandbyList:            "Purge Standby List",
+	PurgeLowPriorityStandbyList: "Purge Low Priority Standby List",
+	CombinePageLists:            "Combine Memory Pages",
+}
+
+// String returns the command-line name of the command.
+func (c Command) String() string {
+	if name, ok := names[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("command(%d)", uint32(c))
+}
+
+// Title returns the name of the command shown in menus.
+func (c Command) Title() string {
+	if title, ok := titles[c]; ok {
+		return title
+	}
+	return c.String()
+}
+
+// Valid reports whether c is a known command.
+func (c Command) Valid() bool {
+	_, ok := names[c]
+	return ok
+}
+
+// Parse returns the command with the given command-line name.
+func Parse(name string) (Command, error) {
+	for c, n := range names {
+		if strings.EqualFold(n, name) {
+			return c, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown memory list command %q", name)
+}
+
+// Request returns the information class and the input buffer of NtSetSystemInformation for c.
+// pointerSize is the size of a pointer of the system in bytes, 4 or 8.
+func (c Command) Request(pointerSize int) (class uint32, buf []byte, err error) {
+	if pointerSize != 4 && pointerSize != 8 {
+		return 0, nil, fmt.Errorf("unsupported pointer size %d", pointerSize)
+	}
+
+	switch {
+	case c == CombinePageLists:
+		// MEMORY_COMBINE_INFORMATION_EX: HANDLE Handle, ULONG_PTR PagesCombined, ULONG Flags,
+		// padded to the pointer alignment. Everything is zero on input.
+		size := 2*pointerSize + 4
+		size = (size + pointerSize - 1) / pointerSize * pointerSize
+		return SystemCombinePhysicalMemoryInfoClass, make([]byte, size), nil
+	case c.Valid():
+		buf = make([]byte, 4)
+		binary.LittleEndian.PutUint32(buf, uint32(c))
+		return SystemMemoryListInformationClass, buf, nil
+	default:
+		return 0, nil, fmt.Errorf("invalid memory list command %d", uint32(c))
+	}
+}
+
+// PagesCombined decodes the number of combined pages from the buffer of a CombinePageLists request
+// after the call.
+func PagesCombined(buf []byte, pointerSize int) (uint64, error) {
+	if len(buf) < 2*pointerSize {
+		return 0, fmt.Errorf("buffer of %d bytes is too short", len(buf))
+	}
+	field := buf[pointerSize : 2*pointerSize]
+	if pointerSize == 4 {
+		return uint64(binary.LittleEndian.Uint32(field)), nil
+	}
+	return binary.LittleEndian.Uint64(field), nil
+}
+
+// Freed estimates the memory released by c from the snapshots before and after it: the shrinking
+// of the list the command empties. Emptying working sets moves pages to the standby and modified
+// lists, so their growth is reported. Capturing accessed bits releases nothing.
+func (c Command) Freed(before, after memory.Snapshot) uint64 {
+	switch c {
+	case EmptyWorkingSets:
+		return delta(after.StandbySize+after.ModifiedSize, before.StandbySize+before.ModifiedSize)
+	case FlushModifiedList:
+		return delta(before.ModifiedSize, after.ModifiedSize)
+	case PurgeStandbyList, PurgeLowPriorityStandbyList:
+		return delta(before.StandbySize, after.StandbySize)
+	default:
+		return 0
+	}
+}
+
+// delta returns a - b, zero if b is larger.
+func delta(a, b uint64) uint64 {
+	if b >= a {
+		return 0
+	}
+	return a - b
+}
@@ -0,0 +1,102 @@
+package memlist
+
+import (
+	"bytes"
+	"testing"
+
+	"windows-ram-cleaner/internal/memory"
+)
+
+func TestRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		command     Command
+		pointerSize int
+		class       uint32
+		buf         []byte
+	}{
+		{name: "capture", command: CaptureAccessedBits, pointerSize: 8, class: 0x50, buf: []byte{0, 0, 0, 0}},
+		{name: "capture and reset", command: CaptureAndResetAccessedBits, pointerSize: 8, class: 0x50, buf: []byte{1, 0, 0, 0}},
+		{name: "empty working sets", command: EmptyWorkingSets, pointerSize: 8, class: 0x50, buf: []byte{2, 0, 0, 0}},
+		{name: "flush modified", command: FlushModifiedList, pointerSize: 8, class: 0x50, buf: []byte{3, 0, 0, 0}},
+		{name: "purge standby", command: PurgeStandbyList, pointerSize: 8, class: 0x50, buf: []byte{4, 0, 0, 0}},
+		{name: "purge standby 32-bit", command: PurgeStandbyList, pointerSize: 4, class: 0x50, buf: []byte{4, 0, 0, 0}},
+		{name: "purge low priority", command: PurgeLowPriorityStandbyList, pointerSize: 8, class: 0x50, buf: []byte{5, 0, 0, 0}},
+		{name: "combine 64-bit", command: CombinePageLists, pointerSize: 8, class: 0x82, buf: make([]byte, 24)},
+		{name: "combine 32-bit", command: CombinePageLists, pointerSize: 4, class: 0x82, buf: make([]byte, 12)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			class, buf, err := tt.command.Request(tt.pointerSize)
+			if err != nil {
+				t.Fatalf("Request() error = %v", err)
+			}
+			if class != tt.class || !bytes.Equal(buf, tt.buf) {
+				t.Errorf("Request() = %#x %v, want %#x %v", class, buf, tt.class, tt.buf)
+			}
+		})
+	}
+}
+
+func TestRequestErrors(t *testing.T) {
+	if _, _, err := Command(6).Request(8); err == nil {
+		t.Errorf("Request() of an unknown command expected an error")
+	}
+	if _, _, err := PurgeStandbyList.Request(2); err == nil {
+		t.Errorf("Request() with an invalid pointer size expected an error")
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, c := range Commands {
+		parsed, err := Parse(c.String())
+		if err != nil || parsed != c {
+			t.Errorf("Parse(%q) = %v, %v, want %v", c.String(), parsed, err, c)
+		}
+	}
+	if _, err := Parse("defrag"); err == nil {
+		t.Errorf("Parse() of an unknown name expected an error")
+	}
+}
+
+func TestPagesCombined(t *testing.T) {
+	buf64 := []byte{0, 0, 0, 0, 0, 0, 0, 0, 0x10, 0x27, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	if pages, err := PagesCombined(buf64, 8); err != nil || pages != 10000 {
+		t.Errorf("PagesCombined() 64-bit = %d, %v, want 10000", pages, err)
+	}
+	buf32 := []byte{0, 0, 0, 0, 0x10, 0x27, 0, 0, 0, 0, 0, 0}
+	if pages, err := PagesCombined(buf32, 4); err != nil || pages != 10000 {
+		t.Errorf("PagesCombined() 32-bit = %d, %v, want 10000", pages, err)
+	}
+	if _, err := PagesCombined(buf32[:6], 4); err == nil {
+		t.Errorf("PagesCombined() of a short buffer expected an error")
+	}
+}
+
+func TestFreed(t *testing.T) {
+	before := memory.Snapshot{StandbySize: 1000, ModifiedSize: 300}
+	after := memory.Snapshot{StandbySize: 1500, ModifiedSize: 100}
+
+	tests := []struct {
+		command  Command
+		expected uint64
+	}{
+		{command: EmptyWorkingSets, expected: 300},
+		{command: FlushModifiedList, expected: 200},
+		{command: PurgeStandbyList, expected: 0},
+		{command: CaptureAccessedBits, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command.String(), func(t *testing.T) {
+			if result := tt.command.Freed(before, after); result != tt.expected {
+				t.Errorf("Freed() = %d, want %d", result, tt.expected)
+			}
+		})
+	}
+
+	if result := PurgeStandbyList.Freed(after, before); result != 500 {
+		t.Errorf("Freed() = %d, want 500", result)
+	}
+}
@@ -0,0 +1,49 @@
+// Description: This file contains a fake Provider for tests.
+
+package memory
+
+import "sync"
+
+// Fake is a Provider returning a preset snapshot. It is safe for concurrent use.
+type Fake struct {
+	mu      sync.Mutex
+	current Snapshot
+	err     error
+	calls   int
+}
+
+// NewFake creates a Fake returning the given snapshot.
+func NewFake(s Snapshot) *Fake {
+	return &Fake{current: s}
+}
+
+// Set replaces the snapshot returned by the next calls.
+func (f *Fake) Set(s Snapshot) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.current = s
+}
+
+// SetError makes the next calls fail with err. A nil err restores normal behaviour.
+func (f *Fake) SetError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+// Calls returns how many times Snapshot was called.
+func (f *Fake) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func (f *Fake) Snapshot() (Snapshot, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.err != nil {
+		return Snapshot{}, f.err
+	}
+	return f.current, nil
+}
@@ -0,0 +1,62 @@
+// Description: This file contains the /proc/meminfo parser used by the Linux provider.
+
+package memory
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// parseMeminfo reads /proc/meminfo formatted data into a Snapshot.
+func parseMeminfo(r io.Reader) (Snapshot, error) {
+	fields := make(map[string]uint64)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		name, rest, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		parts := strings.Fields(rest)
+		if len(parts) == 0 {
+			continue
+		}
+		value, err := strconv.ParseUint(parts[0], 10, 64)
+		if err != nil {
+			return Snapshot{}, fmt.Errorf("invalid value for %s: %v", name, err)
+		}
+		if len(parts) > 1 && parts[1] == "kB" {
+			value *= 1024
+		}
+		fields[name] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return Snapshot{}, fmt.Errorf("failed to read meminfo: %v", err)
+	}
+
+	total, ok := fields["MemTotal"]
+	if !ok {
+		return Snapshot{}, fmt.Errorf("MemTotal is missing")
+	}
+
+	available, ok := fields["MemAvailable"]
+	if !ok {
+		// Kernels before 3.14 do not report MemAvailable.
+		available = fields["MemFree"] + fields["Buffers"] + fields["Cached"]
+	}
+
+	return Snapshot{
+		TotalSize:     total,
+		AvailableSize: available,
+		FreeSize:      fields["MemFree"],
+		StandbySize:   fields["Buffers"] + fields["Cached"],
+		ModifiedSize:  fields["Dirty"] + fields["Writeback"],
+		CommitSize:    fields["Committed_AS"],
+		CommitLimit:   fields["CommitLimit"],
+		PageFileSize:  fields["SwapTotal"],
+		PageFileFree:  fields["SwapFree"],
+	}, nil
+}
@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const meminfoFixture = `MemTotal:       16318024 kB
+MemFree:         1123456 kB
+MemAvailable:    8765432 kB
+Buffers:          204800 kB
+Cached:          6291456 kB
+SwapCached:            0 kB
+Dirty:              1024 kB
+Writeback:           512 kB
+SwapTotal:       2097148 kB
+SwapFree:        2000000 kB
+CommitLimit:    10256160 kB
+Committed_AS:   12345678 kB
+HugePages_Total:       0
+`
+
+func TestParseMeminfo(t *testing.T) {
+	s, err := parseMeminfo(strings.NewReader(meminfoFixture))
+	if err != nil {
+		t.Fatalf("parseMeminfo() error: %v", err)
+	}
+
+	expected := Snapshot{
+		TotalSize:     16318024 * 1024,
+		AvailableSize: 8765432 * 1024,
+		FreeSize:      1123456 * 1024,
+		StandbySize:   (204800 + 6291456) * 1024,
+		ModifiedSize:  (1024 + 512) * 1024,
+		CommitSize:    12345678 * 1024,
+		CommitLimit:   10256160 * 1024,
+		PageFileSize:  2097148 * 1024,
+		PageFileFree:  2000000 * 1024,
+	}
+	if s != expected {
+		t.Errorf("parseMeminfo() = %+v, want %+v", s, expected)
+	}
+}
+
+func TestParseMeminfoWithoutMemAvailable(t *testing.T) {
+	data := "MemTotal: 1000 kB\nMemFree: 100 kB\nBuffers: 20 kB\nCached: 300 kB\n"
+	s, err := parseMeminfo(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("parseMeminfo() error: %v", err)
+	}
+	if s.AvailableSize != 420*1024 {
+		t.Errorf("AvailableSize = %d, want %d", s.AvailableSize, 420*1024)
+	}
+}
+
+func TestParseMeminfoErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "empty input", data: ""},
+		{name: "missing MemTotal", data: "MemFree: 100 kB\n"},
+		{name: "invalid number", data: "MemTotal: lots kB\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseMeminfo(strings.NewReader(tt.data)); err == nil {
+				t.Errorf("parseMeminfo() expected an error")
+			}
+		})
+	}
+}
+
+func TestSnapshotPercents(t *testing.T) {
+	s := Snapshot{TotalSize: 200, AvailableSize: 50}
+	if p := s.AvailablePercent(); p != 25 {
+		t.Errorf("AvailablePercent() = %v, want 25", p)
+	}
+	if p := s.UsedPercent(); p != 75 {
+		t.Errorf("UsedPercent() = %v, want 75", p)
+	}
+	if p := (Snapshot{}).AvailablePercent(); p != 0 {
+		t.Errorf("AvailablePercent() of empty snapshot = %v, want 0", p)
+	}
+}
+
+func TestFake(t *testing.T) {
+	f := NewFake(Snapshot{TotalSize: 1})
+	if s, err := f.Snapshot(); err != nil || s.TotalSize != 1 {
+		t.Errorf("Snapshot() = %+v, %v", s, err)
+	}
+
+	f.SetError(errors.New("boom"))
+	if _, err := f.Snapshot(); err == nil {
+		t.Errorf("Snapshot() expected an error")
+	}
+	if f.Calls() != 2 {
+		t.Errorf("Calls() = %d, want 2", f.Calls())
+	}
+}
@@ -0,0 +1,89 @@
+// Description: This file contains the decoder of SYSTEM_MEMORY_LIST_INFORMATION, the page counts
+// of the memory lists returned by NtQuerySystemInformation. Decoding works on raw bytes so that
+// it is tested on every platform.
+
+package memory
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// StandbyPriorities is the number of standby list priorities.
+const StandbyPriorities = 8
+
+// memoryListFields is the number of ULONG_PTR fields of SYSTEM_MEMORY_LIST_INFORMATION.
+const memoryListFields = 5 + 2*StandbyPriorities + 1
+
+// MemoryListInfo is SYSTEM_MEMORY_LIST_INFORMATION. All values are page counts.
+type MemoryListInfo struct {
+	ZeroPageCount            uint64
+	FreePageCount            uint64
+	ModifiedPageCount        uint64
+	ModifiedNoWritePageCount uint64
+	BadPageCount             uint64
+	// StandbyPageCount is the standby list per priority, from 0 (lowest) to 7.
+	StandbyPageCount [StandbyPriorities]uint64
+	// RepurposedPageCount counts the standby pages of each priority reused for new data
+	// since the system started.
+	RepurposedPageCount       [StandbyPriorities]uint64
+	ModifiedPageCountPageFile uint64
+}
+
+// MemoryListInfoSize returns the size in bytes of SYSTEM_MEMORY_LIST_INFORMATION on a system
+// with the given pointer size.
+func MemoryListInfoSize(pointerSize int) int {
+	return memoryListFields * pointerSize
+}
+
+// DecodeMemoryListInfo decodes SYSTEM_MEMORY_LIST_INFORMATION from buf, written by a system with
+// the given pointer size (4 or 8 bytes) in little endian.
+func DecodeMemoryListInfo(buf []byte, pointerSize int) (MemoryListInfo, error) {
+	var info MemoryListInfo
+	if pointerSize != 4 && pointerSize != 8 {
+		return info, fmt.Errorf("unsupported pointer size %d", pointerSize)
+	}
+	if len(buf) < MemoryListInfoSize(pointerSize) {
+		return info, fmt.Errorf("memory list information is %d bytes, want at least %d", len(buf), MemoryListInfoSize(pointerSize))
+	}
+
+	next := func() uint64 {
+		var value uint64
+		if pointerSize == 4 {
+			value = uint64(binary.LittleEndian.Uint32(buf))
+		} else {
+			value = binary.LittleEndian.Uint64(buf)
+		}
+		buf = buf[pointerSize:]
+		return value
+	}
+
+	info.ZeroPageCount = next()
+	info.FreePageCount = next()
+	info.ModifiedPageCount = next()
+	info.ModifiedNoWritePageCount = next()
+	info.BadPageCount = next()
+	for i := range info.StandbyPageCount {
+		info.StandbyPageCount[i] = next()
+	}
+	for i := range info.RepurposedPageCount {
+		info.RepurposedPageCount[i] = next()
+	}
+	info.ModifiedPageCountPageFile = next()
+	return info, nil
+}
+
+// StandbyPages returns the size of the standby list in pages.
+func (i MemoryListInfo) StandbyPages() uint64 {
+	return sum(i.StandbyPageCount[:])
+}
+
+// apply sets the memory list sizes of s, converting the page counts with pageSize.
+func (i MemoryListInfo) apply(s *Snapshot, pageSize uint64) {
+	s.FreeSize = (i.ZeroPageCount + i.FreePageCount) * pageSize
+	s.ModifiedSize = (i.ModifiedPageCount + i.ModifiedNoWritePageCount) * pageSize
+	s.StandbySize = i.StandbyPages() * pageSize
+	for priority, count := range i.StandbyPageCount {
+		s.StandbyByPriority[priority] = count * pageSize
+	}
+}
@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// Golden SYSTEM_MEMORY_LIST_INFORMATION buffers. Every field has a distinct value:
+// zero 1000, free 2000, modified 300, modified no-write 40, bad 5, standby 10..17,
+// repurposed 100..107 and modified page file 250.
+const (
+	memoryListFixture64 = "e803000000000000d0070000000000002c0100000000000028000000000000000500000000000000" +
+		"0a000000000000000b000000000000000c000000000000000d000000000000000e000000000000000f000000000000001000000000000000" +
+		"1100000000000000640000000000000065000000000000006600000000000000670000000000000068000000000000006900000000000000" +
+		"6a000000000000006b00000000000000fa00000000000000"
+	memoryListFixture32 = "e8030000d00700002c0100002800000005000000" +
+		"0a0000000b0000000c0000000d0000000e0000000f0000001000000011000000" +
+		"6400000065000000660000006700000068000000690000006a0000006b000000fa000000"
+)
+
+var memoryListExpected = MemoryListInfo{
+	ZeroPageCount:             1000,
+	FreePageCount:             2000,
+	ModifiedPageCount:         300,
+	ModifiedNoWritePageCount:  40,
+	BadPageCount:              5,
+	StandbyPageCount:          [StandbyPriorities]uint64{10, 11, 12, 13, 14, 15, 16, 17},
+	RepurposedPageCount:       [StandbyPriorities]uint64{100, 101, 102, 103, 104, 105, 106, 107},
+	ModifiedPageCountPageFile: 250,
+}
+
+func TestDecodeMemoryListInfo(t *testing.T) {
+	tests := []struct {
+		name        string
+		fixture     string
+		pointerSize int
+	}{
+		{name: "64-bit", fixture: memoryListFixture64, pointerSize: 8},
+		{name: "32-bit", fixture: memoryListFixture32, pointerSize: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf, err := hex.DecodeString(tt.fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(buf) != MemoryListInfoSize(tt.pointerSize) {
+				t.Fatalf("fixture is %d bytes, want %d", len(buf), MemoryListInfoSize(tt.pointerSize))
+			}
+
+			info, err := DecodeMemoryListInfo(buf, tt.pointerSize)
+			if err != nil {
+				t.Fatalf("DecodeMemoryListInfo() error = %v", err)
+			}
+			if info != memoryListExpected {
+				t.Errorf("DecodeMemoryListInfo() = %+v, want %+v", info, memoryListExpected)
+			}
+		})
+	}
+}
+
+func TestDecodeMemoryListInfoErrors(t *testing.T) {
+	buf, _ := hex.DecodeString(memoryListFixture64)
+	if _, err := DecodeMemoryListInfo(buf[:100], 8); err == nil {
+		t.Errorf("DecodeMemoryListInfo() of a short buffer expected an error")
+	}
+	if _, err := DecodeMemoryListInfo(buf, 2); err == nil {
+		t.Errorf("DecodeMemoryListInfo() with an invalid pointer size expected an error")
+	}
+}
+
+func TestMemoryListInfoApply(t *testing.T) {
+	var s Snapshot
+	memoryListExpected.apply(&s, 4096)
+
+	if s.FreeSize != 3000*4096 {
+		t.Errorf("FreeSize = %d, want %d", s.FreeSize, 3000*4096)
+	}
+	if s.ModifiedSize != 340*4096 {
+		t.Errorf("ModifiedSize = %d, want %d", s.ModifiedSize, 340*4096)
+	}
+	if s.StandbySize != 108*4096 {
+		t.Errorf("StandbySize = %d, want %d", s.StandbySize, 108*4096)
+	}
+	if s.StandbyByPriority[0] != 10*4096 || s.StandbyByPriority[7] != 17*4096 {
+		t.Errorf("StandbyByPriority = %v, want the page counts of each priority", s.StandbyByPriority)
+	}
+
+	// Sizes follow the page size of the system instead of assuming 4 KB pages
+	memoryListExpected.apply(&s, 16384)
+	if s.StandbySize != 108*16384 {
+		t.Errorf("StandbySize with 16 KB pages = %d, want %d", s.StandbySize, 108*16384)
+	}
+}
@@ -0,0 +1,81 @@
+// Package memory Description: This package provides a platform-neutral view of the system memory state.
+// The concrete provider is selected at build time: WinAPI on Windows and /proc/meminfo on Linux.
+package memory
+
+// Snapshot is a point-in-time view of the system memory.
+// Sizes that a platform cannot report are left at zero.
+type Snapshot struct {
+	TotalSize     uint64 `json:"total_bytes"`          // Total physical memory in bytes
+	AvailableSize uint64 `json:"available_bytes"`      // Physical memory available to applications (free + standby) in bytes
+	FreeSize      uint64 `json:"free_bytes"`           // Free and zeroed pages in bytes
+	StandbySize   uint64 `json:"standby_bytes"`        // Standby list (page cache on Linux) in bytes
+	ModifiedSize  uint64 `json:"modified_bytes"`       // Modified list (dirty pages on Linux) in bytes
+	CommitSize    uint64 `json:"commit_bytes"`         // Committed memory in bytes
+	CommitLimit   uint64 `json:"commit_limit_bytes"`   // Commit limit in bytes
+	PageFileSize  uint64 `json:"page_file_bytes"`      // Total page file (swap on Linux) in bytes
+	PageFileFree  uint64 `json:"page_file_free_bytes"` // Free page file (swap on Linux) in bytes
+	// StandbyByPriority is the standby list per priority, from 0 (lowest) to 7, in bytes.
+	// It is only reported on Windows.
+	StandbyByPriority [StandbyPriorities]uint64 `json:"standby_by_priority_bytes"`
+}
+
+// Standby priority groups. Priority 0 holds pages nobody is expected to need again and is the only
+// priority that can be purged on its own; priorities 5 to 7 hold the data of running applications.
+const (
+	LowStandbyPriority  = 0
+	HighStandbyPriority = 5
+)
+
+// StandbyLow returns the standby list of priority 0, in bytes.
+func (s Snapshot) StandbyLow() uint64 {
+	return s.StandbyByPriority[LowStandbyPriority]
+}
+
+// StandbyNormal returns the standby list of priorities 1 to 4, in bytes.
+func (s Snapshot) StandbyNormal() uint64 {
+	return sum(s.StandbyByPriority[LowStandbyPriority+1 : HighStandbyPriority])
+}
+
+// StandbyHigh returns the standby list of priorities 5 to 7, in bytes.
+func (s Snapshot) StandbyHigh() uint64 {
+	return sum(s.StandbyByPriority[HighStandbyPriority:])
+}
+
+// HasStandbyPriorities reports whether the platform reported the standby list per priority.
+func (s Snapshot) HasStandbyPriorities() bool {
+	return sum(s.StandbyByPriority[:]) > 0
+}
+
+func sum(values []uint64) uint64 {
+	var total uint64
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+// AvailablePercent returns the available memory as a percentage of the total memory.
+func (s Snapshot) AvailablePercent() float64 {
+	if s.TotalSize == 0 {
+		return 0
+	}
+	return float64(s.AvailableSize) * 100 / float64(s.TotalSize)
+}
+
+// UsedPercent returns the memory load in percent.
+func (s Snapshot) UsedPercent() float64 {
+	if s.TotalSize == 0 {
+		return 0
+	}
+	return 100 - s.AvailablePercent()
+}
+
+// Provider returns memory snapshots.
+type Provider interface {
+	Snapshot() (Snapshot, error)
+}
+
+// ProviderFunc adapts a plain function to the Provider interface.
+type ProviderFunc func() (Snapshot, error)
+
+func (f ProviderFunc) Snapshot() (Snapshot, error) { return f() }
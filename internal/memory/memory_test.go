@@ -0,0 +1,20 @@
+package memory
+
+import "testing"
+
+func TestStandbyPriorityGroups(t *testing.T) {
+	s := Snapshot{StandbyByPriority: [StandbyPriorities]uint64{1, 2, 3, 4, 5, 6, 7, 8}}
+
+	if result := s.StandbyLow(); result != 1 {
+		t.Errorf("StandbyLow() = %d, want 1", result)
+	}
+	if result := s.StandbyNormal(); result != 2+3+4+5 {
+		t.Errorf("StandbyNormal() = %d, want 14", result)
+	}
+	if result := s.StandbyHigh(); result != 6+7+8 {
+		t.Errorf("StandbyHigh() = %d, want 21", result)
+	}
+	if !s.HasStandbyPriorities() || (Snapshot{StandbySize: 100}).HasStandbyPriorities() {
+		t.Errorf("HasStandbyPriorities() does not tell whether priorities were reported")
+	}
+}
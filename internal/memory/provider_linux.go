@@ -0,0 +1,31 @@
+//go:build linux
+
+// Description: This file contains the Linux Provider backed by /proc/meminfo.
+
+package memory
+
+import (
+	"fmt"
+	"os"
+)
+
+const meminfoPath = "/proc/meminfo"
+
+type meminfoProvider struct {
+	path string
+}
+
+// NewProvider returns the Provider for the current platform.
+func NewProvider() Provider {
+	return meminfoProvider{path: meminfoPath}
+}
+
+func (p meminfoProvider) Snapshot() (Snapshot, error) {
+	f, err := os.Open(p.path)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("failed to open %s: %v", p.path, err)
+	}
+	defer f.Close()
+
+	return parseMeminfo(f)
+}
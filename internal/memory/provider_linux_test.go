@@ -0,0 +1,15 @@
+//go:build linux
+
+package memory
+
+import "testing"
+
+func TestLinuxProvider(t *testing.T) {
+	s, err := NewProvider().Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot() error: %v", err)
+	}
+	if s.TotalSize == 0 || s.AvailableSize > s.TotalSize {
+		t.Errorf("unexpected snapshot: %+v", s)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !windows && !linux
+
+// Description: This file contains the fallback Provider for unsupported platforms.
+
+package memory
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// NewProvider returns the Provider for the current platform.
+func NewProvider() Provider {
+	return ProviderFunc(func() (Snapshot, error) {
+		return Snapshot{}, fmt.Errorf("memory statistics are not supported on %s", runtime.GOOS)
+	})
+}
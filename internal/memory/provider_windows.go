@@ -0,0 +1,115 @@
+//go:build windows
+
+// Description: This file contains the Windows Provider backed by GlobalMemoryStatusEx and NtQuerySystemInformation.
+
+package memory
+
+import (
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const systemMemoryListInformationClass = 0x50
+
+var (
+	modKernel32                  = windows.NewLazySystemDLL("kernel32.dll")
+	modNtdll                     = windows.NewLazySystemDLL("ntdll.dll")
+	procGlobalMemoryStatusEx     = modKernel32.NewProc("GlobalMemoryStatusEx")
+	procGetSystemInfo            = modKernel32.NewProc("GetSystemInfo")
+	procNtQuerySystemInformation = modNtdll.NewProc("NtQuerySystemInformation")
+)
+
+// MEMORYSTATUSEX for GlobalMemoryStatusEx
+type MEMORYSTATUSEX struct {
+	DwLength                uint32
+	DwMemoryLoad            uint32
+	UllTotalPhys            uint64
+	UllAvailPhys            uint64
+	UllTotalPageFile        uint64
+	UllAvailPageFile        uint64
+	UllTotalVirtual         uint64
+	UllAvailVirtual         uint64
+	UllAvailExtendedVirtual uint64
+}
+
+// systemInfo is SYSTEM_INFO for GetSystemInfo
+type systemInfo struct {
+	ProcessorArchitecture     uint16
+	Reserved                  uint16
+	PageSize                  uint32
+	MinimumApplicationAddress uintptr
+	MaximumApplicationAddress uintptr
+	ActiveProcessorMask       uintptr
+	NumberOfProcessors        uint32
+	ProcessorType             uint32
+	AllocationGranularity     uint32
+	ProcessorLevel            uint16
+	ProcessorRevision         uint16
+}
+
+// pageSize returns the page size of the system, queried once.
+var pageSize = sync.OnceValue(func() uint64 {
+	var info systemInfo
+	procGetSystemInfo.Call(uintptr(unsafe.Pointer(&info)))
+	if info.PageSize == 0 {
+		return 4096
+	}
+	return uint64(info.PageSize)
+})
+
+type winProvider struct{}
+
+// NewProvider returns the Provider for the current platform.
+func NewProvider() Provider {
+	return winProvider{}
+}
+
+func (winProvider) Snapshot() (Snapshot, error) {
+	var s Snapshot
+
+	// 1. Physical memory and commit
+	var status MEMORYSTATUSEX
+	status.DwLength = uint32(unsafe.Sizeof(status))
+	ret, _, err := procGlobalMemoryStatusEx.Call(uintptr(unsafe.Pointer(&status)))
+	if ret == 0 {
+		return s, fmt.Errorf("GlobalMemoryStatusEx failed: %v", err)
+	}
+	s.TotalSize = status.UllTotalPhys
+	s.AvailableSize = status.UllAvailPhys
+	s.CommitLimit = status.UllTotalPageFile
+	s.CommitSize = status.UllTotalPageFile - status.UllAvailPageFile
+	if status.UllTotalPageFile > status.UllTotalPhys {
+		s.PageFileSize = status.UllTotalPageFile - status.UllTotalPhys
+		if status.UllAvailPageFile > status.UllAvailPhys {
+			s.PageFileFree = min(status.UllAvailPageFile-status.UllAvailPhys, s.PageFileSize)
+		}
+	}
+
+	// 2. Memory lists
+	pointerSize := int(unsafe.Sizeof(uintptr(0)))
+	buffer := make([]byte, MemoryListInfoSize(pointerSize))
+	var returnLength uint32
+	ret, _, _ = procNtQuerySystemInformation.Call(
+		uintptr(systemMemoryListInformationClass),
+		uintptr(unsafe.Pointer(&buffer[0])),
+		uintptr(len(buffer)),
+		uintptr(unsafe.Pointer(&returnLength)),
+	)
+	if ret != 0 {
+		return s, fmt.Errorf("NtQuerySystemInformation failed: NTSTATUS=0x%x", ret)
+	}
+
+	// Older systems may leave the returned length at zero, the decoder checks it otherwise.
+	if returnLength != 0 && int(returnLength) < len(buffer) {
+		buffer = buffer[:returnLength]
+	}
+	info, err := DecodeMemoryListInfo(buffer, pointerSize)
+	if err != nil {
+		return s, fmt.Errorf("invalid memory list information: %v", err)
+	}
+	info.apply(&s, pageSize())
+	return s, nil
+}
@@ -0,0 +1,208 @@
+// Package metrics Description: This package exports the memory state and the cleans in the Prometheus text format.
+// The memory gauges come from the latest sample of the sampler, the clean counters and histograms
+// are updated by the clean service. The format is written by hand to avoid a dependency.
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"windows-ram-cleaner/internal/history"
+	"windows-ram-cleaner/internal/report"
+	"windows-ram-cleaner/internal/sampler"
+	"windows-ram-cleaner/internal/trim"
+)
+
+// Prefix is the prefix of every metric name.
+const Prefix = "windows_ram_cleaner_"
+
+// FreedBuckets are the upper bounds in bytes of the histogram of memory freed per clean.
+var FreedBuckets = []float64{16 << 20, 64 << 20, 256 << 20, 1 << 30, 4 << 30, 16 << 30}
+
+// DurationBuckets are the upper bounds in seconds of the histogram of clean durations.
+var DurationBuckets = []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Source provides the memory state.
+type Source struct {
+	// Latest returns the latest sample, false if there is none.
+	Latest func() (sampler.Sample, bool)
+	// SampleErrors returns the number of failed samples. Nil means none are counted.
+	SampleErrors func() uint64
+}
+
+// cleanKey identifies the counters of a kind of clean.
+type cleanKey struct {
+	mode    report.Mode
+	trigger history.Trigger
+}
+
+// histogram counts observations in cumulative buckets.
+type histogram struct {
+	counts []uint64 // Observations per bucket, not cumulative; the last one is +Inf
+	sum    float64
+	count  uint64
+}
+
+func (h *histogram) observe(bounds []float64, v float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(bounds)+1)
+	}
+	i := sort.SearchFloat64s(bounds, v)
+	h.counts[i]++
+	h.sum += v
+	h.count++
+}
+
+// Collector keeps the clean metrics and writes all metrics. It is safe for concurrent use.
+type Collector struct {
+	src Source
+
+	mu       sync.Mutex
+	cleans   map[cleanKey]uint64
+	failures map[cleanKey]uint64
+	freed    map[report.Mode]*histogram
+	duration map[report.Mode]*histogram
+}
+
+// New creates a Collector reading the memory state from src.
+func New(src Source) *Collector {
+	return &Collector{
+		src:      src,
+		cleans:   make(map[cleanKey]uint64),
+		failures: make(map[cleanKey]uint64),
+		freed:    make(map[report.Mode]*histogram),
+		duration: make(map[report.Mode]*histogram),
+	}
+}
+
+// ObserveClean records a finished clean. A clean failing before it produced a report is not
+// counted. Canceled cleans and processes that could not be trimmed are not failures.
+func (c *Collector) ObserveClean(trigger history.Trigger, r *report.CleanReport, err error) {
+	if r == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := cleanKey{mode: r.Mode, trigger: trigger}
+	c.cleans[key]++
+	if err != nil && !trim.IsPartial(err) && !errors.Is(err, context.Canceled) {
+		c.failures[key]++
+	}
+	if c.freed[r.Mode] == nil {
+		c.freed[r.Mode] = &histogram{}
+		c.duration[r.Mode] = &histogram{}
+	}
+	c.freed[r.Mode].observe(FreedBuckets, float64(r.FreedBytes()))
+	c.duration[r.Mode].observe(DurationBuckets, r.Duration.Seconds())
+}
+
+// Write writes every metric in the Prometheus text exposition format.
+func (c *Collector) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	c.writeMemory(bw)
+	c.writeCleans(bw)
+	return bw.Flush()
+}
+
+// Handler returns the handler serving the metrics at /metrics.
+func (c *Collector) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		c.Write(w)
+	})
+	return mux
+}
+
+func (c *Collector) writeMemory(w io.Writer) {
+	if s, ok := c.src.Latest(); ok {
+		gauges := []struct {
+			name, help string
+			value      uint64
+		}{
+			{"memory_total_bytes", "Total physical memory.", s.Snapshot.TotalSize},
+			{"memory_free_bytes", "Free and zeroed physical memory.", s.Snapshot.FreeSize},
+			{"memory_standby_bytes", "Size of the standby list.", s.Snapshot.StandbySize},
+			{"memory_modified_bytes", "Size of the modified list.", s.Snapshot.ModifiedSize},
+			{"memory_commit_bytes", "Committed memory.", s.Snapshot.CommitSize},
+		}
+		for _, g := range gauges {
+			writeHeader(w, g.name, g.help, "gauge")
+			fmt.Fprintf(w, "%s%s %d\n", Prefix, g.name, g.value)
+		}
+		writeHeader(w, "memory_sample_timestamp_seconds", "Time of the memory sample.", "gauge")
+		fmt.Fprintf(w, "%smemory_sample_timestamp_seconds %s\n", Prefix, formatFloat(float64(s.Time.UnixMilli())/1000))
+	}
+
+	if c.src.SampleErrors != nil {
+		writeHeader(w, "memory_sample_errors_total", "Memory samples that failed.", "counter")
+		fmt.Fprintf(w, "%smemory_sample_errors_total %d\n", Prefix, c.src.SampleErrors())
+	}
+}
+
+func (c *Collector) writeCleans(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := make([]cleanKey, 0, len(c.cleans))
+	for key := range c.cleans {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].mode != keys[j].mode {
+			return keys[i].mode < keys[j].mode
+		}
+		return keys[i].trigger < keys[j].trigger
+	})
+
+	writeHeader(w, "cleans_total", "Cleans by mode and trigger.", "counter")
+	for _, key := range keys {
+		fmt.Fprintf(w, "%scleans_total{mode=%q,trigger=%q} %d\n", Prefix, key.mode, key.trigger, c.cleans[key])
+	}
+	writeHeader(w, "clean_errors_total", "Failed cleans by mode and trigger.", "counter")
+	for _, key := range keys {
+		fmt.Fprintf(w, "%sclean_errors_total{mode=%q,trigger=%q} %d\n", Prefix, key.mode, key.trigger, c.failures[key])
+	}
+
+	modes := make([]report.Mode, 0, len(c.freed))
+	for mode := range c.freed {
+		modes = append(modes, mode)
+	}
+	sort.Slice(modes, func(i, j int) bool { return modes[i] < modes[j] })
+
+	writeHeader(w, "clean_freed_bytes", "Memory freed per clean by mode.", "histogram")
+	for _, mode := range modes {
+		writeHistogram(w, "clean_freed_bytes", mode, FreedBuckets, c.freed[mode])
+	}
+	writeHeader(w, "clean_duration_seconds", "Duration of the cleans by mode.", "histogram")
+	for _, mode := range modes {
+		writeHistogram(w, "clean_duration_seconds", mode, DurationBuckets, c.duration[mode])
+	}
+}
+
+func writeHeader(w io.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s%s %s\n# TYPE %s%s %s\n", Prefix, name, help, Prefix, name, kind)
+}
+
+func writeHistogram(w io.Writer, name string, mode report.Mode, bounds []float64, h *histogram) {
+	var cumulative uint64
+	for i, bound := range bounds {
+		cumulative += h.counts[i]
+		fmt.Fprintf(w, "%s%s_bucket{mode=%q,le=%q} %d\n", Prefix, name, mode, formatFloat(bound), cumulative)
+	}
+	fmt.Fprintf(w, "%s%s_bucket{mode=%q,le=\"+Inf\"} %d\n", Prefix, name, mode, h.count)
+	fmt.Fprintf(w, "%s%s_sum{mode=%q} %s\n", Prefix, name, mode, formatFloat(h.sum))
+	fmt.Fprintf(w, "%s%s_count{mode=%q} %d\n", Prefix, name, mode, h.count)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"windows-ram-cleaner/internal/history"
+	"windows-ram-cleaner/internal/memory"
+	"windows-ram-cleaner/internal/report"
+	"windows-ram-cleaner/internal/sampler"
+)
+
+var now = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func testReport(mode report.Mode, freed uint64, duration time.Duration) *report.CleanReport {
+	r := report.New(mode, now, memory.Snapshot{})
+	r.AddStage("stage", freed, duration, nil)
+	r.Finish(now.Add(duration), memory.Snapshot{}, nil)
+	return r
+}
+
+func TestWrite(t *testing.T) {
+	ring := sampler.NewRing(2)
+	ring.Add(sampler.Sample{Time: now, Snapshot: memory.Snapshot{TotalSize: 100, FreeSize: 10, StandbySize: 20, ModifiedSize: 3, CommitSize: 40}})
+	c := New(Source{Latest: ring.Latest, SampleErrors: func() uint64 { return 2 }})
+
+	c.ObserveClean(history.TriggerAuto, testReport(report.ModeStandby, 100<<20, 200*time.Millisecond), nil)
+	c.ObserveClean(history.TriggerAuto, testReport(report.ModeStandby, 2<<30, 3*time.Second), nil)
+	c.ObserveClean(history.TriggerManual, testReport(report.ModeBasic, 0, time.Second), errors.New("access denied"))
+	c.ObserveClean(history.TriggerManual, testReport(report.ModeBasic, 0, 0), context.Canceled)
+	c.ObserveClean(history.TriggerManual, nil, errors.New("busy"))
+
+	var b strings.Builder
+	if err := c.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+
+	for _, line := range []string{
+		"# TYPE windows_ram_cleaner_memory_free_bytes gauge",
+		"windows_ram_cleaner_memory_free_bytes 10",
+		"windows_ram_cleaner_memory_standby_bytes 20",
+		"windows_ram_cleaner_memory_modified_bytes 3",
+		"windows_ram_cleaner_memory_commit_bytes 40",
+		"windows_ram_cleaner_memory_sample_timestamp_seconds 1.7145648e+09",
+		"windows_ram_cleaner_memory_sample_errors_total 2",
+		`windows_ram_cleaner_cleans_total{mode="standby",trigger="auto"} 2`,
+		`windows_ram_cleaner_cleans_total{mode="basic",trigger="manual"} 2`,
+		`windows_ram_cleaner_clean_errors_total{mode="basic",trigger="manual"} 1`,
+		`windows_ram_cleaner_clean_errors_total{mode="standby",trigger="auto"} 0`,
+		"# TYPE windows_ram_cleaner_clean_freed_bytes histogram",
+		`windows_ram_cleaner_clean_freed_bytes_bucket{mode="standby",le="6.7108864e+07"} 0`,
+		`windows_ram_cleaner_clean_freed_bytes_bucket{mode="standby",le="2.68435456e+08"} 1`,
+		`windows_ram_cleaner_clean_freed_bytes_bucket{mode="standby",le="4.294967296e+09"} 2`,
+		`windows_ram_cleaner_clean_freed_bytes_bucket{mode="standby",le="+Inf"} 2`,
+		`windows_ram_cleaner_clean_freed_bytes_count{mode="standby"} 2`,
+		`windows_ram_cleaner_clean_duration_seconds_bucket{mode="standby",le="0.5"} 1`,
+		`windows_ram_cleaner_clean_duration_seconds_sum{mode="standby"} 3.2`,
+		`windows_ram_cleaner_clean_duration_seconds_bucket{mode="basic",le="0.1"} 1`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("metrics do not contain %q:\n%s", line, out)
+		}
+	}
+}
+
+func TestWriteWithoutSamples(t *testing.T) {
+	c := New(Source{Latest: sampler.NewRing(1).Latest})
+	var b strings.Builder
+	if err := c.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(b.String(), "memory_") {
+		t.Errorf("metrics contain memory gauges without a sample:\n%s", b.String())
+	}
+}
+
+func TestHandler(t *testing.T) {
+	c := New(Source{Latest: sampler.NewRing(1).Latest})
+	tests := []struct {
+		method string
+		target string
+		code   int
+	}{
+		{method: http.MethodGet, target: "/metrics", code: http.StatusOK},
+		{method: http.MethodPost, target: "/metrics", code: http.StatusMethodNotAllowed},
+		{method: http.MethodGet, target: "/", code: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		c.Handler().ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+		if rec.Code != tt.code {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.target, rec.Code, tt.code)
+		}
+	}
+	rec := httptest.NewRecorder()
+	c.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+}
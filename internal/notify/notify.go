@@ -0,0 +1,194 @@
+// Package notify Description: This package decides which notifications reach the user.
+// Notifications have a severity, repeated ones are dropped and the rate is limited, so that a
+// failing background task can't flood the desktop. Showing them is left to a platform Sender.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Severity is how important a notification is.
+type Severity int
+
+const (
+	Info    Severity = iota
+	Warning          // Something did not work as expected, the application keeps working
+	Error            // An operation failed
+	Fatal            // The application can't continue; never dropped and shown as a dialog
+)
+
+// String returns a human readable name of the severity.
+func (s Severity) String() string {
+	switch s {
+	case Info:
+		return "info"
+	case Warning:
+		return "warning"
+	case Error:
+		return "error"
+	case Fatal:
+		return "fatal"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// Level returns the log level of the severity.
+func (s Severity) Level() slog.Level {
+	switch s {
+	case Info:
+		return slog.LevelInfo
+	case Warning:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
+
+// Notification is one message for the user.
+type Notification struct {
+	Severity Severity
+	Title    string
+	Message  string
+	// Key identifies repeated notifications. Empty means the title and the message.
+	Key string
+}
+
+func (n Notification) key() string {
+	if n.Key != "" {
+		return n.Key
+	}
+	return n.Title + "\n" + n.Message
+}
+
+// Sender shows notifications to the user.
+type Sender interface {
+	Send(n Notification) error
+}
+
+// SenderFunc adapts a plain function to the Sender interface.
+type SenderFunc func(n Notification) error
+
+func (f SenderFunc) Send(n Notification) error { return f(n) }
+
+// Options limit the notifications shown.
+type Options struct {
+	// MaxPerMinute is the number of notifications shown per minute. Zero means no limit.
+	MaxPerMinute int
+	// DedupWindow drops a notification with the same key as one shown less than DedupWindow ago.
+	DedupWindow time.Duration
+}
+
+// DefaultOptions returns the limits used when none are configured.
+func DefaultOptions() Options {
+	return Options{MaxPerMinute: 4, DedupWindow: 5 * time.Minute}
+}
+
+// Notifier filters notifications and passes the remaining ones to a Sender.
+// It is safe for concurrent use.
+type Notifier struct {
+	sender Sender
+	now    func() time.Time
+
+	mu         sync.Mutex
+	opts       Options
+	sent       []time.Time          // Times of the notifications shown in the last minute
+	last       map[string]time.Time // Last time every key was shown
+	suppressed int
+}
+
+// New creates a Notifier. A nil now means time.Now.
+func New(sender Sender, opts Options, now func() time.Time) *Notifier {
+	if now == nil {
+		now = time.Now
+	}
+	return &Notifier{sender: sender, now: now, opts: opts, last: make(map[string]time.Time)}
+}
+
+// SetOptions replaces the limits.
+func (n *Notifier) SetOptions(opts Options) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.opts = opts
+}
+
+// Suppressed returns the number of notifications dropped so far.
+func (n *Notifier) Suppressed() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.suppressed
+}
+
+// Notify shows x unless it repeats a recent notification or the rate limit is reached.
+// Fatal notifications are always shown. It reports whether x was shown.
+// Every notification is logged, including the dropped ones.
+func (n *Notifier) Notify(x Notification) bool {
+	attrs := []any{"severity", x.Severity, "message", x.Message}
+	shown := false
+	if x.Severity >= Fatal || n.allow(x.key()) {
+		if err := n.sender.Send(x); err != nil {
+			attrs = append(attrs, "err", err)
+		} else {
+			shown = true
+		}
+	}
+	slog.Log(context.Background(), x.Severity.Level(), x.Title, append(attrs, "shown", shown)...)
+	return shown
+}
+
+// allow records a notification with key unless it has to be dropped.
+func (n *Notifier) allow(key string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	now := n.now()
+
+	for k, t := range n.last {
+		if now.Sub(t) >= n.opts.DedupWindow {
+			delete(n.last, k)
+		}
+	}
+	if _, repeated := n.last[key]; repeated {
+		n.suppressed++
+		return false
+	}
+
+	recent := n.sent[:0]
+	for _, t := range n.sent {
+		if now.Sub(t) < time.Minute {
+			recent = append(recent, t)
+		}
+	}
+	n.sent = recent
+	if n.opts.MaxPerMinute > 0 && len(n.sent) >= n.opts.MaxPerMinute {
+		n.suppressed++
+		return false
+	}
+
+	n.sent = append(n.sent, now)
+	n.last[key] = now
+	return true
+}
+
+// Info shows an informational notification.
+func (n *Notifier) Info(title, message string) bool {
+	return n.Notify(Notification{Severity: Info, Title: title, Message: message})
+}
+
+// Warning shows a warning notification.
+func (n *Notifier) Warning(title, message string) bool {
+	return n.Notify(Notification{Severity: Warning, Title: title, Message: message})
+}
+
+// Error shows an error notification.
+func (n *Notifier) Error(title, message string) bool {
+	return n.Notify(Notification{Severity: Error, Title: title, Message: message})
+}
+
+// Fatal shows a notification that is never dropped.
+func (n *Notifier) Fatal(title, message string) bool {
+	return n.Notify(Notification{Severity: Fatal, Title: title, Message: message})
+}
@@ -0,0 +1,117 @@
+package notify
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+type fakeSender struct {
+	sent []Notification
+	err  error
+}
+
+func (s *fakeSender) Send(n Notification) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.sent = append(s.sent, n)
+	return nil
+}
+
+func TestNotify(t *testing.T) {
+	type step struct {
+		after    time.Duration
+		n        Notification
+		expected bool
+	}
+	low := Notification{Severity: Warning, Title: "Low memory", Message: "91%", Key: "low-memory"}
+	tests := []struct {
+		name  string
+		opts  Options
+		steps []step
+	}{
+		{
+			name: "repeated notification is dropped",
+			opts: Options{DedupWindow: time.Minute},
+			steps: []step{
+				{n: low, expected: true},
+				{after: 30 * time.Second, n: Notification{Severity: Warning, Title: "Low memory", Message: "95%", Key: "low-memory"}, expected: false},
+				{after: 30 * time.Second, n: low, expected: true},
+			},
+		},
+		{
+			name: "key defaults to title and message",
+			opts: Options{DedupWindow: time.Minute},
+			steps: []step{
+				{n: Notification{Title: "Clean", Message: "freed 1 GB"}, expected: true},
+				{n: Notification{Title: "Clean", Message: "freed 2 GB"}, expected: true},
+				{n: Notification{Title: "Clean", Message: "freed 1 GB"}, expected: false},
+			},
+		},
+		{
+			name: "rate limit",
+			opts: Options{MaxPerMinute: 2},
+			steps: []step{
+				{n: Notification{Message: "a"}, expected: true},
+				{n: Notification{Message: "b"}, expected: true},
+				{after: 30 * time.Second, n: Notification{Message: "c"}, expected: false},
+				{after: 30 * time.Second, n: Notification{Message: "d"}, expected: true},
+			},
+		},
+		{
+			name: "fatal bypasses the limits",
+			opts: Options{MaxPerMinute: 1, DedupWindow: time.Hour},
+			steps: []step{
+				{n: Notification{Severity: Fatal, Message: "a"}, expected: true},
+				{n: Notification{Message: "b"}, expected: true},
+				{n: Notification{Severity: Fatal, Message: "a"}, expected: true},
+				{n: Notification{Severity: Error, Message: "c"}, expected: false},
+			},
+		},
+		{
+			name: "no limits",
+			opts: Options{},
+			steps: []step{
+				{n: low, expected: true},
+				{n: low, expected: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &fakeClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+			sender := &fakeSender{}
+			n := New(sender, tt.opts, clock.Now)
+			dropped := 0
+			for i, s := range tt.steps {
+				clock.now = clock.now.Add(s.after)
+				if got := n.Notify(s.n); got != s.expected {
+					t.Errorf("step %d: Notify() = %v, want %v", i, got, s.expected)
+				}
+				if !s.expected {
+					dropped++
+				}
+			}
+			if got := n.Suppressed(); got != dropped {
+				t.Errorf("Suppressed() = %d, want %d", got, dropped)
+			}
+			if got := len(sender.sent); got != len(tt.steps)-dropped {
+				t.Errorf("sent %d notifications, want %d", got, len(tt.steps)-dropped)
+			}
+		})
+	}
+}
+
+func TestNotifySenderError(t *testing.T) {
+	sender := &fakeSender{err: errors.New("no tray icon")}
+	n := New(sender, DefaultOptions(), nil)
+	if n.Error("Clean", "failed") {
+		t.Error("Notify() = true, want false when the sender fails")
+	}
+}
@@ -0,0 +1,38 @@
+// Description: This file contains case-insensitive glob matching for names and paths.
+
+package procrules
+
+import (
+	"regexp"
+	"strings"
+)
+
+// compileGlob turns a glob with * and ? into an anchored, case-insensitive regular expression.
+// Unlike path.Match, * also matches path separators, so "C:\Games\*" covers sub-directories.
+// An empty pattern returns nil.
+func compileGlob(pattern string) *regexp.Regexp {
+	if pattern == "" {
+		return nil
+	}
+
+	var b strings.Builder
+	b.WriteString(`(?i)^`)
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString(`.*`)
+		case '?':
+			b.WriteString(`.`)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString(`$`)
+
+	return regexp.MustCompile(b.String())
+}
+
+// normalizePath makes Windows paths comparable regardless of the separator used.
+func normalizePath(p string) string {
+	return strings.ReplaceAll(p, `\`, `/`)
+}
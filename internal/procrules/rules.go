@@ -0,0 +1,252 @@
+// Package procrules Description: This package contains the rule engine deciding which processes may be trimmed.
+// Rules match processes by name, path, regular expression, PID or parent process and either allow
+// trimming or deny it. The first matching user rule wins; the built-in critical processes are
+// checked after the user rules and can be bypassed by a deep clean.
+package procrules
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Action is what a rule does with a matching process.
+type Action string
+
+const (
+	Allow Action = "allow" // The process may be trimmed
+	Deny  Action = "deny"  // The process must not be trimmed
+)
+
+// Rule matches processes. All non-empty conditions must match.
+type Rule struct {
+	Action Action `json:"action"`
+	// Name is a case-insensitive glob (* and ?) matched against the executable name, e.g. "chrome*.exe".
+	Name string `json:"name,omitempty"`
+	// Path is a case-insensitive glob matched against the full executable path, e.g. "C:\Games\*".
+	Path string `json:"path,omitempty"`
+	// Regex is a case-insensitive regular expression matched against the executable name and the full path.
+	Regex string `json:"regex,omitempty"`
+	// PID matches a single process.
+	PID uint32 `json:"pid,omitempty"`
+	// ParentPID matches the children of a process.
+	ParentPID uint32 `json:"parent_pid,omitempty"`
+	// ParentName is a case-insensitive glob matched against the executable name of the parent process.
+	ParentName string `json:"parent_name,omitempty"`
+}
+
+// String returns a short description of the rule for reports and logs.
+func (r Rule) String() string {
+	var conds []string
+	add := func(key, value string) {
+		if value != "" {
+			conds = append(conds, key+"="+value)
+		}
+	}
+	add("name", r.Name)
+	add("path", r.Path)
+	add("regex", r.Regex)
+	if r.PID != 0 {
+		add("pid", fmt.Sprint(r.PID))
+	}
+	if r.ParentPID != 0 {
+		add("parent_pid", fmt.Sprint(r.ParentPID))
+	}
+	add("parent_name", r.ParentName)
+	return fmt.Sprintf("%s %s", r.Action, strings.Join(conds, " "))
+}
+
+// Process is the information the rules are evaluated against.
+// Path and ParentName may be empty when they are unknown.
+type Process struct {
+	PID        uint32
+	ParentPID  uint32
+	Name       string
+	Path       string
+	ParentName string
+}
+
+// Decision is the result of evaluating a process.
+type Decision struct {
+	Allowed  bool
+	Critical bool  // The process matched the built-in critical list
+	Rule     *Rule // The matching rule, nil when no rule matched
+}
+
+// Reason describes why the decision was taken.
+func (d Decision) Reason() string {
+	switch {
+	case d.Rule == nil:
+		return "no rule matched"
+	case d.Critical:
+		return "critical process"
+	default:
+		return "rule: " + d.Rule.String()
+	}
+}
+
+// Engine evaluates processes against compiled rules. It is safe for concurrent use.
+type Engine struct {
+	rules    []compiledRule
+	critical []compiledRule
+}
+
+// New compiles the user rules and the names of the critical processes.
+func New(rules []Rule, critical []string) (*Engine, error) {
+	e := &Engine{}
+
+	var errs []error
+	for i, rule := range rules {
+		compiled, err := compile(rule)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %d: %w", i, err))
+			continue
+		}
+		e.rules = append(e.rules, compiled)
+	}
+	for _, name := range critical {
+		compiled, err := compile(Rule{Action: Deny, Name: name})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("critical process %q: %w", name, err))
+			continue
+		}
+		e.critical = append(e.critical, compiled)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// DefaultCritical returns the processes that are never trimmed by a basic clean.
+func DefaultCritical() []string {
+	return []string{
+		"csrss.exe", "wininit.exe", "services.exe", "lsass.exe", "winlogon.exe", "explorer.exe",
+		"smss.exe", "svchost.exe", "System", "System Idle Process", "conhost.exe", "dwm.exe",
+		"taskhost.exe", "taskhostw.exe", "spoolsv.exe", "msmpeng.exe", "audiodg.exe",
+		"fontdrvhost.exe", "sihost.exe", "dllhost.exe", "logonui.exe", "lsm.exe",
+		"SearchIndexer.exe", "SecurityHealthService.exe", "ShellExperienceHost.exe",
+		"StartMenuExperienceHost.exe", "SystemSettings.exe", "taskeng.exe", "taskhostex.exe",
+		"TrustedInstaller.exe", "userinit.exe", "WmiPrvSE.exe", "WUDFHost.exe",
+	}
+}
+
+// Default returns an engine without user rules that protects the DefaultCritical processes.
+func Default() *Engine {
+	e := &Engine{}
+	for _, name := range DefaultCritical() {
+		e.critical = append(e.critical, compiledRule{Rule: Rule{Action: Deny, Name: name}, name: compileGlob(name)})
+	}
+	return e
+}
+
+// Evaluate decides whether p may be trimmed. With ignoreCritical the built-in critical
+// list is skipped, while the user rules still apply.
+func (e *Engine) Evaluate(p Process, ignoreCritical bool) Decision {
+	for i := range e.rules {
+		if e.rules[i].match(p) {
+			return Decision{Allowed: e.rules[i].Action == Allow, Rule: &e.rules[i].Rule}
+		}
+	}
+
+	if !ignoreCritical {
+		for i := range e.critical {
+			if e.critical[i].match(p) {
+				return Decision{Allowed: false, Critical: true, Rule: &e.critical[i].Rule}
+			}
+		}
+	}
+
+	return Decision{Allowed: true}
+}
+
+// NeedsPath reports whether any rule looks at the executable path, which is expensive to query.
+func (e *Engine) NeedsPath() bool {
+	for _, r := range e.rules {
+		if r.path != nil || r.regex != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Matcher matches processes against the conditions of a rule, ignoring its action.
+// It lets other settings select processes the same way rules do.
+type Matcher struct {
+	rule compiledRule
+}
+
+// NewMatcher compiles the conditions of r. The action of r is not used and may be empty.
+func NewMatcher(r Rule) (*Matcher, error) {
+	r.Action = Allow
+	compiled, err := compile(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Matcher{rule: compiled}, nil
+}
+
+// Match reports whether p matches all conditions.
+func (m *Matcher) Match(p Process) bool {
+	return m.rule.match(p)
+}
+
+// NeedsPath reports whether the conditions look at the executable path.
+func (m *Matcher) NeedsPath() bool {
+	return m.rule.path != nil || m.rule.regex != nil
+}
+
+type compiledRule struct {
+	Rule
+	name       *regexp.Regexp
+	path       *regexp.Regexp
+	regex      *regexp.Regexp
+	parentName *regexp.Regexp
+}
+
+func compile(r Rule) (compiledRule, error) {
+	c := compiledRule{Rule: r}
+
+	if r.Action != Allow && r.Action != Deny {
+		return c, fmt.Errorf("action must be %q or %q, got %q", Allow, Deny, r.Action)
+	}
+	if r.Name == "" && r.Path == "" && r.Regex == "" && r.PID == 0 && r.ParentPID == 0 && r.ParentName == "" {
+		return c, errors.New("at least one condition is required")
+	}
+
+	var err error
+	if r.Regex != "" {
+		// Case-insensitive like the globs, Windows file names are
+		if c.regex, err = regexp.Compile("(?i)" + r.Regex); err != nil {
+			return c, fmt.Errorf("invalid regex: %v", err)
+		}
+	}
+	c.name = compileGlob(r.Name)
+	c.path = compileGlob(normalizePath(r.Path))
+	c.parentName = compileGlob(r.ParentName)
+	return c, nil
+}
+
+func (c compiledRule) match(p Process) bool {
+	if c.PID != 0 && c.PID != p.PID {
+		return false
+	}
+	if c.ParentPID != 0 && c.ParentPID != p.ParentPID {
+		return false
+	}
+	if c.name != nil && !c.name.MatchString(p.Name) {
+		return false
+	}
+	if c.parentName != nil && !c.parentName.MatchString(p.ParentName) {
+		return false
+	}
+	if c.path != nil && (p.Path == "" || !c.path.MatchString(normalizePath(p.Path))) {
+		return false
+	}
+	if c.regex != nil && !c.regex.MatchString(p.Name) && (p.Path == "" || !c.regex.MatchString(p.Path)) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,191 @@
+package procrules
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	rules := []Rule{
+		{Action: Deny, Name: "Game*.exe"},
+		{Action: Deny, Path: `C:\Program Files\Ableton\*`},
+		{Action: Deny, Regex: `^vmware-vmx\.exe$`},
+		{Action: Deny, PID: 4242},
+		{Action: Deny, ParentName: "steam.exe"},
+		{Action: Deny, ParentPID: 777, Name: "worker.exe"},
+		{Action: Allow, Name: "explorer.exe"},
+	}
+	critical := []string{"explorer.exe", "MsMpEng.exe", "lsass.exe"}
+
+	engine, err := New(rules, critical)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		process        Process
+		ignoreCritical bool
+		allowed        bool
+		critical       bool
+	}{
+		{name: "unmatched process", process: Process{Name: "notepad.exe"}, allowed: true},
+		{name: "glob name", process: Process{Name: "GameClient.exe"}, allowed: false},
+		{name: "glob name is case-insensitive", process: Process{Name: "gameserver.EXE"}, allowed: false},
+		{name: "glob does not match partially", process: Process{Name: "MyGame.exe"}, allowed: true},
+		{name: "path glob", process: Process{Name: "Live.exe", Path: `c:\program files\ableton\Live 11\Live.exe`}, allowed: false},
+		{name: "path glob with forward slashes", process: Process{Name: "Live.exe", Path: `C:/Program Files/Ableton/Live.exe`}, allowed: false},
+		{name: "unknown path does not match", process: Process{Name: "Live.exe"}, allowed: true},
+		{name: "regex on name", process: Process{Name: "vmware-vmx.exe"}, allowed: false},
+		{name: "regex is case-insensitive", process: Process{Name: "VMWARE-VMX.EXE"}, allowed: false},
+		{name: "pid", process: Process{PID: 4242, Name: "anything.exe"}, allowed: false},
+		{name: "parent name", process: Process{Name: "csgo.exe", ParentName: "Steam.exe"}, allowed: false},
+		{name: "parent pid and name", process: Process{Name: "worker.exe", ParentPID: 777}, allowed: false},
+		{name: "parent pid with other name", process: Process{Name: "other.exe", ParentPID: 777}, allowed: true},
+		{name: "critical process", process: Process{Name: "lsass.exe"}, allowed: false, critical: true},
+		{name: "critical is case-insensitive", process: Process{Name: "msmpeng.exe"}, allowed: false, critical: true},
+		{name: "deep clean ignores critical", process: Process{Name: "lsass.exe"}, ignoreCritical: true, allowed: true},
+		{name: "deep clean keeps user rules", process: Process{Name: "Game.exe"}, ignoreCritical: true, allowed: false},
+		{name: "user allow overrides critical", process: Process{Name: "Explorer.exe"}, allowed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := engine.Evaluate(tt.process, tt.ignoreCritical)
+			if d.Allowed != tt.allowed || d.Critical != tt.critical {
+				t.Errorf("Evaluate() = allowed %v critical %v (%s), want allowed %v critical %v",
+					d.Allowed, d.Critical, d.Reason(), tt.allowed, tt.critical)
+			}
+		})
+	}
+}
+
+func TestFirstMatchWins(t *testing.T) {
+	engine, err := New([]Rule{
+		{Action: Allow, Name: "chrome.exe", ParentName: "chrome.exe"},
+		{Action: Deny, Name: "chrome.exe"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if d := engine.Evaluate(Process{Name: "chrome.exe", ParentName: "chrome.exe"}, false); !d.Allowed {
+		t.Errorf("child process should match the first rule, got %s", d.Reason())
+	}
+	if d := engine.Evaluate(Process{Name: "chrome.exe", ParentName: "explorer.exe"}, false); d.Allowed {
+		t.Errorf("main process should match the second rule, got %s", d.Reason())
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []Rule
+		wantErr string
+	}{
+		{name: "unknown action", rules: []Rule{{Action: "skip", Name: "a.exe"}}, wantErr: "action"},
+		{name: "no condition", rules: []Rule{{Action: Deny}}, wantErr: "condition"},
+		{name: "invalid regex", rules: []Rule{{Action: Deny, Regex: "("}}, wantErr: "regex"},
+		{name: "reports index", rules: []Rule{{Action: Deny, Name: "a"}, {Action: Deny}}, wantErr: "rule 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.rules, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("New() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNeedsPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    []Rule
+		expected bool
+	}{
+		{name: "names only", rules: []Rule{{Action: Deny, Name: "a.exe"}}, expected: false},
+		{name: "path rule", rules: []Rule{{Action: Deny, Path: `C:\*`}}, expected: true},
+		{name: "regex rule", rules: []Rule{{Action: Deny, Regex: "a"}}, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := New(tt.rules, []string{"lsass.exe"})
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if result := engine.NeedsPath(); result != tt.expected {
+				t.Errorf("NeedsPath() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	m, err := NewMatcher(Rule{Name: "chrome*.exe", Path: `C:\Program Files\*`})
+	if err != nil {
+		t.Fatalf("NewMatcher() error = %v", err)
+	}
+	if !m.NeedsPath() {
+		t.Error("NeedsPath() = false, want true")
+	}
+
+	tests := []struct {
+		process  Process
+		expected bool
+	}{
+		{process: Process{Name: "Chrome.exe", Path: `C:\Program Files\Google\chrome.exe`}, expected: true},
+		{process: Process{Name: "chrome.exe", Path: `D:\chrome.exe`}, expected: false},
+		{process: Process{Name: "chrome.exe"}, expected: false},
+	}
+	for _, tt := range tests {
+		if result := m.Match(tt.process); result != tt.expected {
+			t.Errorf("Match(%+v) = %v, want %v", tt.process, result, tt.expected)
+		}
+	}
+
+	if _, err := NewMatcher(Rule{}); err == nil {
+		t.Error("NewMatcher() without conditions expected an error")
+	}
+}
+
+func TestDefault(t *testing.T) {
+	e := Default()
+	for _, name := range DefaultCritical() {
+		if e.Evaluate(Process{Name: name}, false).Allowed {
+			t.Errorf("Evaluate(%q) allowed, want the critical process protected", name)
+		}
+	}
+	if !e.Evaluate(Process{Name: "LSASS.EXE"}, true).Allowed {
+		t.Errorf("Evaluate() with ignoreCritical protected a critical process")
+	}
+	if !e.Evaluate(Process{Name: "notepad.exe"}, false).Allowed {
+		t.Errorf("Evaluate(notepad.exe) protected, want allowed")
+	}
+}
+
+func TestCompileGlob(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		subject  string
+		expected bool
+	}{
+		{pattern: "*.exe", subject: "a.exe", expected: true},
+		{pattern: "a?c", subject: "abc", expected: true},
+		{pattern: "a?c", subject: "ac", expected: false},
+		{pattern: "a.c", subject: "abc", expected: false},
+		{pattern: "C:/Games/*", subject: "c:/games/x/y.exe", expected: true},
+		{pattern: "(x)+", subject: "(x)+", expected: true},
+	}
+
+	for _, tt := range tests {
+		if result := compileGlob(tt.pattern).MatchString(tt.subject); result != tt.expected {
+			t.Errorf("compileGlob(%q).MatchString(%q) = %v, want %v", tt.pattern, tt.subject, result, tt.expected)
+		}
+	}
+	if compileGlob("") != nil {
+		t.Errorf("compileGlob(\"\") should be nil")
+	}
+}
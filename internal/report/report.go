@@ -0,0 +1,194 @@
+// Package report Description: This package contains the report produced by every clean.
+// A report holds the memory snapshots before and after the clean, the memory released by
+// each stage and the outcome for every process.
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"windows-ram-cleaner/internal/memory"
+)
+
+// Mode is the kind of clean.
+type Mode string
+
+const (
+	ModeStandby Mode = "standby" // Purge of the standby list
+	ModeBasic   Mode = "basic"   // Working set trim skipping critical processes
+	ModeDeep    Mode = "deep"    // Working set trim including critical processes
+	// ModeMemoryList is a memory list command; its only stage is named after the command.
+	ModeMemoryList Mode = "memory-list"
+	// ModeTargeted is a working set trim of the processes selected by the user.
+	ModeTargeted Mode = "targeted"
+)
+
+// summaryProcesses is how many processes of a targeted trim Summary lists.
+const summaryProcesses = 3
+
+// Stage names used in reports.
+const (
+	StageProcesses = "processes" // Working sets of other processes
+	StageSelf      = "self"      // Working set of this process
+	StageSystemWS  = "system-ws" // System working set
+	StageStandby   = "standby"   // Standby list
+)
+
+// ProcessStatus is the outcome of trimming one process.
+type ProcessStatus string
+
+const (
+	ProcessTrimmed ProcessStatus = "trimmed"
+	ProcessSkipped ProcessStatus = "skipped"
+	ProcessFailed  ProcessStatus = "failed"
+)
+
+// ProcessResult is the outcome of trimming one process.
+type ProcessResult struct {
+	PID    uint32        `json:"pid"`
+	Name   string        `json:"name"`
+	Status ProcessStatus `json:"status"`
+	Reason string        `json:"reason,omitempty"`
+	// FreedBytes is the working set reduction of a trimmed process.
+	FreedBytes uint64 `json:"freed_bytes,omitempty"`
+	// WorkingSetBefore and WorkingSetAfter are recorded by targeted trims only.
+	WorkingSetBefore uint64 `json:"working_set_before_bytes,omitempty"`
+	WorkingSetAfter  uint64 `json:"working_set_after_bytes,omitempty"`
+}
+
+// StageResult is the outcome of one stage of a clean.
+type StageResult struct {
+	Name string `json:"name"`
+	// FreedBytes is the working set reduction for trimming stages and the standby list
+	// reduction for the standby stage.
+	FreedBytes uint64        `json:"freed_bytes"`
+	Duration   time.Duration `json:"duration_ns"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// CleanReport describes one clean.
+type CleanReport struct {
+	Mode      Mode            `json:"mode"`
+	Target    string          `json:"target,omitempty"` // The processes selected by a targeted trim
+	Started   time.Time       `json:"started"`
+	Duration  time.Duration   `json:"duration_ns"`
+	Before    memory.Snapshot `json:"before"`
+	After     memory.Snapshot `json:"after"`
+	Stages    []StageResult   `json:"stages"`
+	Processes []ProcessResult `json:"processes,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// New starts a report for a clean in the given mode.
+func New(mode Mode, started time.Time, before memory.Snapshot) *CleanReport {
+	return &CleanReport{Mode: mode, Started: started, Before: before}
+}
+
+// AddStage records a finished stage.
+func (r *CleanReport) AddStage(name string, freed uint64, duration time.Duration, err error) {
+	stage := StageResult{Name: name, FreedBytes: freed, Duration: duration}
+	if err != nil {
+		stage.Error = err.Error()
+	}
+	r.Stages = append(r.Stages, stage)
+}
+
+// AddProcess records the outcome for one process.
+func (r *CleanReport) AddProcess(result ProcessResult) {
+	r.Processes = append(r.Processes, result)
+}
+
+// Finish records the memory state after the clean, the duration and the overall error.
+func (r *CleanReport) Finish(finished time.Time, after memory.Snapshot, err error) {
+	r.After = after
+	r.Duration = finished.Sub(r.Started)
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+// FreedBytes returns the total memory released by all stages.
+func (r *CleanReport) FreedBytes() uint64 {
+	var total uint64
+	for _, s := range r.Stages {
+		total += s.FreedBytes
+	}
+	return total
+}
+
+// AvailableDelta returns how much the available memory changed, negative if it shrank.
+func (r *CleanReport) AvailableDelta() int64 {
+	return int64(r.After.AvailableSize) - int64(r.Before.AvailableSize)
+}
+
+// Counts returns how many processes were trimmed, skipped and failed.
+func (r *CleanReport) Counts() (trimmed, skipped, failed int) {
+	for _, p := range r.Processes {
+		switch p.Status {
+		case ProcessTrimmed:
+			trimmed++
+		case ProcessSkipped:
+			skipped++
+		case ProcessFailed:
+			failed++
+		}
+	}
+	return trimmed, skipped, failed
+}
+
+// Summary returns a short human readable description, suitable for a notification.
+func (r *CleanReport) Summary() string {
+	text := fmt.Sprintf("Freed %s in %s", FormatBytes(r.FreedBytes()), r.Duration.Round(time.Millisecond))
+	if r.Mode == ModeBasic || r.Mode == ModeDeep || r.Mode == ModeTargeted {
+		trimmed, skipped, failed := r.Counts()
+		text += fmt.Sprintf("\nProcesses: %d trimmed, %d skipped, %d failed", trimmed, skipped, failed)
+	}
+	if r.Mode == ModeTargeted {
+		text += r.workingSets()
+	}
+	if r.After.HasStandbyPriorities() {
+		text += fmt.Sprintf("\nStandby low/normal/high: %s / %s / %s",
+			FormatBytes(r.After.StandbyLow()), FormatBytes(r.After.StandbyNormal()), FormatBytes(r.After.StandbyHigh()))
+	}
+	if r.Error != "" {
+		text += "\nError: " + r.Error
+	}
+	return text
+}
+
+// workingSets returns a line with the working set before and after for the first trimmed processes.
+func (r *CleanReport) workingSets() string {
+	var text string
+	listed := 0
+	for _, p := range r.Processes {
+		if p.Status != ProcessTrimmed {
+			continue
+		}
+		if listed == summaryProcesses {
+			return text + "\n..."
+		}
+		text += fmt.Sprintf("\n%s (%d): %s -> %s", p.Name, p.PID, FormatBytes(p.WorkingSetBefore), FormatBytes(p.WorkingSetAfter))
+		listed++
+	}
+	return text
+}
+
+// JSON returns the report as indented JSON.
+func (r *CleanReport) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// FormatBytes formats a size with a binary unit, e.g. "1.5 GB".
+func FormatBytes(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit && exp < 3; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(b)/float64(div), "KMGT"[exp])
+}
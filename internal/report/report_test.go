@@ -0,0 +1,119 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"windows-ram-cleaner/internal/memory"
+)
+
+func testReport() *CleanReport {
+	started := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	r := New(ModeBasic, started, memory.Snapshot{AvailableSize: 1000})
+	r.AddProcess(ProcessResult{PID: 1, Name: "a.exe", Status: ProcessTrimmed, FreedBytes: 300})
+	r.AddProcess(ProcessResult{PID: 2, Name: "b.exe", Status: ProcessTrimmed, FreedBytes: 200})
+	r.AddProcess(ProcessResult{PID: 3, Name: "lsass.exe", Status: ProcessSkipped, Reason: "critical process"})
+	r.AddProcess(ProcessResult{PID: 4, Name: "c.exe", Status: ProcessFailed, Reason: "access denied"})
+	r.AddStage(StageProcesses, 500, time.Second, nil)
+	r.AddStage(StageSelf, 24, time.Millisecond, nil)
+	r.Finish(started.Add(1500*time.Millisecond), memory.Snapshot{AvailableSize: 900}, nil)
+	return r
+}
+
+func TestCleanReport(t *testing.T) {
+	r := testReport()
+
+	if r.FreedBytes() != 524 {
+		t.Errorf("FreedBytes() = %d, want 524", r.FreedBytes())
+	}
+	if r.AvailableDelta() != -100 {
+		t.Errorf("AvailableDelta() = %d, want -100", r.AvailableDelta())
+	}
+	if trimmed, skipped, failed := r.Counts(); trimmed != 2 || skipped != 1 || failed != 1 {
+		t.Errorf("Counts() = %d, %d, %d, want 2, 1, 1", trimmed, skipped, failed)
+	}
+	if r.Duration != 1500*time.Millisecond {
+		t.Errorf("Duration = %s, want 1.5s", r.Duration)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	r := testReport()
+	expected := "Freed 524 B in 1.5s\nProcesses: 2 trimmed, 1 skipped, 1 failed"
+	if s := r.Summary(); s != expected {
+		t.Errorf("Summary() = %q, want %q", s, expected)
+	}
+
+	standby := New(ModeStandby, time.Time{}, memory.Snapshot{})
+	standby.AddStage(StageStandby, 3*1024*1024*1024, 0, nil)
+	standby.Finish(time.Time{}, memory.Snapshot{}, errors.New("partial"))
+	if s := standby.Summary(); s != "Freed 3.0 GB in 0s\nError: partial" {
+		t.Errorf("Summary() = %q", s)
+	}
+
+	after := memory.Snapshot{StandbyByPriority: [memory.StandbyPriorities]uint64{0, 1024, 0, 0, 0, 2048, 0, 0}}
+	lists := New(ModeMemoryList, time.Time{}, memory.Snapshot{})
+	lists.AddStage("purge-low-priority-standby", 1024, 0, nil)
+	lists.Finish(time.Time{}, after, nil)
+	expected = "Freed 1.0 KB in 0s\nStandby low/normal/high: 0 B / 1.0 KB / 2.0 KB"
+	if s := lists.Summary(); s != expected {
+		t.Errorf("Summary() = %q, want %q", s, expected)
+	}
+
+	targeted := New(ModeTargeted, time.Time{}, memory.Snapshot{})
+	targeted.AddStage(StageProcesses, 3072, 0, nil)
+	for pid := uint32(1); pid <= 4; pid++ {
+		targeted.AddProcess(ProcessResult{PID: pid, Name: "chrome.exe", Status: ProcessTrimmed, FreedBytes: 768, WorkingSetBefore: 1024, WorkingSetAfter: 256})
+	}
+	targeted.AddProcess(ProcessResult{PID: 5, Name: "csrss.exe", Status: ProcessFailed, Reason: "access denied"})
+	targeted.Finish(time.Time{}, memory.Snapshot{}, nil)
+	expected = "Freed 3.0 KB in 0s\nProcesses: 4 trimmed, 0 skipped, 1 failed" +
+		"\nchrome.exe (1): 1.0 KB -> 256 B\nchrome.exe (2): 1.0 KB -> 256 B\nchrome.exe (3): 1.0 KB -> 256 B\n..."
+	if s := targeted.Summary(); s != expected {
+		t.Errorf("Summary() = %q, want %q", s, expected)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	data, err := testReport().JSON()
+	if err != nil {
+		t.Fatalf("JSON() error = %v", err)
+	}
+
+	for _, key := range []string{`"mode": "basic"`, `"duration_ns": 1500000000`, `"status": "skipped"`, `"reason": "critical process"`, `"available_bytes": 900`} {
+		if !strings.Contains(string(data), key) {
+			t.Errorf("JSON() does not contain %s", key)
+		}
+	}
+
+	var decoded CleanReport
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("report JSON can't be decoded: %v", err)
+	}
+	if decoded.FreedBytes() != 524 || len(decoded.Processes) != 4 {
+		t.Errorf("decoded report = %+v", decoded)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		size     uint64
+		expected string
+	}{
+		{size: 0, expected: "0 B"},
+		{size: 1023, expected: "1023 B"},
+		{size: 1536, expected: "1.5 KB"},
+		{size: 200 * 1024 * 1024, expected: "200.0 MB"},
+		{size: 5 * 1024 * 1024 * 1024 * 1024, expected: "5.0 TB"},
+		{size: 2048 * 1024 * 1024 * 1024 * 1024, expected: "2048.0 TB"},
+	}
+
+	for _, tt := range tests {
+		if result := FormatBytes(tt.size); result != tt.expected {
+			t.Errorf("FormatBytes(%d) = %q, want %q", tt.size, result, tt.expected)
+		}
+	}
+}
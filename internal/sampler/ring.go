@@ -0,0 +1,85 @@
+// Package sampler Description: This package keeps the recent memory samples in a fixed-size ring buffer.
+// The samples feed the tooltip and the memory graph, so the memory state is queried once per refresh.
+package sampler
+
+import (
+	"sync"
+	"time"
+
+	"windows-ram-cleaner/internal/memory"
+)
+
+// Sample is the memory state at one point in time.
+type Sample struct {
+	Time     time.Time       `json:"time"`
+	Snapshot memory.Snapshot `json:"snapshot"`
+}
+
+// Ring holds the latest samples, dropping the oldest one when it is full.
+// It is safe for concurrent use.
+type Ring struct {
+	mu      sync.Mutex
+	samples []Sample
+	next    int // Index the next sample is written to
+	full    bool
+}
+
+// NewRing creates a ring holding up to capacity samples. A capacity below one is treated as one.
+func NewRing(capacity int) *Ring {
+	return &Ring{samples: make([]Sample, max(capacity, 1))}
+}
+
+// Add stores s, replacing the oldest sample when the ring is full.
+func (r *Ring) Add(s Sample) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.samples[r.next] = s
+	r.next = (r.next + 1) % len(r.samples)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Len returns the number of stored samples.
+func (r *Ring) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.len()
+}
+
+func (r *Ring) len() int {
+	if r.full {
+		return len(r.samples)
+	}
+	return r.next
+}
+
+// Latest returns the newest sample, false if the ring is empty.
+func (r *Ring) Latest() (Sample, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.len() == 0 {
+		return Sample{}, false
+	}
+	return r.samples[(r.next+len(r.samples)-1)%len(r.samples)], true
+}
+
+// Since returns a copy of the samples taken at or after t, oldest first.
+func (r *Ring) Since(t time.Time) []Sample {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := r.len()
+	start := 0
+	if r.full {
+		start = r.next
+	}
+	result := make([]Sample, 0, n)
+	for i := 0; i < n; i++ {
+		s := r.samples[(start+i)%len(r.samples)]
+		if !s.Time.Before(t) {
+			result = append(result, s)
+		}
+	}
+	return result
+}
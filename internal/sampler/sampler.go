@@ -0,0 +1,43 @@
+// Description: This file contains the sampler taking memory snapshots into a ring buffer.
+
+package sampler
+
+import (
+	"sync/atomic"
+	"time"
+
+	"windows-ram-cleaner/internal/memory"
+)
+
+// Sampler takes memory snapshots from a provider and keeps them in a Ring.
+// It is safe for concurrent use.
+type Sampler struct {
+	*Ring
+	provider memory.Provider
+	now      func() time.Time
+	errors   atomic.Uint64
+}
+
+// New creates a Sampler keeping capacity samples. A nil now means time.Now.
+func New(provider memory.Provider, capacity int, now func() time.Time) *Sampler {
+	if now == nil {
+		now = time.Now
+	}
+	return &Sampler{Ring: NewRing(capacity), provider: provider, now: now}
+}
+
+// Sample takes a snapshot and stores it. Failed snapshots are not stored.
+func (s *Sampler) Sample() (memory.Snapshot, error) {
+	snapshot, err := s.provider.Snapshot()
+	if err != nil {
+		s.errors.Add(1)
+		return snapshot, err
+	}
+	s.Add(Sample{Time: s.now(), Snapshot: snapshot})
+	return snapshot, nil
+}
+
+// Errors returns the number of snapshots that failed.
+func (s *Sampler) Errors() uint64 {
+	return s.errors.Load()
+}
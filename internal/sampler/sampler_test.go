@@ -0,0 +1,95 @@
+package sampler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"windows-ram-cleaner/internal/memory"
+)
+
+var baseTime = time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+func sampleAt(seconds int) Sample {
+	return Sample{Time: baseTime.Add(time.Duration(seconds) * time.Second), Snapshot: memory.Snapshot{FreeSize: uint64(seconds)}}
+}
+
+func frees(samples []Sample) []uint64 {
+	result := make([]uint64, len(samples))
+	for i, s := range samples {
+		result[i] = s.Snapshot.FreeSize
+	}
+	return result
+}
+
+func TestRing(t *testing.T) {
+	tests := []struct {
+		name     string
+		added    int
+		since    int
+		expected []uint64
+	}{
+		{name: "empty", added: 0, since: 0, expected: []uint64{}},
+		{name: "partly filled", added: 2, since: 0, expected: []uint64{0, 1}},
+		{name: "exactly full", added: 3, since: 0, expected: []uint64{0, 1, 2}},
+		{name: "wrapped", added: 5, since: 0, expected: []uint64{2, 3, 4}},
+		{name: "wrapped since", added: 5, since: 3, expected: []uint64{3, 4}},
+		{name: "nothing recent", added: 5, since: 10, expected: []uint64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRing(3)
+			for i := 0; i < tt.added; i++ {
+				r.Add(sampleAt(i))
+			}
+
+			got := frees(r.Since(baseTime.Add(time.Duration(tt.since) * time.Second)))
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Since() = %v, want %v", got, tt.expected)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Fatalf("Since() = %v, want %v", got, tt.expected)
+				}
+			}
+			if want := min(tt.added, 3); r.Len() != want {
+				t.Errorf("Len() = %d, want %d", r.Len(), want)
+			}
+
+			latest, ok := r.Latest()
+			if ok != (tt.added > 0) || (ok && latest.Snapshot.FreeSize != uint64(tt.added-1)) {
+				t.Errorf("Latest() = %v, %v", latest.Snapshot.FreeSize, ok)
+			}
+		})
+	}
+}
+
+func TestSampler(t *testing.T) {
+	var fail bool
+	provider := memory.ProviderFunc(func() (memory.Snapshot, error) {
+		if fail {
+			return memory.Snapshot{}, errors.New("query failed")
+		}
+		return memory.Snapshot{FreeSize: 42}, nil
+	})
+	s := New(provider, 10, func() time.Time { return baseTime })
+
+	if snapshot, err := s.Sample(); err != nil || snapshot.FreeSize != 42 {
+		t.Fatalf("Sample() = %+v, %v", snapshot, err)
+	}
+	fail = true
+	if _, err := s.Sample(); err == nil {
+		t.Error("Sample() expected an error")
+	}
+
+	if s.Len() != 1 {
+		t.Errorf("Len() = %d, want only the successful sample", s.Len())
+	}
+	if s.Errors() != 1 {
+		t.Errorf("Errors() = %d, want 1", s.Errors())
+	}
+	if latest, _ := s.Latest(); !latest.Time.Equal(baseTime) {
+		t.Errorf("Latest().Time = %v, want %v", latest.Time, baseTime)
+	}
+}
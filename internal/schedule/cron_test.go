@@ -0,0 +1,110 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day, hour, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestNext(t *testing.T) {
+	// Monday
+	start := time.Date(2024, 1, 1, 12, 0, 30, 0, time.UTC)
+
+	tests := []struct {
+		spec     string
+		expected time.Time
+	}{
+		{"@every 30m", start.Add(30 * time.Minute)},
+		{"@hourly", date(2024, 1, 1, 13, 0)},
+		{"@daily", date(2024, 1, 2, 0, 0)},
+		{"@weekly", date(2024, 1, 7, 0, 0)},
+		{"@monthly", date(2024, 2, 1, 0, 0)},
+		{"* * * * *", date(2024, 1, 1, 12, 1)},
+		{"*/15 * * * *", date(2024, 1, 1, 12, 15)},
+		{"0 3 * * *", date(2024, 1, 2, 3, 0)},
+		{"30 12 * * *", date(2024, 1, 1, 12, 30)},
+		{"0 8-18/2 * * *", date(2024, 1, 1, 14, 0)},
+		{"0 9 * * mon-fri", date(2024, 1, 2, 9, 0)},
+		{"0 9 * * 6,7", date(2024, 1, 6, 9, 0)},
+		{"0 0 29 feb *", date(2024, 2, 29, 0, 0)},
+		{"0 0 1 Jun *", date(2024, 6, 1, 0, 0)},
+		// Day of month or day of week
+		{"0 0 15 * fri", date(2024, 1, 5, 0, 0)},
+		{"0 0 31 * *", date(2024, 1, 31, 0, 0)},
+		{"5/20 * * * *", date(2024, 1, 1, 12, 5)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.spec, err)
+			}
+			if next := s.Next(start); !next.Equal(tt.expected) {
+				t.Errorf("Next(%v) = %v, want %v", start, next, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNextNever(t *testing.T) {
+	s, err := Parse("0 0 30 feb *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next := s.Next(date(2024, 1, 1, 0, 0)); !next.IsZero() {
+		t.Errorf("Next() = %v, want the zero time", next)
+	}
+}
+
+func TestNextDaylightSaving(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+	s, err := Parse("30 2 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 02:30 doesn't exist on 2024-03-31, the job runs the next day
+	next := s.Next(time.Date(2024, 3, 30, 12, 0, 0, 0, loc))
+	if want := time.Date(2024, 4, 1, 2, 30, 0, 0, loc); !next.Equal(want) {
+		t.Errorf("Next() = %v, want %v", next, want)
+	}
+
+	// 02:30 happens twice on 2024-10-27, the job runs once
+	first := s.Next(time.Date(2024, 10, 27, 0, 0, 0, 0, loc))
+	second := s.Next(first)
+	if second.Sub(first) < 23*time.Hour {
+		t.Errorf("Next() = %v then %v, want one run a day", first, second)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"@every",
+		"@every 10s",
+		"@every soon",
+		"@sometimes",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"* * * foo *",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"1,,2 * * * *",
+	}
+	for _, spec := range tests {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) expected an error", spec)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+// Package schedule Description: This package runs cleans at scheduled times, such as a standby list purge
+// every 30 minutes or a deep clean at 03:00 every day. The times are cron expressions or simple intervals.
+// The cleaning and the clock are injected, so the scheduling logic does not depend on WinAPI and can be
+// tested without waiting.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MaxWait is the longest time Run sleeps before checking the jobs again, so that changed jobs
+// and a wall clock jumping forward, e.g. after the computer wakes up, are noticed.
+const MaxWait = time.Minute
+
+// Action is the clean run by a job.
+type Action string
+
+const (
+	ActionCleanStandby Action = "clean-standby"
+	ActionCleanRAM     Action = "clean-ram"
+)
+
+// Job is a clean run at scheduled times.
+type Job struct {
+	// When is a cron expression such as "0 3 * * *", a shortcut such as "@daily" or
+	// an interval such as "@every 30m", see Parse.
+	When   string `json:"when"`
+	Action Action `json:"action"`
+	// Deep includes the critical processes in a RAM clean.
+	Deep bool `json:"deep,omitempty"`
+}
+
+// String returns a short name of the clean.
+func (j Job) String() string {
+	switch {
+	case j.Action == ActionCleanStandby:
+		return "standby"
+	case j.Deep:
+		return "deep RAM"
+	default:
+		return "RAM"
+	}
+}
+
+// Set is a compiled list of jobs.
+type Set struct {
+	jobs      []Job
+	schedules []Schedule
+}
+
+// Compile validates the jobs and parses their schedules; a job must run at least once after now.
+// It returns all problems at once.
+func Compile(jobs []Job, now time.Time) (*Set, error) {
+	s := &Set{}
+	var errs []error
+	for i, j := range jobs {
+		switch {
+		case j.Action != ActionCleanStandby && j.Action != ActionCleanRAM:
+			errs = append(errs, fmt.Errorf("job %d: action must be %s or %s, got %q", i, ActionCleanStandby, ActionCleanRAM, j.Action))
+			continue
+		case j.Deep && j.Action != ActionCleanRAM:
+			errs = append(errs, fmt.Errorf("job %d: deep only applies to %s", i, ActionCleanRAM))
+			continue
+		}
+		schedule, err := Parse(j.When)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("job %d: %w", i, err))
+			continue
+		}
+		if schedule.Next(now).IsZero() {
+			errs = append(errs, fmt.Errorf("job %d: %q never runs", i, j.When))
+			continue
+		}
+		s.jobs = append(s.jobs, j)
+		s.schedules = append(s.schedules, schedule)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Cleaner performs the cleans of the jobs.
+type Cleaner interface {
+	// Busy reports whether a clean is running.
+	Busy() bool
+	CleanStandbyList() error
+	CleanRAM(deep bool) error
+}
+
+// Clock provides the current time and timers, so tests can control time.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock is a Clock backed by the time package.
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time                         { return time.Now() }
+func (SystemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Run is the outcome of one scheduled run of a job.
+type Run struct {
+	Job  Job
+	Time time.Time // When the run was due
+	// Skipped is set when another clean was running, the job runs again at its next time.
+	Skipped bool
+	Err     error
+}
+
+// scheduled is a job with the time of its next run.
+type scheduled struct {
+	job      Job
+	schedule Schedule
+	next     time.Time
+}
+
+// Scheduler runs the jobs of a Set when they are due. It is safe for concurrent use.
+type Scheduler struct {
+	cleaner Cleaner
+	clock   Clock
+
+	mu   sync.Mutex
+	jobs []scheduled
+}
+
+// New creates a Scheduler without jobs. A nil clock means the system clock.
+func New(cleaner Cleaner, clock Clock) *Scheduler {
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	return &Scheduler{cleaner: cleaner, clock: clock}
+}
+
+// SetJobs replaces the jobs. Jobs that were already scheduled keep their next run, so that
+// reloading the configuration doesn't restart the intervals. A nil set removes all jobs.
+func (s *Scheduler) SetJobs(set *Set) {
+	now := s.clock.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := make(map[Job]time.Time, len(s.jobs))
+	for _, j := range s.jobs {
+		previous[j.job] = j.next
+	}
+	s.jobs = nil
+	if set == nil {
+		return
+	}
+	for i, job := range set.jobs {
+		next, ok := previous[job]
+		if !ok {
+			next = set.schedules[i].Next(now)
+		}
+		s.jobs = append(s.jobs, scheduled{job: job, schedule: set.schedules[i], next: next})
+	}
+}
+
+// Next returns the job running next and when, or false if there are no jobs.
+func (s *Scheduler) Next() (Job, time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var first *scheduled
+	for i := range s.jobs {
+		j := &s.jobs[i]
+		if !j.next.IsZero() && (first == nil || j.next.Before(first.next)) {
+			first = j
+		}
+	}
+	if first == nil {
+		return Job{}, time.Time{}, false
+	}
+	return first.job, first.next, true
+}
+
+// Step runs the jobs that are due, in the order of the Set, and returns their outcome.
+// A job due several times, e.g. while the computer was asleep, runs once. A job due
+// while a clean is running is skipped until its next time.
+func (s *Scheduler) Step() []Run {
+	now := s.clock.Now()
+
+	var runs []Run
+	s.mu.Lock()
+	for i := range s.jobs {
+		j := &s.jobs[i]
+		if j.next.IsZero() || j.next.After(now) {
+			continue
+		}
+		runs = append(runs, Run{Job: j.job, Time: j.next})
+		j.next = j.schedule.Next(now)
+	}
+	s.mu.Unlock()
+
+	// The cleans run without the lock, they take a while
+	for i := range runs {
+		r := &runs[i]
+		if s.cleaner.Busy() {
+			r.Skipped = true
+			continue
+		}
+		if r.Job.Action == ActionCleanStandby {
+			r.Err = s.cleaner.CleanStandbyList()
+		} else {
+			r.Err = s.cleaner.CleanRAM(r.Job.Deep)
+		}
+	}
+	return runs
+}
+
+// Run runs the jobs when they are due until stop is closed. onRun, if not nil, receives every run.
+func (s *Scheduler) Run(stop <-chan struct{}, onRun func(Run)) {
+	for {
+		wait := MaxWait
+		if _, next, ok := s.Next(); ok {
+			wait = min(max(next.Sub(s.clock.Now()), 0), MaxWait)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-s.clock.After(wait):
+			for _, r := range s.Step() {
+				if onRun != nil {
+					onRun(r)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package schedule
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+type fakeCleaner struct {
+	busy  bool
+	err   error
+	calls []string
+}
+
+func (c *fakeCleaner) Busy() bool { return c.busy }
+
+func (c *fakeCleaner) CleanStandbyList() error {
+	c.calls = append(c.calls, "standby")
+	return c.err
+}
+
+func (c *fakeCleaner) CleanRAM(deep bool) error {
+	if deep {
+		c.calls = append(c.calls, "deep")
+	} else {
+		c.calls = append(c.calls, "ram")
+	}
+	return c.err
+}
+
+func mustCompile(t *testing.T, jobs ...Job) *Set {
+	t.Helper()
+	set, err := Compile(jobs, date(2024, 1, 1, 12, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return set
+}
+
+func newTestScheduler(t *testing.T, jobs ...Job) (*Scheduler, *fakeCleaner, *fakeClock) {
+	cleaner := &fakeCleaner{}
+	clock := &fakeClock{now: date(2024, 1, 1, 12, 0)}
+	s := New(cleaner, clock)
+	s.SetJobs(mustCompile(t, jobs...))
+	return s, cleaner, clock
+}
+
+func TestStep(t *testing.T) {
+	s, cleaner, clock := newTestScheduler(t,
+		Job{When: "@every 30m", Action: ActionCleanStandby},
+		Job{When: "0 3 * * *", Action: ActionCleanRAM, Deep: true},
+	)
+
+	if job, next, ok := s.Next(); !ok || job.Action != ActionCleanStandby || !next.Equal(date(2024, 1, 1, 12, 30)) {
+		t.Errorf("Next() = %v, %v, %v, want the standby purge at 12:30", job, next, ok)
+	}
+	if runs := s.Step(); len(runs) != 0 {
+		t.Errorf("Step() before the first run = %+v", runs)
+	}
+
+	clock.now = date(2024, 1, 1, 12, 30)
+	runs := s.Step()
+	if len(runs) != 1 || runs[0].Job.Action != ActionCleanStandby || runs[0].Skipped || runs[0].Err != nil {
+		t.Errorf("Step() at 12:30 = %+v", runs)
+	}
+
+	// Both jobs are due, the purge was due several times while asleep and runs once
+	clock.now = date(2024, 1, 2, 3, 0)
+	runs = s.Step()
+	if len(runs) != 2 {
+		t.Fatalf("Step() at 03:00 = %+v, want both jobs", runs)
+	}
+	if calls, want := strings.Join(cleaner.calls, ","), "standby,standby,deep"; calls != want {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+	if _, next, _ := s.Next(); !next.Equal(date(2024, 1, 2, 3, 30)) {
+		t.Errorf("Next() = %v, want 03:30", next)
+	}
+}
+
+func TestStepSkipsWhileBusy(t *testing.T) {
+	s, cleaner, clock := newTestScheduler(t, Job{When: "@every 30m", Action: ActionCleanRAM})
+	cleaner.busy = true
+
+	clock.now = clock.now.Add(30 * time.Minute)
+	runs := s.Step()
+	if len(runs) != 1 || !runs[0].Skipped || len(cleaner.calls) != 0 {
+		t.Errorf("Step() while busy = %+v, calls %v, want a skipped run", runs, cleaner.calls)
+	}
+
+	// The skipped job waits for its next time
+	cleaner.busy = false
+	if runs := s.Step(); len(runs) != 0 {
+		t.Errorf("Step() after a skipped run = %+v, want nothing until the next time", runs)
+	}
+	clock.now = clock.now.Add(30 * time.Minute)
+	if runs := s.Step(); len(runs) != 1 || runs[0].Skipped || len(cleaner.calls) != 1 {
+		t.Errorf("Step() = %+v, calls %v, want a run", runs, cleaner.calls)
+	}
+}
+
+func TestStepError(t *testing.T) {
+	s, cleaner, clock := newTestScheduler(t, Job{When: "@hourly", Action: ActionCleanStandby})
+	cleaner.err = errors.New("access denied")
+
+	clock.now = date(2024, 1, 1, 13, 0)
+	if runs := s.Step(); len(runs) != 1 || runs[0].Err != cleaner.err {
+		t.Errorf("Step() = %+v, want the clean error", runs)
+	}
+}
+
+func TestSetJobsKeepsNextRun(t *testing.T) {
+	interval := Job{When: "@every 30m", Action: ActionCleanStandby}
+	s, _, clock := newTestScheduler(t, interval)
+
+	clock.now = clock.now.Add(20 * time.Minute)
+	s.SetJobs(mustCompile(t, interval, Job{When: "@daily", Action: ActionCleanRAM}))
+	if job, next, _ := s.Next(); job != interval || !next.Equal(date(2024, 1, 1, 12, 30)) {
+		t.Errorf("Next() = %v, %v, want the interval kept", job, next)
+	}
+
+	s.SetJobs(nil)
+	if _, _, ok := s.Next(); ok {
+		t.Error("Next() without jobs = true, want false")
+	}
+}
+
+func TestRun(t *testing.T) {
+	s, cleaner, _ := newTestScheduler(t, Job{When: "@every 30m", Action: ActionCleanStandby})
+
+	stop := make(chan struct{})
+	var runs []Run
+	s.Run(stop, func(r Run) {
+		runs = append(runs, r)
+		if len(runs) == 3 {
+			close(stop)
+		}
+	})
+
+	if len(cleaner.calls) != 3 {
+		t.Errorf("calls = %v, want 3 runs", cleaner.calls)
+	}
+	for i, r := range runs {
+		if want := date(2024, 1, 1, 12, 30).Add(time.Duration(i) * 30 * time.Minute); !r.Time.Equal(want) {
+			t.Errorf("run %d at %v, want %v", i, r.Time, want)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		job  Job
+	}{
+		{"unknown action", Job{When: "@daily", Action: "reboot"}},
+		{"deep standby", Job{When: "@daily", Action: ActionCleanStandby, Deep: true}},
+		{"invalid schedule", Job{When: "every day", Action: ActionCleanRAM}},
+		{"never runs", Job{When: "0 0 31 apr *", Action: ActionCleanRAM}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Compile([]Job{tt.job}, date(2024, 1, 1, 12, 0)); err == nil {
+				t.Errorf("Compile(%+v) expected an error", tt.job)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+// Package service Description: This package coordinates cleans started from the tray, the command line
+// and the automatic triggers: it runs the platform backend and records every clean in the history.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"windows-ram-cleaner/internal/autoclean"
+	"windows-ram-cleaner/internal/history"
+	"windows-ram-cleaner/internal/inventory"
+	"windows-ram-cleaner/internal/memlist"
+	"windows-ram-cleaner/internal/report"
+	"windows-ram-cleaner/internal/trim"
+)
+
+// ErrBusy is returned when a clean is requested while another one is running.
+var ErrBusy = errors.New("a clean is already running")
+
+// Backend performs the cleans on the current platform. Cleans stop early when ctx is canceled.
+type Backend interface {
+	CleanStandbyList(ctx context.Context) (*report.CleanReport, error)
+	CleanRAM(ctx context.Context, deep bool) (*report.CleanReport, error)
+	MemoryList(ctx context.Context, command memlist.Command) (*report.CleanReport, error)
+	Trim(ctx context.Context, target inventory.Target) (*report.CleanReport, error)
+}
+
+// BackendFuncs adapts plain functions to the Backend interface.
+type BackendFuncs struct {
+	Standby  func(ctx context.Context) (*report.CleanReport, error)
+	RAM      func(ctx context.Context, deep bool) (*report.CleanReport, error)
+	MemList  func(ctx context.Context, command memlist.Command) (*report.CleanReport, error)
+	Targeted func(ctx context.Context, target inventory.Target) (*report.CleanReport, error)
+}
+
+func (b BackendFuncs) CleanStandbyList(ctx context.Context) (*report.CleanReport, error) {
+	return b.Standby(ctx)
+}
+
+func (b BackendFuncs) CleanRAM(ctx context.Context, deep bool) (*report.CleanReport, error) {
+	return b.RAM(ctx, deep)
+}
+
+func (b BackendFuncs) MemoryList(ctx context.Context, command memlist.Command) (*report.CleanReport, error) {
+	return b.MemList(ctx, command)
+}
+
+func (b BackendFuncs) Trim(ctx context.Context, target inventory.Target) (*report.CleanReport, error) {
+	return b.Targeted(ctx, target)
+}
+
+// Service runs cleans one at a time and records them. It is safe for concurrent use.
+type Service struct {
+	backend Backend
+	history *history.Store
+
+	mu     sync.Mutex
+	cancel context.CancelFunc // Cancels the running clean, nil when idle
+
+	// OnHistoryError, if not nil, receives errors saving the history. They never fail a clean.
+	OnHistoryError func(error)
+	// OnBusyChange, if not nil, is called when a clean starts and when it ends.
+	OnBusyChange func(busy bool)
+	// OnClean, if not nil, receives the result of every clean started by the service.
+	OnClean func(trigger history.Trigger, r *report.CleanReport, err error)
+}
+
+// New creates a Service. A nil store disables the history.
+func New(backend Backend, store *history.Store) *Service {
+	return &Service{backend: backend, history: store}
+}
+
+// History returns the history store, nil if the history is disabled.
+func (s *Service) History() *history.Store {
+	return s.history
+}
+
+// CleanStandbyList purges the standby list and records the clean.
+// It returns ErrBusy if another clean is running.
+func (s *Service) CleanStandbyList(ctx context.Context, trigger history.Trigger) (*report.CleanReport, error) {
+	ctx, done, err := s.start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	r, err := s.backend.CleanStandbyList(ctx)
+	s.record(trigger, r, err)
+	return r, err
+}
+
+// CleanRAM trims working sets, including critical processes when deep is set, and records the clean.
+// It returns ErrBusy if another clean is running.
+func (s *Service) CleanRAM(ctx context.Context, trigger history.Trigger, deep bool) (*report.CleanReport, error) {
+	ctx, done, err := s.start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	r, err := s.backend.CleanRAM(ctx, deep)
+	s.record(trigger, r, err)
+	return r, err
+}
+
+// MemoryList runs a memory list command and records it.
+// It returns ErrBusy if a clean is running.
+func (s *Service) MemoryList(ctx context.Context, trigger history.Trigger, command memlist.Command) (*report.CleanReport, error) {
+	ctx, done, err := s.start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	r, err := s.backend.MemoryList(ctx, command)
+	s.record(trigger, r, err)
+	return r, err
+}
+
+// Trim trims the processes selected by target and records the clean.
+// It returns ErrBusy if another clean is running.
+func (s *Service) Trim(ctx context.Context, trigger history.Trigger, target inventory.Target) (*report.CleanReport, error) {
+	ctx, done, err := s.start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	r, err := s.backend.Trim(ctx, target)
+	s.record(trigger, r, err)
+	return r, err
+}
+
+// Busy reports whether a clean is running.
+func (s *Service) Busy() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cancel != nil
+}
+
+// Cancel cancels the running clean and reports whether there was one.
+// The clean returns as soon as the backend notices the cancellation.
+func (s *Service) Cancel() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel == nil {
+		return false
+	}
+	s.cancel()
+	return true
+}
+
+// start marks the service busy and returns the context of the new clean and the function
+// ending it, or ErrBusy.
+func (s *Service) start(parent context.Context) (context.Context, func(), error) {
+	s.mu.Lock()
+	if s.cancel != nil {
+		s.mu.Unlock()
+		return nil, nil, ErrBusy
+	}
+	ctx, cancel := context.WithCancel(parent)
+	s.cancel = cancel
+	s.mu.Unlock()
+	s.notifyBusy(true)
+
+	return ctx, func() {
+		cancel()
+		s.mu.Lock()
+		s.cancel = nil
+		s.mu.Unlock()
+		s.notifyBusy(false)
+	}, nil
+}
+
+func (s *Service) notifyBusy(busy bool) {
+	if s.OnBusyChange != nil {
+		s.OnBusyChange(busy)
+	}
+}
+
+// Stats returns the history statistics, zero if the history is disabled.
+func (s *Service) Stats() (history.Stats, error) {
+	if s.history == nil {
+		return history.Stats{}, nil
+	}
+	return s.history.Stats()
+}
+
+// AutoCleaner returns a Cleaner for the auto-clean engine recording its cleans with trigger.
+// Automatic cleans never use the deep mode. Processes that could not be trimmed are only
+// recorded in the history: they don't make the clean fail for the engine. A clean refused
+// because another one is running is skipped, see autoclean.ErrSkipped.
+func (s *Service) AutoCleaner(trigger history.Trigger) autoclean.Cleaner {
+	return autoclean.CleanerFuncs{
+		Standby: func() error {
+			_, err := s.CleanStandbyList(context.Background(), trigger)
+			return autoCleanError(err)
+		},
+		LowStandby: func() error {
+			_, err := s.MemoryList(context.Background(), trigger, memlist.PurgeLowPriorityStandbyList)
+			return autoCleanError(err)
+		},
+		RAM: func() error {
+			_, err := s.CleanRAM(context.Background(), trigger, false)
+			if trim.IsPartial(err) {
+				return nil
+			}
+			return autoCleanError(err)
+		},
+	}
+}
+
+// autoCleanError marks ErrBusy as a skipped clean for the auto-clean engine.
+func autoCleanError(err error) error {
+	if errors.Is(err, ErrBusy) {
+		return fmt.Errorf("%w: %w", autoclean.ErrSkipped, err)
+	}
+	return err
+}
+
+func (s *Service) record(trigger history.Trigger, r *report.CleanReport, err error) {
+	if s.OnClean != nil {
+		s.OnClean(trigger, r, err)
+	}
+	if s.history == nil || r == nil {
+		return
+	}
+	if err := s.history.Append(history.NewEntry(trigger, r)); err != nil && s.OnHistoryError != nil {
+		s.OnHistoryError(fmt.Errorf("failed to save clean history: %v", err))
+	}
+}
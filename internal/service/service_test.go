@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"windows-ram-cleaner/internal/autoclean"
+	"windows-ram-cleaner/internal/history"
+	"windows-ram-cleaner/internal/inventory"
+	"windows-ram-cleaner/internal/memlist"
+	"windows-ram-cleaner/internal/memory"
+	"windows-ram-cleaner/internal/report"
+	"windows-ram-cleaner/internal/trim"
+)
+
+type fakeBackend struct {
+	err   error
+	calls []report.Mode
+	// started, if not nil, makes a clean signal its start and wait for its context to be done.
+	started chan struct{}
+}
+
+func (b *fakeBackend) clean(ctx context.Context, mode report.Mode) (*report.CleanReport, error) {
+	b.calls = append(b.calls, mode)
+	if b.started != nil {
+		close(b.started)
+		<-ctx.Done()
+		b.err = ctx.Err()
+	}
+	r := report.New(mode, time.Now(), memory.Snapshot{})
+	r.AddStage(string(mode), 100, 0, b.err)
+	r.Finish(time.Now(), memory.Snapshot{}, b.err)
+	return r, b.err
+}
+
+func (b *fakeBackend) CleanStandbyList(ctx context.Context) (*report.CleanReport, error) {
+	return b.clean(ctx, report.ModeStandby)
+}
+
+func (b *fakeBackend) CleanRAM(ctx context.Context, deep bool) (*report.CleanReport, error) {
+	if deep {
+		return b.clean(ctx, report.ModeDeep)
+	}
+	return b.clean(ctx, report.ModeBasic)
+}
+
+func (b *fakeBackend) MemoryList(ctx context.Context, command memlist.Command) (*report.CleanReport, error) {
+	return b.clean(ctx, report.ModeMemoryList)
+}
+
+func (b *fakeBackend) Trim(ctx context.Context, target inventory.Target) (*report.CleanReport, error) {
+	return b.clean(ctx, report.ModeTargeted)
+}
+
+func newTestService(t *testing.T) (*Service, *fakeBackend) {
+	backend := &fakeBackend{}
+	store := history.Open(filepath.Join(t.TempDir(), history.FileName), history.Retention{})
+	return New(backend, store), backend
+}
+
+func TestCleansAreRecorded(t *testing.T) {
+	s, backend := newTestService(t)
+
+	if _, err := s.CleanStandbyList(context.Background(), history.TriggerManual); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CleanRAM(context.Background(), history.TriggerCLI, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.MemoryList(context.Background(), history.TriggerCLI, memlist.FlushModifiedList); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Trim(context.Background(), history.TriggerManual, inventory.Target{PID: 42}); err != nil {
+		t.Fatal(err)
+	}
+	backend.err = errors.New("denied")
+	if _, err := s.CleanRAM(context.Background(), history.TriggerManual, false); err == nil {
+		t.Fatal("CleanRAM() expected an error")
+	}
+
+	entries, err := s.History().Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct {
+		trigger history.Trigger
+		mode    report.Mode
+		failed  bool
+	}{
+		{trigger: history.TriggerManual, mode: report.ModeStandby},
+		{trigger: history.TriggerCLI, mode: report.ModeDeep},
+		{trigger: history.TriggerCLI, mode: report.ModeMemoryList},
+		{trigger: history.TriggerManual, mode: report.ModeTargeted},
+		{trigger: history.TriggerManual, mode: report.ModeBasic, failed: true},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("recorded %d entries, want %d", len(entries), len(expected))
+	}
+	for i, e := range expected {
+		got := entries[i]
+		if got.Trigger != e.trigger || got.Mode != e.mode || (got.Report.Error != "") != e.failed {
+			t.Errorf("entry %d = %s/%s error %q, want %s/%s", i, got.Trigger, got.Mode, got.Report.Error, e.trigger, e.mode)
+		}
+	}
+
+	stats, err := s.Stats()
+	if err != nil || stats.Cleans != 5 {
+		t.Errorf("Stats() = %+v, %v", stats, err)
+	}
+}
+
+func TestAutoCleaner(t *testing.T) {
+	s, backend := newTestService(t)
+	cleaner := s.AutoCleaner(history.TriggerAuto)
+
+	if err := cleaner.CleanRAM(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cleaner.CleanStandbyList(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cleaner.CleanLowPriorityStandby(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []report.Mode{report.ModeBasic, report.ModeStandby, report.ModeMemoryList}
+	if len(backend.calls) != 3 || backend.calls[0] != expected[0] || backend.calls[1] != expected[1] || backend.calls[2] != expected[2] {
+		t.Errorf("backend calls = %v, want %v", backend.calls, expected)
+	}
+	entries, _ := s.History().Entries()
+	if len(entries) != 3 || entries[0].Trigger != history.TriggerAuto {
+		t.Errorf("entries = %+v", entries)
+	}
+}
+
+func TestAutoCleanerPartialFailure(t *testing.T) {
+	s, backend := newTestService(t)
+	cleaner := s.AutoCleaner(history.TriggerAuto)
+
+	partial := &trim.Errors{Attempted: 3}
+	partial.Add(4, "csrss.exe", "open process", errors.New("access denied"))
+	backend.err = partial
+	if err := cleaner.CleanRAM(); err != nil {
+		t.Errorf("CleanRAM() error = %v, want nil for a partial failure", err)
+	}
+
+	backend.err = errors.New("snapshot failed")
+	if err := cleaner.CleanRAM(); err == nil {
+		t.Errorf("CleanRAM() error = nil, want the backend error")
+	}
+}
+
+func TestAutoCleanerBusy(t *testing.T) {
+	s, backend := newTestService(t)
+	backend.started = make(chan struct{})
+	cleaner := s.AutoCleaner(history.TriggerAuto)
+
+	done := make(chan error)
+	go func() {
+		_, err := s.CleanRAM(context.Background(), history.TriggerManual, false)
+		done <- err
+	}()
+	<-backend.started
+
+	for name, clean := range map[string]func() error{
+		"CleanRAM":                cleaner.CleanRAM,
+		"CleanStandbyList":        cleaner.CleanStandbyList,
+		"CleanLowPriorityStandby": cleaner.CleanLowPriorityStandby,
+	} {
+		if err := clean(); !errors.Is(err, autoclean.ErrSkipped) || !errors.Is(err, ErrBusy) {
+			t.Errorf("%s() during a clean error = %v, want autoclean.ErrSkipped and ErrBusy", name, err)
+		}
+	}
+
+	s.Cancel()
+	<-done
+}
+
+func TestBusyAndCancel(t *testing.T) {
+	s, backend := newTestService(t)
+	backend.started = make(chan struct{})
+	var busyChanges []bool
+	s.OnBusyChange = func(busy bool) { busyChanges = append(busyChanges, busy) }
+
+	if s.Cancel() {
+		t.Errorf("Cancel() = true without a running clean")
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := s.CleanRAM(context.Background(), history.TriggerManual, false)
+		done <- err
+	}()
+	<-backend.started
+
+	if !s.Busy() {
+		t.Errorf("Busy() = false during a clean")
+	}
+	if _, err := s.CleanStandbyList(context.Background(), history.TriggerManual); !errors.Is(err, ErrBusy) {
+		t.Errorf("CleanStandbyList() during a clean error = %v, want ErrBusy", err)
+	}
+	if !s.Cancel() {
+		t.Errorf("Cancel() = false during a clean")
+	}
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("CleanRAM() error = %v, want context.Canceled", err)
+	}
+
+	if s.Busy() {
+		t.Errorf("Busy() = true after the clean")
+	}
+	if len(busyChanges) != 2 || !busyChanges[0] || busyChanges[1] {
+		t.Errorf("busy changes = %v, want [true false]", busyChanges)
+	}
+	entries, _ := s.History().Entries()
+	if len(entries) != 1 || entries[0].Report.Error == "" {
+		t.Errorf("entries = %+v, want the canceled clean", entries)
+	}
+}
+
+func TestHistoryErrorDoesNotFailClean(t *testing.T) {
+	dir := t.TempDir()
+	// A directory in place of the file makes every write fail.
+	path := filepath.Join(dir, history.FileName)
+	if err := os.Mkdir(path, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(&fakeBackend{}, history.Open(path, history.Retention{}))
+	var historyErr error
+	s.OnHistoryError = func(err error) { historyErr = err }
+
+	if _, err := s.CleanStandbyList(context.Background(), history.TriggerManual); err != nil {
+		t.Errorf("CleanStandbyList() error = %v", err)
+	}
+	if historyErr == nil {
+		t.Errorf("OnHistoryError was not called")
+	}
+}
+
+func TestWithoutHistory(t *testing.T) {
+	s := New(&fakeBackend{}, nil)
+	if _, err := s.CleanRAM(context.Background(), history.TriggerManual, false); err != nil {
+		t.Fatal(err)
+	}
+	if stats, err := s.Stats(); err != nil || stats.Cleans != 0 {
+		t.Errorf("Stats() = %+v, %v", stats, err)
+	}
+}
+
+func TestOnClean(t *testing.T) {
+	s := New(&fakeBackend{}, nil)
+	var triggers []history.Trigger
+	s.OnClean = func(trigger history.Trigger, r *report.CleanReport, err error) {
+		if r == nil || err != nil {
+			t.Errorf("OnClean(%v, %v, %v), want a report", trigger, r, err)
+		}
+		triggers = append(triggers, trigger)
+	}
+
+	if _, err := s.CleanRAM(context.Background(), history.TriggerAuto, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CleanStandbyList(context.Background(), history.TriggerManual); err != nil {
+		t.Fatal(err)
+	}
+	if len(triggers) != 2 || triggers[0] != history.TriggerAuto || triggers[1] != history.TriggerManual {
+		t.Errorf("OnClean triggers = %v, want [auto manual]", triggers)
+	}
+}
@@ -0,0 +1,62 @@
+//go:build windows
+
+// Description: This file contains the local web page with the memory graphs.
+
+package tray
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"windows-ram-cleaner/internal/graph"
+	"windows-ram-cleaner/internal/history"
+	"windows-ram-cleaner/internal/windows_api"
+)
+
+// graphServer serves the memory graph page on a free loopback port, started on first use.
+var graphServer struct {
+	once sync.Once
+	url  string
+	err  error
+}
+
+// graphURL starts the memory graph server if needed and returns its address.
+func graphURL() (string, error) {
+	graphServer.once.Do(func() {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			graphServer.err = fmt.Errorf("failed to start the graph server: %v", err)
+			return
+		}
+		src := graph.Source{Samples: Samples.Since}
+		if Service != nil && Service.History() != nil {
+			src.Cleans = func() ([]history.Entry, error) { return Service.History().Entries() }
+		}
+		host := listener.Addr().String()
+		server := &http.Server{
+			Handler:           graph.HostOnly(host, graph.Handler(src)),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go server.Serve(listener)
+		graphServer.url = "http://" + host + "/"
+	})
+	return graphServer.url, graphServer.err
+}
+
+// handleMemoryGraph opens the memory graph page in the browser. The browser is started by the shell
+// of the user, so that it doesn't run elevated like the tray application.
+func handleMemoryGraph() {
+	url, err := graphURL()
+	if err == nil {
+		err = windowsapi.ShellOpenUnelevated(url)
+	}
+	if err != nil {
+		Notifier.Error(
+			"Error opening memory graph",
+			fmt.Sprintf("Can't open the memory graph, err: %s", err.Error()),
+		)
+	}
+}
@@ -1,27 +1,44 @@
+//go:build windows
+
 // Description: This file contains functions for handling menu item clicks.
 
 package tray
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/getlantern/systray"
 
+	"windows-ram-cleaner/internal/history"
+	"windows-ram-cleaner/internal/memlist"
+	"windows-ram-cleaner/internal/notify"
+	"windows-ram-cleaner/internal/report"
+	"windows-ram-cleaner/internal/service"
+	"windows-ram-cleaner/internal/trim"
 	winstartup "windows-ram-cleaner/internal/win_startup"
 	"windows-ram-cleaner/internal/windows_api"
 )
 
 // handleMenuClicks listens for clicks on tray menu items and performs the corresponding actions.
+// Cleans run in their own goroutine so that the menu stays responsive and can cancel them.
 func handleMenuClicks(TrayMenuItems *TrayMenuItems) {
 	for {
 		select {
 		case <-TrayMenuItems.MRAMCleanForce.ClickedCh:
-			handleRAMClean(true)
+			go handleRAMClean(true)
 		case <-TrayMenuItems.MRAMCleanSafe.ClickedCh:
-			handleRAMClean(false)
+			go handleRAMClean(false)
 		case <-TrayMenuItems.MSTDClean.ClickedCh:
-			handleSTDClean()
+			go handleSTDClean()
+		case <-TrayMenuItems.MCancelClean.ClickedCh:
+			Service.Cancel()
+		case <-TrayMenuItems.MMemoryGraph.ClickedCh:
+			go handleMemoryGraph()
+		case <-TrayMenuItems.MOpenLog.ClickedCh:
+			go handleOpenLog()
 		case <-TrayMenuItems.MStartupAdd.ClickedCh:
 			handleAddToStartup()
 		case <-TrayMenuItems.MStartupRemove.ClickedCh:
@@ -34,28 +51,90 @@ func handleMenuClicks(TrayMenuItems *TrayMenuItems) {
 
 // handleRAMClean handles RAM cleaning based on the given option.
 func handleRAMClean(ignoreCritical bool) {
-	options := windowsapi.CleanOptions{
-		IgnoreCritical: ignoreCritical,
-	}
-	if err := windowsapi.CleanRAM(options); err != nil {
-		windowsapi.ShowError(
-			fmt.Sprintf("Can't clean RAM, err: %s", err.Error()),
+	cleanReport, err := Service.CleanRAM(context.Background(), history.TriggerManual, ignoreCritical)
+	switch {
+	case errors.Is(err, service.ErrBusy):
+		showBusy()
+	case errors.Is(err, context.Canceled):
+		UpdateTooltip()
+		showReport("RAM clean canceled", cleanReport, notify.Warning)
+	case trim.IsPartial(err):
+		// Some processes can't be trimmed, e.g. protected ones: the report lists them.
+		UpdateTooltip()
+		showReport("RAM cleaned with failures", cleanReport, notify.Warning)
+	case err != nil:
+		Notifier.Error(
 			"Error cleaning RAM",
+			fmt.Sprintf("Can't clean RAM, err: %s", err.Error()),
 		)
-	} else {
+	default:
 		UpdateTooltip()
+		showReport("RAM cleaned", cleanReport, notify.Info)
 	}
 }
 
 // handleSTDClean handles standby list cleaning.
 func handleSTDClean() {
-	if err := windowsapi.CleanStandbyList(); err != nil {
-		windowsapi.ShowError(
-			fmt.Sprintf("Can't clean standby list, err: %s", err.Error()),
+	cleanReport, err := Service.CleanStandbyList(context.Background(), history.TriggerManual)
+	switch {
+	case errors.Is(err, service.ErrBusy):
+		showBusy()
+	case errors.Is(err, context.Canceled):
+		showReport("Standby list clean canceled", cleanReport, notify.Warning)
+	case err != nil:
+		Notifier.Error(
 			"Error cleaning standby list",
+			fmt.Sprintf("Can't clean standby list, err: %s", err.Error()),
 		)
-	} else {
+	default:
 		UpdateTooltip()
+		showReport("Standby list cleaned", cleanReport, notify.Info)
+	}
+}
+
+// handleMemoryListClicks runs command every time item is clicked.
+func handleMemoryListClicks(command memlist.Command, item *systray.MenuItem) {
+	for range item.ClickedCh {
+		go handleMemoryList(command)
+	}
+}
+
+// handleMemoryList runs a memory list command.
+func handleMemoryList(command memlist.Command) {
+	cleanReport, err := Service.MemoryList(context.Background(), history.TriggerManual, command)
+	switch {
+	case errors.Is(err, service.ErrBusy):
+		showBusy()
+	case errors.Is(err, context.Canceled):
+		showReport(command.Title()+" canceled", cleanReport, notify.Warning)
+	case err != nil:
+		Notifier.Error(
+			"Error running memory list command",
+			fmt.Sprintf("Can't run %s, err: %s", command.Title(), err.Error()),
+		)
+	default:
+		UpdateTooltip()
+		showReport(command.Title()+" done", cleanReport, notify.Info)
+	}
+}
+
+// showBusy tells that a clean was not started because another one is running.
+func showBusy() {
+	Notifier.Info("Clean not started", "Another clean is running.")
+}
+
+// showReport shows the summary of a clean as a tray notification with the given severity.
+func showReport(title string, cleanReport *report.CleanReport, severity notify.Severity) {
+	Notifier.Notify(notify.Notification{Severity: severity, Title: title, Message: cleanReport.Summary()})
+}
+
+// handleOpenLog opens the log file with the default text editor, unelevated like the memory graph.
+func handleOpenLog() {
+	if err := windowsapi.ShellOpenUnelevated(LogPath); err != nil {
+		Notifier.Error(
+			"Error opening log",
+			fmt.Sprintf("Can't open the log file, err: %s", err.Error()),
+		)
 	}
 }
 
@@ -65,9 +144,9 @@ func handleAddToStartup() {
 		MenuItems.MStartupAdd.Disable()
 		MenuItems.MStartupRemove.Enable()
 	} else {
-		windowsapi.ShowError(
-			fmt.Sprintf("Can't create startup task, err: %s", err.Error()),
+		Notifier.Error(
 			"Error creating startup task",
+			fmt.Sprintf("Can't create startup task, err: %s", err.Error()),
 		)
 	}
 }
@@ -78,15 +157,18 @@ func handleRemoveFromStartup() {
 		MenuItems.MStartupRemove.Disable()
 		MenuItems.MStartupAdd.Enable()
 	} else {
-		windowsapi.ShowError(
-			fmt.Sprintf("Can't delete startup task, err: %s", err.Error()),
+		Notifier.Error(
 			"Error deleting startup task",
+			fmt.Sprintf("Can't delete startup task, err: %s", err.Error()),
 		)
 	}
 }
 
 // handleQuit handles quitting the application.
 func handleQuit() {
+	if OnQuit != nil {
+		OnQuit()
+	}
 	systray.Quit()
 	os.Exit(0)
 }
@@ -1,3 +1,5 @@
+//go:build windows
+
 // Package tray Description: This file contains the tray package which is responsible for creating the system tray icon and handling the tray menu items.
 package tray
 
@@ -5,8 +7,11 @@ import (
 	_ "embed"
 	"fmt"
 	"github.com/getlantern/systray"
+	"log/slog"
+	"sync/atomic"
+	"windows-ram-cleaner/internal/memlist"
+	"windows-ram-cleaner/internal/trayicon"
 	winstartup "windows-ram-cleaner/internal/win_startup"
-	windowsapi "windows-ram-cleaner/internal/windows_api"
 )
 
 type StartupManagement struct {
@@ -15,14 +20,21 @@ type StartupManagement struct {
 }
 
 type TrayMenuItems struct {
-	MSTDClean       *systray.MenuItem
-	MRAMClean       *systray.MenuItem
-	MRAMCleanForce  *systray.MenuItem
-	MRAMCleanSafe   *systray.MenuItem
-	MStartupOptions *systray.MenuItem
-	MStartupAdd     *systray.MenuItem
-	MStartupRemove  *systray.MenuItem
-	MQuit           *systray.MenuItem
+	MSTDClean        *systray.MenuItem
+	MRAMClean        *systray.MenuItem
+	MRAMCleanForce   *systray.MenuItem
+	MRAMCleanSafe    *systray.MenuItem
+	MMemoryLists     *systray.MenuItem
+	MMemoryListCmds  map[memlist.Command]*systray.MenuItem
+	MCancelClean     *systray.MenuItem
+	MTopProcesses    *systray.MenuItem
+	MTopProcessSlots []*TopProcessSlot
+	MMemoryGraph     *systray.MenuItem
+	MOpenLog         *systray.MenuItem
+	MStartupOptions  *systray.MenuItem
+	MStartupAdd      *systray.MenuItem
+	MStartupRemove   *systray.MenuItem
+	MQuit            *systray.MenuItem
 }
 
 // Embed the icon using go:embed
@@ -33,20 +45,70 @@ var iconData []byte
 // MenuItems stores the menu items for the system tray.
 var MenuItems = TrayMenuItems{}
 
+// LogPath is the log file opened by the Open log item, which is disabled while it is empty.
+var LogPath string
+
+// OnQuit, if not nil, is called when Quit is selected, before the application exits.
+var OnQuit func()
+
 // OnReady initializes the system tray icon and menu items.
 func OnReady() {
 	initializeTrayIcon()
 	initializeMenuItems()
 	checkAndManageStartup()
 	go handleMenuClicks(&MenuItems)
+	for command, item := range MenuItems.MMemoryListCmds {
+		go handleMemoryListClicks(command, item)
+	}
+	for _, slot := range MenuItems.MTopProcessSlots {
+		go handleTopProcessClicks(slot)
+	}
+	go autoRefreshTopProcesses()
 }
 
 // initializeTrayIcon sets the icon and title for the system tray.
+// The embedded icon is shown until UpdateTooltip reports the memory load.
 func initializeTrayIcon() {
 	systray.SetIcon(iconData) // Use the embedded icon
 	systray.SetTitle("Memory Cleaner")
 }
 
+// iconCache holds the generated gauge icons.
+var iconCache trayicon.Cache
+
+// iconLevel identifies the gauge shown, see iconID; zero while the embedded icon is shown.
+var iconLevel atomic.Int32
+
+// iconID returns the non-zero iconLevel value of a gauge level.
+func iconID(level int) int32 {
+	return int32(level - trayicon.Unavailable + 1)
+}
+
+// updateIcon shows the gauge of the memory load, changing the icon only when the level changes.
+func updateIcon(usedPercent float64) {
+	setIconLevel(trayicon.Level(usedPercent))
+}
+
+// showUnavailableIcon shows the grayed out gauge while the memory load is unknown.
+func showUnavailableIcon() {
+	setIconLevel(trayicon.Unavailable)
+}
+
+// setIconLevel shows the gauge of a level unless it is already shown.
+func setIconLevel(level int) {
+	if iconLevel.Swap(iconID(level)) == iconID(level) {
+		return
+	}
+	icon, err := iconCache.Icon(level)
+	if err != nil {
+		// Keep the current icon, the next level may encode
+		slog.Warn("failed to generate tray icon", "level", level, "err", err)
+		iconLevel.Store(0)
+		return
+	}
+	systray.SetIcon(icon)
+}
+
 // initializeMenuItems creates and sets up the menu items.
 func initializeMenuItems() {
 	MenuItems.MSTDClean = systray.AddMenuItem("Clean Standby List", "Clean the standby list")
@@ -56,6 +118,24 @@ func initializeMenuItems() {
 	MenuItems.MRAMCleanSafe = MenuItems.MRAMClean.AddSubMenuItem("Basic Clean", "Basic Clean")
 	MenuItems.MRAMCleanForce = MenuItems.MRAMClean.AddSubMenuItem("Deep Clean", "Thorough Clean")
 
+	// Create a submenu with every memory list command
+	MenuItems.MMemoryLists = systray.AddMenuItem("Memory Lists", "Run a memory list command")
+	MenuItems.MMemoryListCmds = make(map[memlist.Command]*systray.MenuItem, len(memlist.Commands))
+	for _, command := range memlist.Commands {
+		MenuItems.MMemoryListCmds[command] = MenuItems.MMemoryLists.AddSubMenuItem(command.Title(), command.Title())
+	}
+
+	initializeTopProcesses()
+	MenuItems.MMemoryGraph = systray.AddMenuItem("Memory Graph", "Show the memory usage of the last hour")
+	MenuItems.MOpenLog = systray.AddMenuItem("Open log", "Open the log file of the application")
+	if LogPath == "" {
+		MenuItems.MOpenLog.Disable()
+	}
+
+	// Only shown while a clean is running
+	MenuItems.MCancelClean = systray.AddMenuItem("Cancel running clean", "Stop the running clean")
+	MenuItems.MCancelClean.Hide()
+
 	// Create a submenu for startup options
 	MenuItems.MStartupOptions = systray.AddMenuItem("Startup Options", "Manage startup options")
 	MenuItems.MStartupAdd = MenuItems.MStartupOptions.AddSubMenuItem("Add to Startup", "Add the application to startup")
@@ -64,15 +144,38 @@ func initializeMenuItems() {
 	MenuItems.MQuit = systray.AddMenuItem("Quit", "Exit the application")
 }
 
+// SetBusy updates the menu when a clean starts or ends: cleans can't be started while
+// one is running, and the running one can be canceled.
+func SetBusy(busy bool) {
+	// Automatic cleans may start before the menu is created.
+	if MenuItems.MCancelClean == nil {
+		return
+	}
+
+	if busy {
+		MenuItems.MSTDClean.Disable()
+		MenuItems.MRAMClean.Disable()
+		MenuItems.MMemoryLists.Disable()
+		MenuItems.MTopProcesses.Disable()
+		MenuItems.MCancelClean.Show()
+	} else {
+		MenuItems.MCancelClean.Hide()
+		MenuItems.MSTDClean.Enable()
+		MenuItems.MRAMClean.Enable()
+		MenuItems.MMemoryLists.Enable()
+		MenuItems.MTopProcesses.Enable()
+	}
+}
+
 // checkAndManageStartup checks the startup task status and updates the menu items accordingly.
 func checkAndManageStartup() {
 	ex, err := winstartup.IsStartupTaskExists()
 	if err != nil {
 		MenuItems.MStartupAdd.Disable()
 		MenuItems.MStartupRemove.Disable()
-		windowsapi.ShowError(
-			fmt.Sprintf("Can't check startup application status, err: %s", err.Error()),
+		Notifier.Error(
 			"Error checking startup task",
+			fmt.Sprintf("Can't check startup application status, err: %s", err.Error()),
 		)
 	} else {
 		if ex {
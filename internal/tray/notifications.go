@@ -0,0 +1,40 @@
+//go:build windows
+
+// Description: This file contains showing the notifications of the application on the tray icon.
+
+package tray
+
+import (
+	"windows-ram-cleaner/internal/notify"
+	"windows-ram-cleaner/internal/windows_api"
+)
+
+// Notifier shows the notifications of the application as balloons on the tray icon.
+// Repeated notifications are dropped and their rate is limited.
+var Notifier = notify.New(notify.SenderFunc(showNotification), notify.DefaultOptions(), nil)
+
+// showNotification shows n as a balloon, or as a dialog if it is fatal.
+// Errors are shown as a dialog too while there is no tray icon, e.g. during startup and exit,
+// so that they are not lost.
+func showNotification(n notify.Notification) error {
+	if n.Severity < notify.Fatal {
+		err := windowsapi.ShowBalloon(n.Title, n.Message, balloonIcon(n.Severity))
+		if err == nil || n.Severity < notify.Error {
+			return err
+		}
+	}
+	windowsapi.ShowError(n.Message, n.Title)
+	return nil
+}
+
+// balloonIcon returns the balloon icon of a severity.
+func balloonIcon(severity notify.Severity) uint32 {
+	switch severity {
+	case notify.Info:
+		return windowsapi.NiifInfo
+	case notify.Warning:
+		return windowsapi.NiifWarning
+	default:
+		return windowsapi.NiifError
+	}
+}
@@ -1,3 +1,5 @@
+//go:build windows
+
 // Description: This file contains functions for updating the tooltip text of the system tray icon.
 
 package tray
@@ -5,31 +7,103 @@ package tray
 import (
 	"fmt"
 	"github.com/getlantern/systray"
-	"windows-ram-cleaner/internal/windows_api"
+	"log/slog"
+	"time"
+	"windows-ram-cleaner/internal/backoff"
+	"windows-ram-cleaner/internal/graph"
+	"windows-ram-cleaner/internal/memory"
+	"windows-ram-cleaner/internal/sampler"
+	"windows-ram-cleaner/internal/schedule"
+	"windows-ram-cleaner/internal/service"
 )
 
-const (
-	PercentThreshold = 65
-)
+// MemoryProvider is the source of the memory statistics shown in the tooltip.
+var MemoryProvider = memory.NewProvider()
+
+// sampleCapacity keeps the samples of the graph window at the fastest refresh interval.
+const sampleCapacity = int(graph.Window / (500 * time.Millisecond))
+
+// Samples keeps the memory statistics taken by UpdateTooltip for the memory graph.
+var Samples = sampler.New(MemoryProvider, sampleCapacity, nil)
+
+// Service runs the cleans started from the menu and provides the history statistics.
+var Service *service.Service
+
+// Scheduler runs the scheduled cleans, the tooltip shows the next one.
+var Scheduler *schedule.Scheduler
 
-// UpdateTooltip updates the tooltip text of the system tray icon.
-// It retrieves the standby list and free RAM size using the windowsapi package,
+// statsBackoff spaces out the retries while the memory statistics can't be read.
+var statsBackoff = backoff.New(2*time.Second, 5*time.Minute)
+
+// StatsAvailable reports whether the last attempt to read the memory statistics succeeded.
+func StatsAvailable() bool {
+	return statsBackoff.Failures() == 0
+}
+
+// UpdateTooltip updates the tooltip text and the gauge of the system tray icon.
+// It samples the standby list and free RAM size from MemoryProvider into Samples,
 // and formats the tooltip string with the obtained values and the last cleanup timestamps.
 // The formatted tooltip string is then set as the tooltip for the system tray icon.
+// While the statistics can't be read, the tray shows that they are unavailable and
+// sampling is retried less and less often; the failures are logged.
 func UpdateTooltip() {
-	memInfo, err := windowsapi.GetMemoryInfo()
+	now := time.Now()
+	if !statsBackoff.Ready(now) {
+		return
+	}
+	memInfo, err := Samples.Sample()
 	if err != nil {
-		windowsapi.ShowError(
-			fmt.Sprintf("Can't get standby list and free RAM size, err: %s", err.Error()),
-			"Error getting standby list and free RAM size",
-		)
+		delay := statsBackoff.Failure(now)
+		slog.Warn("failed to get memory statistics",
+			"err", err, "failures", statsBackoff.Failures(), "retry_in", delay)
+		showStatsUnavailable(delay)
+		return
+	}
+	if statsBackoff.Success() {
+		slog.Info("memory statistics available again")
 	}
+	updateIcon(memInfo.UsedPercent())
 
-	tooltipStr := fmt.Sprintf(
-		"FreeRAM      : %dMB\nStandby List : %d MB",
-		memInfo.FreeSize/(1024*1024),
-		memInfo.StandbySize/(1024*1024),
-	)
+	// The schedule goes last, it is cut first if the tooltip is too long
+	setTooltip(memoryTooltip(memInfo) + historyTooltip() + scheduleTooltip(now))
+}
 
-	systray.SetTooltip(tooltipStr)
+// setTooltip sets the tooltip, cutting the lines that don't fit.
+func setTooltip(text string) {
+	systray.SetTooltip(fitTooltip(text))
+}
+
+// showStatsUnavailable shows the grayed out gauge and a tooltip telling when sampling is retried,
+// instead of statistics of zero.
+func showStatsUnavailable(retryIn time.Duration) {
+	showUnavailableIcon()
+	setTooltip(fmt.Sprintf("Memory stats unavailable\nRetrying in %s", retryIn) + historyTooltip() + scheduleTooltip(time.Now()))
+}
+
+// scheduleTooltip returns the tooltip line with the next scheduled clean, or an empty string
+// if no clean is scheduled.
+func scheduleTooltip(now time.Time) string {
+	if Scheduler == nil {
+		return ""
+	}
+	job, next, ok := Scheduler.Next()
+	if !ok {
+		return ""
+	}
+	return scheduleLine(job, next, now)
+}
+
+// historyTooltip returns the tooltip lines with today's cleans and the last clean,
+// or an empty string if there is no history.
+func historyTooltip() string {
+	if Service == nil {
+		return ""
+	}
+	stats, err := Service.Stats()
+	if err != nil {
+		// Logged at debug level, the tooltip is refreshed every few seconds
+		slog.Debug("failed to read clean history statistics", "err", err)
+		return ""
+	}
+	return historyLines(stats)
 }
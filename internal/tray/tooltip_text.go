@@ -0,0 +1,70 @@
+// Description: This file contains formatting the tooltip text. It doesn't use systray, so that
+// the length of the tooltip can be tested on every platform.
+
+package tray
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"windows-ram-cleaner/internal/history"
+	"windows-ram-cleaner/internal/memory"
+	"windows-ram-cleaner/internal/report"
+	"windows-ram-cleaner/internal/schedule"
+)
+
+// tooltipLength is the number of UTF-16 characters of a tooltip, without the terminating zero.
+// systray copies longer tooltips without the terminating zero.
+const tooltipLength = 127
+
+// memoryTooltip returns the tooltip lines with the free memory and the standby list.
+func memoryTooltip(s memory.Snapshot) string {
+	text := fmt.Sprintf("Free: %d MB\nStandby: %d MB", s.AvailableSize/(1024*1024), s.StandbySize/(1024*1024))
+	// Low priority standby is safe to drop, high priority holds application data.
+	if s.HasStandbyPriorities() {
+		text += fmt.Sprintf(
+			" (L/N/H %d/%d/%d)",
+			s.StandbyLow()/(1024*1024),
+			s.StandbyNormal()/(1024*1024),
+			s.StandbyHigh()/(1024*1024),
+		)
+	}
+	return text
+}
+
+// historyLines returns the tooltip lines with today's cleans and the last clean,
+// or an empty string if there is no history.
+func historyLines(stats history.Stats) string {
+	if stats.Cleans == 0 {
+		return ""
+	}
+	return fmt.Sprintf(
+		"\nToday: %d cleans, %s\nLast: %s, %s",
+		stats.CleansToday,
+		report.FormatBytes(stats.FreedToday),
+		stats.LastClean.Format("15:04"),
+		report.FormatBytes(stats.LastFreed),
+	)
+}
+
+// scheduleLine returns the tooltip line with the next scheduled clean. The day is shown when it isn't today.
+func scheduleLine(job schedule.Job, next, now time.Time) string {
+	at := next.Format("15:04")
+	if y, m, d := next.Date(); y != now.Year() || m != now.Month() || d != now.Day() {
+		at = next.Format("Mon 15:04")
+	}
+	return fmt.Sprintf("\nNext: %s %s", job, at)
+}
+
+// fitTooltip cuts the last lines of text until it fits in a tooltip.
+func fitTooltip(text string) string {
+	for len(utf16.Encode([]rune(text))) > tooltipLength {
+		i := strings.LastIndexByte(text, '\n')
+		if i < 0 {
+			return string([]rune(text)[:tooltipLength/2])
+		}
+		text = text[:i]
+	}
+	return text
+}
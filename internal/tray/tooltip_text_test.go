@@ -0,0 +1,59 @@
+package tray
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"windows-ram-cleaner/internal/history"
+	"windows-ram-cleaner/internal/memory"
+	"windows-ram-cleaner/internal/schedule"
+)
+
+const mb = 1024 * 1024
+
+func TestTooltipFits(t *testing.T) {
+	now := time.Date(2024, 1, 1, 20, 0, 0, 0, time.Local)
+	// A computer with 16 GB of memory
+	snapshot := memory.Snapshot{AvailableSize: 5432 * mb, StandbySize: 4321 * mb}
+	snapshot.StandbyByPriority = [memory.StandbyPriorities]uint64{1234 * mb, 1000 * mb, 1000 * mb, 200 * mb, 145 * mb, 742 * mb}
+	stats := history.Stats{
+		Cleans:      120,
+		CleansToday: 12,
+		FreedToday:  3500 * mb,
+		LastClean:   now.Add(-time.Hour),
+		LastFreed:   512 * mb,
+	}
+	job := schedule.Job{When: "0 3 * * *", Action: schedule.ActionCleanRAM, Deep: true}
+
+	text := memoryTooltip(snapshot) + historyLines(stats) + scheduleLine(job, now.Add(7*time.Hour), now)
+	if n := len(utf16.Encode([]rune(text))); n > tooltipLength {
+		t.Errorf("tooltip has %d characters, want at most %d:\n%s", n, tooltipLength, text)
+	}
+	for _, line := range []string{"Standby: 4321 MB (L/N/H 1234/2345/742)", "Today: 12 cleans", "Last: 19:00", "Next: deep RAM Tue 03:00"} {
+		if !strings.Contains(text, line) {
+			t.Errorf("tooltip doesn't contain %q:\n%s", line, text)
+		}
+	}
+}
+
+func TestFitTooltip(t *testing.T) {
+	long := strings.Repeat("x", 100)
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{"short", "Free: 1 MB\nNext: RAM 03:00", "Free: 1 MB\nNext: RAM 03:00"},
+		{"last lines cut", long + "\n" + long + "\n" + long, long},
+		{"single long line", strings.Repeat("x", 200), strings.Repeat("x", tooltipLength/2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := fitTooltip(tt.text); result != tt.expected {
+				t.Errorf("fitTooltip() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
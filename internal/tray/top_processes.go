@@ -0,0 +1,134 @@
+//go:build windows
+
+// Description: This file contains the submenu with the processes using the most memory.
+
+package tray
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/getlantern/systray"
+
+	"windows-ram-cleaner/internal/history"
+	"windows-ram-cleaner/internal/inventory"
+	"windows-ram-cleaner/internal/notify"
+	"windows-ram-cleaner/internal/report"
+	"windows-ram-cleaner/internal/service"
+	"windows-ram-cleaner/internal/trim"
+	"windows-ram-cleaner/internal/windows_api"
+)
+
+// topProcessCount is the number of processes shown in the Top Processes submenu.
+const topProcessCount = 10
+
+// topProcessesInterval is how often the Top Processes submenu is refreshed.
+const topProcessesInterval = 10 * time.Second
+
+// TopProcessSlot is one process of the Top Processes submenu, with its trim actions.
+type TopProcessSlot struct {
+	Item      *systray.MenuItem
+	MTrim     *systray.MenuItem
+	MTrimTree *systray.MenuItem
+	mu        sync.Mutex
+	process   inventory.Process // The process shown by Item
+}
+
+// initializeTopProcesses creates the Top Processes submenu. Menu items can't be removed,
+// so the slots are created once, filled by refreshTopProcesses and hidden while unused.
+func initializeTopProcesses() {
+	MenuItems.MTopProcesses = systray.AddMenuItem("Top Processes", "Processes using the most memory")
+	MenuItems.MTopProcessSlots = make([]*TopProcessSlot, topProcessCount)
+	for i := range MenuItems.MTopProcessSlots {
+		item := MenuItems.MTopProcesses.AddSubMenuItem("", "")
+		MenuItems.MTopProcessSlots[i] = &TopProcessSlot{
+			Item:      item,
+			MTrim:     item.AddSubMenuItem("Trim", "Trim the working set of this process"),
+			MTrimTree: item.AddSubMenuItem("Trim with children", "Trim the working sets of this process and its children"),
+		}
+		item.Hide()
+	}
+}
+
+// autoRefreshTopProcesses keeps the Top Processes submenu up to date.
+func autoRefreshTopProcesses() {
+	for {
+		refreshTopProcesses()
+		time.Sleep(topProcessesInterval)
+	}
+}
+
+// refreshTopProcesses fills the slots of the Top Processes submenu with the largest working sets.
+func refreshTopProcesses() {
+	processes, err := windowsapi.ProcessInventory()
+	if err != nil {
+		// The submenu keeps the last known processes, the next refresh may succeed.
+		slog.Warn("failed to list processes for the top processes menu", "err", err)
+		return
+	}
+
+	top := inventory.Top(processes, topProcessCount, inventory.SortWorkingSet)
+	for i, slot := range MenuItems.MTopProcessSlots {
+		if i >= len(top) {
+			slot.Item.Hide()
+			continue
+		}
+		p := top[i]
+		slot.mu.Lock()
+		slot.process = p
+		slot.mu.Unlock()
+		slot.Item.SetTitle(fmt.Sprintf("%s (%d) - %s", p.Name, p.PID, report.FormatBytes(p.WorkingSet)))
+		slot.Item.SetTooltip(fmt.Sprintf("Private: %s, page faults: %d, %s", report.FormatBytes(p.PrivateBytes), p.PageFaults, p.Flags()))
+		slot.Item.Show()
+	}
+}
+
+// handleTopProcessClicks trims the process shown by slot every time one of its actions is clicked.
+func handleTopProcessClicks(slot *TopProcessSlot) {
+	for {
+		select {
+		case <-slot.MTrim.ClickedCh:
+			go handleTrim(slot.target(false))
+		case <-slot.MTrimTree.ClickedCh:
+			go handleTrim(slot.target(true))
+		}
+	}
+}
+
+// target returns the target trimming the process shown by the slot.
+func (s *TopProcessSlot) target(tree bool) inventory.Target {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return inventory.Target{PID: s.process.PID, Tree: tree}
+}
+
+// handleTrim trims the processes selected by target.
+func handleTrim(target inventory.Target) {
+	cleanReport, err := Service.Trim(context.Background(), history.TriggerManual, target)
+	switch {
+	case errors.Is(err, service.ErrBusy):
+		showBusy()
+	case errors.Is(err, inventory.ErrNoProcess):
+		// The process exited since the menu was refreshed
+		Notifier.Info("Process not trimmed", fmt.Sprintf("%s is no longer running.", target))
+	case errors.Is(err, context.Canceled):
+		UpdateTooltip()
+		showReport(target.String()+" trim canceled", cleanReport, notify.Warning)
+	case trim.IsPartial(err):
+		UpdateTooltip()
+		showReport(target.String()+" trimmed with failures", cleanReport, notify.Warning)
+	case err != nil:
+		Notifier.Error(
+			"Error trimming process",
+			fmt.Sprintf("Can't trim %s, err: %s", target, err.Error()),
+		)
+	default:
+		UpdateTooltip()
+		showReport(target.String()+" trimmed", cleanReport, notify.Info)
+	}
+	refreshTopProcesses()
+}
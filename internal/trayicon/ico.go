@@ -0,0 +1,96 @@
+// Description: This file contains the ICO encoding of generated images.
+
+package trayicon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+)
+
+// EncodeICO encodes images as an ICO file with one 32-bit BMP entry per image.
+// Images must be square and at most 256 pixels wide.
+func EncodeICO(images ...*image.RGBA) ([]byte, error) {
+	if len(images) == 0 {
+		return nil, errors.New("at least one image is required")
+	}
+
+	var dibs [][]byte
+	for _, img := range images {
+		w, h := img.Bounds().Dx(), img.Bounds().Dy()
+		if w != h || w == 0 || w > 256 {
+			return nil, errors.New("images must be square with a size between 1 and 256")
+		}
+		dibs = append(dibs, encodeDIB(img))
+	}
+
+	var buf bytes.Buffer
+	// ICONDIR: reserved, type 1 (icon), number of images
+	write(&buf, uint16(0), uint16(1), uint16(len(images)))
+
+	offset := 6 + 16*len(images)
+	for i, img := range images {
+		size := img.Bounds().Dx()
+		// ICONDIRENTRY: width and height (0 means 256), no palette, reserved, 1 plane, 32 bpp
+		write(&buf, uint8(size), uint8(size), uint8(0), uint8(0), uint16(1), uint16(32),
+			uint32(len(dibs[i])), uint32(offset))
+		offset += len(dibs[i])
+	}
+	for _, dib := range dibs {
+		buf.Write(dib)
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeDIB encodes img as a BITMAPINFOHEADER followed by the BGRA pixels and the AND mask,
+// both bottom-up. The height in the header counts both the pixels and the mask.
+func encodeDIB(img *image.RGBA) []byte {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	maskStride := (w + 31) / 32 * 4 // Rows of 1 bit per pixel padded to 4 bytes
+
+	var buf bytes.Buffer
+	write(&buf, uint32(40), int32(w), int32(2*h), uint16(1), uint16(32), uint32(0),
+		uint32(w*h*4+maskStride*h), int32(0), int32(0), uint32(0), uint32(0))
+
+	for y := b.Max.Y - 1; y >= b.Min.Y; y-- {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			i := img.PixOffset(x, y)
+			p := img.Pix[i : i+4]
+			r, g, bl, a := unpremultiply(p[0], p[1], p[2], p[3])
+			buf.Write([]byte{bl, g, r, a})
+		}
+	}
+
+	// The alpha channel decides the transparency, the mask marks transparent pixels for old systems.
+	mask := make([]byte, maskStride)
+	for y := b.Max.Y - 1; y >= b.Min.Y; y-- {
+		clear(mask)
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if img.Pix[img.PixOffset(x, y)+3] == 0 {
+				col := x - b.Min.X
+				mask[col/8] |= 0x80 >> (col % 8)
+			}
+		}
+		buf.Write(mask)
+	}
+	return buf.Bytes()
+}
+
+// unpremultiply converts an alpha-premultiplied image.RGBA pixel to the straight alpha used by ICO files.
+func unpremultiply(r, g, b, a uint8) (uint8, uint8, uint8, uint8) {
+	if a == 0 || a == 0xff {
+		return r, g, b, a
+	}
+	scale := func(c uint8) uint8 { return uint8(min(uint32(c)*0xff/uint32(a), 0xff)) }
+	return scale(r), scale(g), scale(b), a
+}
+
+// write appends the little-endian encoding of values to buf.
+func write(buf *bytes.Buffer, values ...any) {
+	for _, v := range values {
+		// Writing fixed-size values to a bytes.Buffer can't fail
+		_ = binary.Write(buf, binary.LittleEndian, v)
+	}
+}
@@ -0,0 +1,106 @@
+package trayicon
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestEncodeICO(t *testing.T) {
+	// A 2x2 image: opaque red at the top left, transparent at the bottom right
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.SetRGBA(0, 0, color.RGBA{0xff, 0, 0, 0xff})
+	img.SetRGBA(1, 0, color.RGBA{0, 0xff, 0, 0xff})
+	img.SetRGBA(0, 1, color.RGBA{0, 0, 0xff, 0xff})
+
+	data, err := EncodeICO(img)
+	if err != nil {
+		t.Fatalf("EncodeICO() error = %v", err)
+	}
+
+	le := binary.LittleEndian
+	if le.Uint16(data[0:]) != 0 || le.Uint16(data[2:]) != 1 || le.Uint16(data[4:]) != 1 {
+		t.Fatalf("ICONDIR = % x", data[:6])
+	}
+	entry := data[6:22]
+	if entry[0] != 2 || entry[1] != 2 || le.Uint16(entry[6:]) != 32 {
+		t.Errorf("ICONDIRENTRY = % x", entry)
+	}
+	size, offset := le.Uint32(entry[8:]), le.Uint32(entry[12:])
+	// Header, 4 pixels of 4 bytes and 2 mask rows of 4 bytes
+	if offset != 22 || size != 40+16+8 || len(data) != int(offset+size) {
+		t.Fatalf("entry size %d at offset %d, file is %d bytes", size, offset, len(data))
+	}
+
+	dib := data[offset:]
+	if le.Uint32(dib[0:]) != 40 || int32(le.Uint32(dib[4:])) != 2 || int32(le.Uint32(dib[8:])) != 4 || le.Uint16(dib[14:]) != 32 {
+		t.Errorf("BITMAPINFOHEADER = % x", dib[:40])
+	}
+
+	// Rows are stored bottom-up in BGRA order
+	pixels := dib[40:56]
+	expected := []byte{
+		0xff, 0, 0, 0xff, 0, 0, 0, 0, // Bottom row: blue, transparent
+		0, 0, 0xff, 0xff, 0, 0xff, 0, 0xff, // Top row: red, green
+	}
+	for i := range expected {
+		if pixels[i] != expected[i] {
+			t.Fatalf("pixels = % x, want % x", pixels, expected)
+		}
+	}
+
+	mask := dib[56:]
+	if mask[0] != 0x40 || mask[4] != 0 {
+		t.Errorf("mask = % x, want the bottom right pixel transparent", mask)
+	}
+}
+
+func TestEncodeICOMultipleImages(t *testing.T) {
+	data, err := EncodeICO(image.NewRGBA(image.Rect(0, 0, 16, 16)), image.NewRGBA(image.Rect(0, 0, 256, 256)))
+	if err != nil {
+		t.Fatalf("EncodeICO() error = %v", err)
+	}
+
+	le := binary.LittleEndian
+	if le.Uint16(data[4:]) != 2 {
+		t.Fatalf("image count = %d, want 2", le.Uint16(data[4:]))
+	}
+	first, second := data[6:22], data[22:38]
+	if second[0] != 0 {
+		t.Errorf("256 pixel width = %d, want 0", second[0])
+	}
+	if le.Uint32(second[12:]) != le.Uint32(first[12:])+le.Uint32(first[8:]) {
+		t.Errorf("second image does not follow the first")
+	}
+	// 16 pixel rows need 2 bytes of mask, padded to 4
+	if le.Uint32(first[8:]) != 40+16*16*4+16*4 {
+		t.Errorf("first image size = %d", le.Uint32(first[8:]))
+	}
+}
+
+func TestEncodeICOErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		images []*image.RGBA
+	}{
+		{name: "no image", images: nil},
+		{name: "not square", images: []*image.RGBA{image.NewRGBA(image.Rect(0, 0, 16, 8))}},
+		{name: "too large", images: []*image.RGBA{image.NewRGBA(image.Rect(0, 0, 512, 512))}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := EncodeICO(tt.images...); err == nil {
+				t.Error("EncodeICO() expected an error")
+			}
+		})
+	}
+}
+
+func TestUnpremultiply(t *testing.T) {
+	// Half transparent white is stored premultiplied as 0x80 0x80 0x80 0x80
+	if r, g, b, a := unpremultiply(0x80, 0x80, 0x80, 0x80); r != 0xff || g != 0xff || b != 0xff || a != 0x80 {
+		t.Errorf("unpremultiply() = %x %x %x %x", r, g, b, a)
+	}
+}
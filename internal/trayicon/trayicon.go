@@ -0,0 +1,125 @@
+// Package trayicon Description: This package draws the tray icon showing the memory load as a gauge.
+// The icon is generated and encoded as ICO in pure Go, one image per load level, so it only changes
+// when the load crosses a level.
+package trayicon
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sync"
+)
+
+// Levels is the number of fill steps of the gauge; level 0 is empty and level Levels is full.
+const Levels = 10
+
+// Unavailable is the level shown when the memory load is unknown: an empty, grayed out gauge.
+const Unavailable = -1
+
+// Sizes are the image sizes stored in every icon, for the notification area at 100% and 200% scaling.
+var Sizes = []int{16, 32}
+
+// Load thresholds in percent at which the gauge turns yellow and red.
+const (
+	WarningPercent  = 60
+	CriticalPercent = 80
+)
+
+var (
+	borderColor     = color.RGBA{0x30, 0x30, 0x30, 0xff}
+	backgroundColor = color.RGBA{0xf0, 0xf0, 0xf0, 0xff}
+	normalColor     = color.RGBA{0x2e, 0x7d, 0x32, 0xff}
+	warningColor    = color.RGBA{0xf9, 0xa8, 0x25, 0xff}
+	criticalColor   = color.RGBA{0xc6, 0x28, 0x28, 0xff}
+	grayedColor     = color.RGBA{0x90, 0x90, 0x90, 0xff}
+)
+
+// Level quantizes a memory load in percent to a gauge level between 0 and Levels.
+func Level(usedPercent float64) int {
+	level := int(math.Round(usedPercent * Levels / 100))
+	return min(max(level, 0), Levels)
+}
+
+// Color returns the fill color of a gauge level.
+func Color(level int) color.RGBA {
+	switch percent := level * 100 / Levels; {
+	case level == Unavailable:
+		return grayedColor
+	case percent >= CriticalPercent:
+		return criticalColor
+	case percent >= WarningPercent:
+		return warningColor
+	default:
+		return normalColor
+	}
+}
+
+// Render draws the gauge of a level as a size x size image: a bordered box filled from the bottom.
+// The Unavailable level has a gray border and no fill.
+func Render(level, size int) *image.RGBA {
+	level = min(max(level, Unavailable), Levels)
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	frame := borderColor
+	if level == Unavailable {
+		frame = grayedColor
+	}
+
+	border := max(size/16, 1)
+	// The box is narrower than the icon, like a memory module standing upright
+	left, right := size/5, size-size/5
+	for y := 0; y < size; y++ {
+		for x := left; x < right; x++ {
+			if x < left+border || x >= right-border || y < border || y >= size-border {
+				img.SetRGBA(x, y, frame)
+			} else {
+				img.SetRGBA(x, y, backgroundColor)
+			}
+		}
+	}
+
+	inner := size - 2*border
+	filled := inner * max(level, 0) / Levels
+	fill := Color(level)
+	for y := size - border - filled; y < size-border; y++ {
+		for x := left + border; x < right-border; x++ {
+			img.SetRGBA(x, y, fill)
+		}
+	}
+	return img
+}
+
+// Icon returns the ICO file of a gauge level, with one image for each of Sizes.
+func Icon(level int) ([]byte, error) {
+	images := make([]*image.RGBA, len(Sizes))
+	for i, size := range Sizes {
+		images[i] = Render(level, size)
+	}
+	return EncodeICO(images...)
+}
+
+// Cache keeps the ICO file of every level, so that each is encoded once.
+// It is safe for concurrent use.
+type Cache struct {
+	mu    sync.Mutex
+	icons map[int][]byte
+}
+
+// Icon returns the ICO file of a gauge level.
+func (c *Cache) Icon(level int) ([]byte, error) {
+	level = min(max(level, Unavailable), Levels)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if icon, ok := c.icons[level]; ok {
+		return icon, nil
+	}
+	icon, err := Icon(level)
+	if err != nil {
+		return nil, err
+	}
+	if c.icons == nil {
+		c.icons = make(map[int][]byte)
+	}
+	c.icons[level] = icon
+	return icon, nil
+}
@@ -0,0 +1,125 @@
+package trayicon
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLevel(t *testing.T) {
+	tests := []struct {
+		percent  float64
+		expected int
+	}{
+		{percent: -5, expected: 0},
+		{percent: 0, expected: 0},
+		{percent: 4.9, expected: 0},
+		{percent: 5, expected: 1},
+		{percent: 64, expected: 6},
+		{percent: 100, expected: 10},
+		{percent: 130, expected: 10},
+	}
+
+	for _, tt := range tests {
+		if level := Level(tt.percent); level != tt.expected {
+			t.Errorf("Level(%v) = %d, want %d", tt.percent, level, tt.expected)
+		}
+	}
+}
+
+func TestColor(t *testing.T) {
+	tests := []struct {
+		level    int
+		expected string
+	}{
+		{level: 0, expected: "normal"},
+		{level: 5, expected: "normal"},
+		{level: 6, expected: "warning"},
+		{level: 7, expected: "warning"},
+		{level: 8, expected: "critical"},
+		{level: 10, expected: "critical"},
+	}
+	names := map[string]string{
+		string([]byte{normalColor.R, normalColor.G, normalColor.B}):       "normal",
+		string([]byte{warningColor.R, warningColor.G, warningColor.B}):    "warning",
+		string([]byte{criticalColor.R, criticalColor.G, criticalColor.B}): "critical",
+	}
+
+	for _, tt := range tests {
+		c := Color(tt.level)
+		if name := names[string([]byte{c.R, c.G, c.B})]; name != tt.expected {
+			t.Errorf("Color(%d) = %s, want %s", tt.level, name, tt.expected)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		level      int
+		filledRows int
+	}{
+		{level: 0, filledRows: 0},
+		{level: 5, filledRows: 14},
+		{level: 10, filledRows: 28},
+	}
+
+	for _, tt := range tests {
+		img := Render(tt.level, 32)
+		fill := Color(tt.level)
+
+		// Count the filled rows in the middle column: the border is 2 pixels, leaving 28 rows
+		rows := 0
+		for y := 0; y < 32; y++ {
+			if img.RGBAAt(16, y) == fill {
+				rows++
+			}
+		}
+		if rows != tt.filledRows {
+			t.Errorf("Render(%d) fills %d rows, want %d", tt.level, rows, tt.filledRows)
+		}
+		if img.RGBAAt(0, 16).A != 0 {
+			t.Errorf("Render(%d) is not transparent beside the gauge", tt.level)
+		}
+		if img.RGBAAt(16, 0) != borderColor || img.RGBAAt(16, 31) != borderColor {
+			t.Errorf("Render(%d) has no border", tt.level)
+		}
+	}
+}
+
+func TestRenderUnavailable(t *testing.T) {
+	img := Render(Unavailable, 32)
+	if img.RGBAAt(16, 0) != grayedColor || img.RGBAAt(16, 31) != grayedColor {
+		t.Errorf("Render(Unavailable) border = %v, want gray", img.RGBAAt(16, 0))
+	}
+	if img.RGBAAt(16, 29) != backgroundColor {
+		t.Errorf("Render(Unavailable) is filled: %v", img.RGBAAt(16, 29))
+	}
+}
+
+func TestCache(t *testing.T) {
+	var c Cache
+	first, err := c.Icon(3)
+	if err != nil {
+		t.Fatalf("Icon() error = %v", err)
+	}
+	second, _ := c.Icon(3)
+	if &first[0] != &second[0] {
+		t.Error("Icon() encoded the same level twice")
+	}
+
+	other, _ := c.Icon(9)
+	if bytes.Equal(first, other) {
+		t.Error("different levels have the same icon")
+	}
+	if clamped, _ := c.Icon(42); !bytes.Equal(clamped, mustIcon(t, Levels)) {
+		t.Error("Icon() does not clamp the level")
+	}
+}
+
+func mustIcon(t *testing.T, level int) []byte {
+	t.Helper()
+	icon, err := Icon(level)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return icon
+}
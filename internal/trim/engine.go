@@ -0,0 +1,210 @@
+// Description: This file contains the worker pool trimming the working sets of processes.
+
+package trim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Process is a process to trim.
+type Process struct {
+	PID  uint32
+	Name string
+}
+
+// Enumerator lists the processes to trim, i.e. the ones allowed by the process rules.
+type Enumerator interface {
+	Processes() ([]Process, error)
+}
+
+// EnumeratorFunc adapts a function to the Enumerator interface.
+type EnumeratorFunc func() ([]Process, error)
+
+func (f EnumeratorFunc) Processes() ([]Process, error) { return f() }
+
+// Trimmer trims the working set of one process and returns the working set reduction.
+// It should return early when ctx is done. A failure may be returned as a *ProcessError
+// to name the failed operation, a process that can't be trimmed by design as a *SkipError.
+type Trimmer interface {
+	Trim(ctx context.Context, p Process) (uint64, error)
+}
+
+// TrimmerFunc adapts a function to the Trimmer interface.
+type TrimmerFunc func(ctx context.Context, p Process) (uint64, error)
+
+func (f TrimmerFunc) Trim(ctx context.Context, p Process) (uint64, error) { return f(ctx, p) }
+
+// Options configure a trimming pass.
+type Options struct {
+	// Concurrency is the number of processes trimmed at the same time, at least one.
+	Concurrency int
+	// Timeout is how long one process may take. A trim that doesn't return in time is
+	// recorded as failed and abandoned: a blocked system call can't be interrupted.
+	// Zero means no timeout.
+	Timeout time.Duration
+	// Interval is the average time between starting two trims. Zero disables pacing.
+	Interval time.Duration
+	// Burst is the number of trims that may start without pacing. It defaults to Concurrency.
+	Burst int
+}
+
+// DefaultOptions returns the options used when none are configured.
+func DefaultOptions() Options {
+	return Options{
+		Concurrency: 4,
+		Timeout:     5 * time.Second,
+		Interval:    2 * time.Millisecond,
+	}
+}
+
+// normalized returns o with the missing values filled in.
+func (o Options) normalized() Options {
+	o.Concurrency = max(o.Concurrency, 1)
+	if o.Burst <= 0 {
+		o.Burst = o.Concurrency
+	}
+	return o
+}
+
+// Result is the outcome of trimming one process.
+type Result struct {
+	Process  Process
+	Freed    uint64
+	Duration time.Duration
+	Err      *ProcessError
+	// Skipped is why the process was not trimmed, empty if it was attempted.
+	Skipped string
+}
+
+// Run trims every process listed by e with t and returns the total working set reduction.
+// onResult, if not nil, is called for every attempted process from a single goroutine, in
+// completion order. Processes are started in the order of e.
+//
+// Every process is attempted even if some fail: the failures are returned as *Errors.
+// When ctx is canceled no new process is started and the returned error wraps ctx.Err().
+func Run(ctx context.Context, e Enumerator, t Trimmer, opts Options, onResult func(Result)) (uint64, error) {
+	processes, err := e.Processes()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list processes: %v", err)
+	}
+
+	opts = opts.normalized()
+	jobs := make(chan Process)
+	results := make(chan Result)
+
+	go func() {
+		defer close(jobs)
+		pacing := newLimiter(opts.Interval, opts.Burst, time.Now)
+		for _, p := range processes {
+			if pacing.wait(ctx) != nil {
+				return
+			}
+			select {
+			case jobs <- p:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var workers sync.WaitGroup
+	for i := 0; i < opts.Concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for p := range jobs {
+				// A process handed over just before the cancellation is not started.
+				if ctx.Err() != nil {
+					continue
+				}
+				results <- trimOne(ctx, t, p, opts.Timeout)
+			}
+		}()
+	}
+	go func() {
+		workers.Wait()
+		close(results)
+	}()
+
+	var freed uint64
+	var finished int
+	errs := Errors{}
+	for r := range results {
+		finished++
+		if r.Skipped == "" {
+			errs.Attempted++
+		}
+		freed += r.Freed
+		if r.Err != nil {
+			errs.Failures = append(errs.Failures, r.Err)
+		}
+		if onResult != nil {
+			onResult(r)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return freed, fmt.Errorf("trimming canceled after %d of %d processes: %w", finished, len(processes), err)
+	}
+	return freed, errs.Err()
+}
+
+// trimOne trims p with t, giving up after timeout. When ctx is canceled it waits for t,
+// which returns early, so that no trim outlives Run.
+func trimOne(ctx context.Context, t Trimmer, p Process, timeout time.Duration) Result {
+	start := time.Now()
+	trimCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		trimCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	done := make(chan Result, 1)
+	go func() {
+		freed, err := t.Trim(trimCtx, p)
+		var skip *SkipError
+		if errors.As(err, &skip) {
+			done <- Result{Process: p, Skipped: skip.Reason}
+			return
+		}
+		done <- Result{Process: p, Freed: freed, Err: processError(p, err)}
+	}()
+
+	var r Result
+	select {
+	case r = <-done:
+	case <-trimCtx.Done():
+		if ctx.Err() != nil {
+			r = <-done
+			break
+		}
+		// Only the per-process timeout abandons the trim, unless it just finished.
+		select {
+		case r = <-done:
+		default:
+			r = Result{Process: p, Err: processError(p, trimCtx.Err())}
+		}
+	}
+	r.Duration = time.Since(start)
+	return r
+}
+
+// processError converts an error of a Trimmer to a *ProcessError for p, nil if err is nil.
+func processError(p Process, err error) *ProcessError {
+	if err == nil {
+		return nil
+	}
+
+	var processErr *ProcessError
+	if errors.As(err, &processErr) {
+		e := *processErr
+		e.PID, e.Name = p.PID, p.Name
+		return &e
+	}
+	return &ProcessError{PID: p.PID, Name: p.Name, Op: "trim", Err: err}
+}
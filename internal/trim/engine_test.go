@@ -0,0 +1,256 @@
+package trim
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func processes(n int) EnumeratorFunc {
+	return func() ([]Process, error) {
+		list := make([]Process, n)
+		for i := range list {
+			list[i] = Process{PID: uint32(i + 1), Name: "p.exe"}
+		}
+		return list, nil
+	}
+}
+
+// fastOptions disables pacing and timeouts so that tests only depend on the trimmer.
+func fastOptions(concurrency int) Options {
+	return Options{Concurrency: concurrency}
+}
+
+func TestRunTrimsEveryProcess(t *testing.T) {
+	var order []uint32
+	trimmer := TrimmerFunc(func(ctx context.Context, p Process) (uint64, error) {
+		return 100, nil
+	})
+
+	freed, err := Run(context.Background(), processes(5), trimmer, fastOptions(1), func(r Result) {
+		order = append(order, r.Process.PID)
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if freed != 500 {
+		t.Errorf("Run() freed = %d, want 500", freed)
+	}
+	for i, pid := range order {
+		if pid != uint32(i+1) {
+			t.Fatalf("results = %v, want processes in enumeration order with one worker", order)
+		}
+	}
+	if len(order) != 5 {
+		t.Errorf("got %d results, want 5", len(order))
+	}
+}
+
+func TestRunConcurrency(t *testing.T) {
+	const concurrency = 3
+	var active, peak atomic.Int32
+	release := make(chan struct{})
+	trimmer := TrimmerFunc(func(ctx context.Context, p Process) (uint64, error) {
+		n := active.Add(1)
+		for {
+			old := peak.Load()
+			if n <= old || peak.CompareAndSwap(old, n) {
+				break
+			}
+		}
+		<-release
+		active.Add(-1)
+		return 1, nil
+	})
+
+	done := make(chan uint64)
+	go func() {
+		freed, _ := Run(context.Background(), processes(10), trimmer, fastOptions(concurrency), nil)
+		done <- freed
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for active.Load() < concurrency {
+		if time.Now().After(deadline) {
+			t.Fatalf("only %d trims started, want %d at the same time", active.Load(), concurrency)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+
+	if freed := <-done; freed != 10 {
+		t.Errorf("Run() freed = %d, want 10", freed)
+	}
+	if peak.Load() != concurrency {
+		t.Errorf("peak concurrency = %d, want %d", peak.Load(), concurrency)
+	}
+}
+
+func TestRunContinuesAfterFailures(t *testing.T) {
+	errDenied := errors.New("access denied")
+	trimmer := TrimmerFunc(func(ctx context.Context, p Process) (uint64, error) {
+		switch p.PID {
+		case 2:
+			return 0, &ProcessError{Op: "open process", Err: errDenied}
+		case 4:
+			return 0, errors.New("invalid handle")
+		}
+		return 10, nil
+	})
+
+	freed, err := Run(context.Background(), processes(5), trimmer, fastOptions(2), nil)
+	if freed != 30 {
+		t.Errorf("Run() freed = %d, want 30", freed)
+	}
+
+	var errs *Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Run() error = %v, want *Errors", err)
+	}
+	if errs.Attempted != 5 || len(errs.Failures) != 2 {
+		t.Errorf("Run() attempted %d with %d failures, want 5 with 2", errs.Attempted, len(errs.Failures))
+	}
+	if !errors.Is(err, errDenied) {
+		t.Errorf("Run() error does not wrap the failure of a process")
+	}
+	for _, f := range errs.Failures {
+		if f.PID == 2 && f.Op != "open process" || f.PID == 4 && f.Op != "trim" || f.Name != "p.exe" {
+			t.Errorf("failure = %+v, unexpected process or operation", f)
+		}
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	trimmer := TrimmerFunc(func(ctx context.Context, p Process) (uint64, error) {
+		if p.PID == 1 {
+			<-block // A system call that ignores the context
+		}
+		return 1, nil
+	})
+
+	opts := Options{Concurrency: 1, Timeout: 20 * time.Millisecond}
+	freed, err := Run(context.Background(), processes(3), trimmer, opts, nil)
+	if freed != 2 {
+		t.Errorf("Run() freed = %d, want 2", freed)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) || !IsPartial(err) {
+		t.Errorf("Run() error = %v, want a partial failure with a timeout", err)
+	}
+}
+
+func TestRunCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var attempted atomic.Int32
+	trimmer := TrimmerFunc(func(ctx context.Context, p Process) (uint64, error) {
+		attempted.Add(1)
+		return 1, nil
+	})
+
+	_, err := Run(ctx, processes(100), trimmer, fastOptions(1), func(r Result) {
+		if r.Process.PID == 3 {
+			cancel()
+		}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run() error = %v, want context.Canceled", err)
+	}
+	if IsPartial(err) {
+		t.Errorf("IsPartial() = true for a canceled pass")
+	}
+	if n := attempted.Load(); n >= 100 {
+		t.Errorf("attempted %d processes, want the pass to stop early", n)
+	}
+}
+
+func TestRunCanceledWaitsForTrims(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var returned atomic.Bool
+	trimmer := TrimmerFunc(func(ctx context.Context, p Process) (uint64, error) {
+		cancel()
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond) // Finishing the system call in progress
+		returned.Store(true)
+		return 1, nil
+	})
+
+	var failed bool
+	freed, err := Run(ctx, processes(1), trimmer, Options{Concurrency: 1, Timeout: time.Minute}, func(r Result) {
+		failed = r.Err != nil
+	})
+	if !returned.Load() {
+		t.Errorf("Run() returned before the trim")
+	}
+	if freed != 1 || failed {
+		t.Errorf("Run() freed = %d, failed = %v, want the finished trim counted", freed, failed)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestRunPacing(t *testing.T) {
+	trimmer := TrimmerFunc(func(ctx context.Context, p Process) (uint64, error) { return 1, nil })
+	opts := Options{Concurrency: 4, Interval: 10 * time.Millisecond, Burst: 1}
+
+	start := time.Now()
+	if _, err := Run(context.Background(), processes(6), trimmer, opts, nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Run() took %v, want at least 50ms for 6 trims paced at 10ms", elapsed)
+	}
+}
+
+func TestRunEnumeratorError(t *testing.T) {
+	enumerator := EnumeratorFunc(func() ([]Process, error) { return nil, errors.New("snapshot failed") })
+	trimmer := TrimmerFunc(func(ctx context.Context, p Process) (uint64, error) {
+		t.Error("Trim() called without processes")
+		return 0, nil
+	})
+
+	if _, err := Run(context.Background(), enumerator, trimmer, fastOptions(1), nil); err == nil || IsPartial(err) {
+		t.Errorf("Run() error = %v, want the enumeration error", err)
+	}
+}
+
+func TestRunSkippedProcesses(t *testing.T) {
+	trimmer := TrimmerFunc(func(ctx context.Context, p Process) (uint64, error) {
+		switch p.PID {
+		case 1:
+			return 0, Skip("protected process")
+		case 2:
+			return 0, &ProcessError{Op: "empty working set", Err: errors.New("invalid handle")}
+		}
+		return 10, nil
+	})
+
+	var skipped []string
+	freed, err := Run(context.Background(), processes(4), trimmer, fastOptions(1), func(r Result) {
+		if r.Skipped != "" {
+			if r.Err != nil {
+				t.Errorf("skipped result has an error: %+v", r)
+			}
+			skipped = append(skipped, r.Skipped)
+		}
+	})
+	if freed != 20 {
+		t.Errorf("Run() freed = %d, want 20", freed)
+	}
+	if len(skipped) != 1 || skipped[0] != "protected process" {
+		t.Errorf("skipped = %v, want the protected process", skipped)
+	}
+
+	var errs *Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Run() error = %v, want *Errors", err)
+	}
+	if errs.Attempted != 3 || len(errs.Failures) != 1 || errs.Failures[0].PID != 2 {
+		t.Errorf("Run() attempted %d with failures %v, want 3 with the failure of process 2", errs.Attempted, errs.Failures)
+	}
+}
@@ -0,0 +1,97 @@
+// Package trim Description: This package contains the platform-neutral parts of working set trimming.
+package trim
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// maxListedErrors is how many process failures Errors.Error lists before summarizing the rest.
+const maxListedErrors = 3
+
+// ProcessError is the failure of one operation on one process.
+type ProcessError struct {
+	PID  uint32
+	Name string
+	Op   string // The failed operation, e.g. "open", "empty working set", "close"
+	Err  error
+}
+
+func (e *ProcessError) Error() string {
+	return fmt.Sprintf("%s (%d): %s: %v", e.Name, e.PID, e.Op, e.Err)
+}
+
+func (e *ProcessError) Unwrap() error {
+	return e.Err
+}
+
+// SkipError is returned by a Trimmer for a process it can't trim by design, e.g. a protected
+// process that can't be opened. The process is reported as skipped, not as a failure.
+type SkipError struct {
+	Reason string
+}
+
+func (e *SkipError) Error() string {
+	return "skipped: " + e.Reason
+}
+
+// Skip returns a *SkipError with reason.
+func Skip(reason string) error {
+	return &SkipError{Reason: reason}
+}
+
+// Errors aggregates the per-process failures of a trimming pass that attempted every eligible process.
+// Skipped processes are not attempted.
+type Errors struct {
+	Attempted int
+	Failures  []*ProcessError
+}
+
+// Add records a failure. A nil err is ignored.
+func (e *Errors) Add(pid uint32, name, op string, err error) {
+	if err == nil {
+		return
+	}
+	e.Failures = append(e.Failures, &ProcessError{PID: pid, Name: name, Op: op, Err: err})
+}
+
+// Err returns e if any failure was recorded, nil otherwise.
+func (e *Errors) Err() error {
+	if len(e.Failures) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *Errors) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d of %d processes failed: ", len(e.Failures), e.Attempted)
+	for i, f := range e.Failures {
+		if i == maxListedErrors {
+			fmt.Fprintf(&b, "; and %d more", len(e.Failures)-maxListedErrors)
+			break
+		}
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString(f.Error())
+	}
+	return b.String()
+}
+
+// Unwrap returns the individual failures, so errors.Is and errors.As see them.
+func (e *Errors) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, f := range e.Failures {
+		errs[i] = f
+	}
+	return errs
+}
+
+// IsPartial reports whether err only describes processes that could not be trimmed,
+// i.e. the pass itself completed.
+func IsPartial(err error) bool {
+	var trimErrs *Errors
+	return errors.As(err, &trimErrs)
+}
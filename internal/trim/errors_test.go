@@ -0,0 +1,75 @@
+package trim
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+var errAccessDenied = errors.New("access denied")
+
+func TestErrors(t *testing.T) {
+	var e Errors
+	e.Attempted = 10
+	if e.Err() != nil {
+		t.Fatalf("Err() without failures = %v, want nil", e.Err())
+	}
+
+	e.Add(1, "a.exe", "open", errAccessDenied)
+	e.Add(2, "b.exe", "empty working set", errors.New("invalid handle"))
+	e.Add(3, "c.exe", "close", nil)
+
+	err := e.Err()
+	if err == nil {
+		t.Fatal("Err() = nil, want an error")
+	}
+	expected := "2 of 10 processes failed: a.exe (1): open: access denied; b.exe (2): empty working set: invalid handle"
+	if err.Error() != expected {
+		t.Errorf("Error() = %q, want %q", err.Error(), expected)
+	}
+	if !errors.Is(err, errAccessDenied) {
+		t.Errorf("errors.Is() does not find the failure of a single process")
+	}
+
+	var processErr *ProcessError
+	if !errors.As(err, &processErr) || processErr.PID != 1 {
+		t.Errorf("errors.As() = %+v, want the first failure", processErr)
+	}
+}
+
+func TestErrorsTruncated(t *testing.T) {
+	e := Errors{Attempted: 5}
+	for i := 0; i < 5; i++ {
+		e.Add(uint32(i), "p.exe", "open", errAccessDenied)
+	}
+
+	expected := "5 of 5 processes failed: p.exe (0): open: access denied; p.exe (1): open: access denied; " +
+		"p.exe (2): open: access denied; and 2 more"
+	if e.Error() != expected {
+		t.Errorf("Error() = %q, want %q", e.Error(), expected)
+	}
+}
+
+func TestIsPartial(t *testing.T) {
+	partial := &Errors{Attempted: 1}
+	partial.Add(1, "a.exe", "open", errAccessDenied)
+
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "nil", err: nil, expected: false},
+		{name: "other error", err: errors.New("snapshot failed"), expected: false},
+		{name: "trim errors", err: partial, expected: true},
+		{name: "wrapped trim errors", err: fmt.Errorf("failed to clean system memory: %w", partial), expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := IsPartial(tt.err); result != tt.expected {
+				t.Errorf("IsPartial() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+// Description: This file contains the token bucket pacing the start of trims.
+
+package trim
+
+import (
+	"context"
+	"time"
+)
+
+// limiter is a token bucket: a trim may start when a token is available, tokens are added
+// every interval up to burst. It is used by a single goroutine.
+type limiter struct {
+	interval time.Duration
+	burst    int
+	tokens   float64
+	last     time.Time
+	now      func() time.Time
+}
+
+// newLimiter creates a limiter with a full bucket. A zero interval disables pacing.
+func newLimiter(interval time.Duration, burst int, now func() time.Time) *limiter {
+	return &limiter{interval: interval, burst: burst, tokens: float64(burst), last: now(), now: now}
+}
+
+// reserve takes a token and returns how long to wait before it may be used.
+func (l *limiter) reserve() time.Duration {
+	if l.interval <= 0 {
+		return 0
+	}
+
+	now := l.now()
+	l.tokens = min(float64(l.burst), l.tokens+float64(now.Sub(l.last))/float64(l.interval))
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens * float64(l.interval))
+}
+
+// wait takes a token and blocks until it may be used or ctx is done.
+func (l *limiter) wait(ctx context.Context) error {
+	delay := l.reserve()
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
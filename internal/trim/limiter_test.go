@@ -0,0 +1,40 @@
+package trim
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiterReserve(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	l := newLimiter(10*time.Millisecond, 2, func() time.Time { return now })
+
+	steps := []struct {
+		advance  time.Duration
+		expected time.Duration
+	}{
+		{advance: 0, expected: 0},                                         // burst
+		{advance: 0, expected: 0},                                         // burst
+		{advance: 0, expected: 10 * time.Millisecond},                     // bucket empty
+		{advance: 10 * time.Millisecond, expected: 10 * time.Millisecond}, // one token refilled, one owed
+		{advance: time.Second, expected: 0},                               // refilled up to burst
+		{advance: 0, expected: 0},
+		{advance: 0, expected: 10 * time.Millisecond},
+	}
+
+	for i, step := range steps {
+		now = now.Add(step.advance)
+		if result := l.reserve(); result != step.expected {
+			t.Errorf("step %d: reserve() = %v, want %v", i, result, step.expected)
+		}
+	}
+}
+
+func TestLimiterDisabled(t *testing.T) {
+	l := newLimiter(0, 1, time.Now)
+	for i := 0; i < 10; i++ {
+		if result := l.reserve(); result != 0 {
+			t.Fatalf("reserve() = %v, want 0 without pacing", result)
+		}
+	}
+}
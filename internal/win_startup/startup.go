@@ -1,8 +1,11 @@
+//go:build windows
+
 package winstartup
 
 import (
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 
 	"golang.org/x/sys/windows/registry"
@@ -11,6 +14,53 @@ import (
 var WinTaskName = "WindowsRAMCleaner"
 
 func CreateStartupTask() error {
+	if err := createStartupTask(WinTaskName); err != nil {
+		slog.Error("failed to create startup entry", "name", WinTaskName, "err", err)
+		return err
+	}
+	slog.Info("startup entry created", "name", WinTaskName)
+	return nil
+}
+
+func DeleteStartupTask() error {
+	if err := deleteStartupTask(WinTaskName); err != nil {
+		slog.Error("failed to delete startup entry", "name", WinTaskName, "err", err)
+		return err
+	}
+	slog.Info("startup entry deleted", "name", WinTaskName)
+	return nil
+}
+
+func IsStartupTaskExists() (bool, error) {
+	return isStartupTaskExists(WinTaskName)
+}
+
+// RenameStartupTask changes WinTaskName and moves an existing startup entry to the new name.
+func RenameStartupTask(name string) error {
+	if name == WinTaskName {
+		return nil
+	}
+	if err := renameStartupTask(WinTaskName, name); err != nil {
+		slog.Error("failed to rename startup entry", "name", WinTaskName, "new_name", name, "err", err)
+		return err
+	}
+	slog.Info("startup entry renamed", "name", WinTaskName, "new_name", name)
+	WinTaskName = name
+	return nil
+}
+
+func renameStartupTask(oldName, newName string) error {
+	exists, err := isStartupTaskExists(oldName)
+	if err != nil || !exists {
+		return err
+	}
+	if err := createStartupTask(newName); err != nil {
+		return err
+	}
+	return deleteStartupTask(oldName)
+}
+
+func createStartupTask(name string) error {
 	exePath, err := os.Executable()
 	if err != nil {
 		return fmt.Errorf("failed to get executable path: %v", err)
@@ -20,14 +70,9 @@ func CreateStartupTask() error {
 	if err != nil {
 		return fmt.Errorf("failed to open registry key: %v", err)
 	}
-	defer func(key registry.Key) {
-		err := key.Close()
-		if err != nil {
-
-		}
-	}(key)
+	defer closeKey(key)
 
-	err = key.SetStringValue(WinTaskName, exePath)
+	err = key.SetStringValue(name, exePath)
 	if err != nil {
 		return fmt.Errorf("failed to set registry value: %v", err)
 	}
@@ -35,19 +80,14 @@ func CreateStartupTask() error {
 	return nil
 }
 
-func DeleteStartupTask() error {
+func deleteStartupTask(name string) error {
 	key, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Run`, registry.SET_VALUE)
 	if err != nil {
 		return fmt.Errorf("failed to open registry key: %v", err)
 	}
-	defer func(key registry.Key) {
-		err := key.Close()
-		if err != nil {
-			fmt.Printf("failed to close registry key: %v\n", err)
-		}
-	}(key)
+	defer closeKey(key)
 
-	err = key.DeleteValue(WinTaskName)
+	err = key.DeleteValue(name)
 	if err != nil {
 		return fmt.Errorf("failed to delete registry value: %v", err)
 	}
@@ -55,19 +95,14 @@ func DeleteStartupTask() error {
 	return nil
 }
 
-func IsStartupTaskExists() (bool, error) {
+func isStartupTaskExists(name string) (bool, error) {
 	key, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Run`, registry.QUERY_VALUE)
 	if err != nil {
 		return false, fmt.Errorf("failed to open registry key: %v", err)
 	}
-	defer func(key registry.Key) {
-		err := key.Close()
-		if err != nil {
-			fmt.Printf("failed to close registry key: %v\n", err)
-		}
-	}(key)
+	defer closeKey(key)
 
-	_, valType, err := key.GetStringValue(WinTaskName)
+	_, valType, err := key.GetStringValue(name)
 	if err != nil {
 		if errors.Is(err, registry.ErrNotExist) {
 			return false, nil
@@ -77,3 +112,10 @@ func IsStartupTaskExists() (bool, error) {
 
 	return valType != 0, nil
 }
+
+// closeKey closes a registry key. The change is already done, so a failure is only logged.
+func closeKey(key registry.Key) {
+	if err := key.Close(); err != nil {
+		slog.Warn("failed to close registry key", "err", err)
+	}
+}
@@ -1,93 +1,44 @@
+//go:build windows
+
 package windowsapi
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 	"unsafe"
 
 	"golang.org/x/sys/windows"
+
+	"windows-ram-cleaner/internal/inventory"
+	"windows-ram-cleaner/internal/memlist"
+	"windows-ram-cleaner/internal/memory"
+	"windows-ram-cleaner/internal/procrules"
+	"windows-ram-cleaner/internal/report"
+	"windows-ram-cleaner/internal/trim"
 )
 
 const (
-	SystemMemoryListInformationClass = 0x50
-	MemoryPurgeStandbyList           = 4
+	SystemMemoryListInformationClass = memlist.SystemMemoryListInformationClass
+	MemoryPurgeStandbyList           = uint32(memlist.PurgeStandbyList)
 )
 
-// MEMORYSTATUSEX for GlobalMemoryStatusEx
-type MEMORYSTATUSEX struct {
-	DwLength                uint32
-	DwMemoryLoad            uint32
-	UllTotalPhys            uint64
-	UllAvailPhys            uint64
-	UllTotalPageFile        uint64
-	UllAvailPageFile        uint64
-	UllTotalVirtual         uint64
-	UllAvailVirtual         uint64
-	UllAvailExtendedVirtual uint64
-}
-
-// SystemMemoryListInformation struct for NtQuerySystemInformation
-type SystemMemoryListInformation struct {
-	ZeroPageCount               uint64
-	FreePageCount               uint64
-	ModifiedPageCount           uint64
-	ModifiedNoWritePageCount    uint64
-	BadPageCount                uint64
-	ActivePageCount             uint64
-	StandbyPageCount            uint64
-	StandbyPageCountLowPriority uint64
-	StandbyPageCountNormal      uint64
-	StandbyPageCountReserve     uint64
-	TransitionPageCount         uint64
-	ModifiedPageCountPagefile   uint64
-}
-
-// MemoryInfo represents memory stats
-type MemoryInfo struct {
-	FreeSize    uint64 // Available physical memory in bytes
-	StandbySize uint64 // Standby cache size in bytes
-}
-
-// GetMemoryInfo gets memory info via WinAPI
-func GetMemoryInfo() (MemoryInfo, error) {
-	var memInfo MemoryInfo
-
-	// 1. Free RAM
-	var status MEMORYSTATUSEX
-	status.DwLength = uint32(unsafe.Sizeof(status))
-	ret, _, err := procGlobalMemoryStatusEx.Call(uintptr(unsafe.Pointer(&status)))
-	if ret == 0 {
-		return memInfo, fmt.Errorf("GlobalMemoryStatusEx failed: %v", err)
-	}
-	memInfo.FreeSize = status.UllAvailPhys
-
-	// 2. Standby RAM
-	bufferSize := uintptr(16 * 1024) // 16 KB
-	buffer := make([]byte, bufferSize)
-
-	ret, _, _ = NtQuerySystemInformation.Call(
-		uintptr(SystemMemoryListInformationClass),
-		uintptr(unsafe.Pointer(&buffer[0])),
-		bufferSize,
-		0,
-	)
+// trimOptions configure the worker pool trimming processes
+var trimOptions atomic.Pointer[trim.Options]
 
-	if ret != 0 {
-		return memInfo, fmt.Errorf("NtQuerySystemInformation failed: NTSTATUS=0x%x", ret)
-	}
-
-	pageSize := uint64(4096)
-	counts := (*[128]uint64)(unsafe.Pointer(&buffer[0])) // берём большой массив, чтобы не выйти за границу
-
-	// Standby страницы примерно с 6 по 15 индекс (включая high/low)
-	standby := uint64(0)
-	for i := 6; i <= 15; i++ {
-		standby += counts[i]
-	}
+func init() {
+	SetTrimOptions(trim.DefaultOptions())
+}
 
-	memInfo.StandbySize = standby * pageSize
-	return memInfo, nil
+// SetTrimOptions sets the concurrency, timeout and pacing of process trimming
+func SetTrimOptions(opts trim.Options) {
+	trimOptions.Store(&opts)
 }
 
 // CleanOptions for cleaning RAM
@@ -102,105 +53,402 @@ func DefaultCleanOptions() CleanOptions {
 	}
 }
 
-// CleanRAM cleans RAM: standby list, process WS, system WS
+// memoryProvider takes the snapshots before and after a clean
+var memoryProvider = memory.NewProvider()
+
+// CleanRAM cleans RAM: process WS, own WS, system WS
 func CleanRAM(opts ...CleanOptions) error {
+	_, err := CleanRAMReport(opts...)
+	return err
+}
+
+// CleanRAMReport cleans RAM like CleanRAM and returns a report of what was achieved.
+// The report is returned even if the clean failed. Processes that could not be trimmed
+// don't stop the clean: they are returned as an error for which trim.IsPartial is true.
+func CleanRAMReport(opts ...CleanOptions) (*report.CleanReport, error) {
 	var options CleanOptions
 	if len(opts) > 0 {
 		options = opts[0]
 	} else {
 		options = DefaultCleanOptions()
 	}
+	return CleanRAMContext(context.Background(), options)
+}
+
+// CleanRAMContext cleans RAM like CleanRAMReport until ctx is canceled or its deadline passes.
+// A canceled clean stops starting new processes and stages; its error wraps ctx.Err().
+func CleanRAMContext(ctx context.Context, options CleanOptions) (*report.CleanReport, error) {
+	mode := report.ModeBasic
+	if options.IgnoreCritical {
+		mode = report.ModeDeep
+	}
+
+	r := startReport(mode)
+	err := cleanRAM(ctx, r, options)
+	finishReport(r, snapshotOrZero(), err)
+	return r, err
+}
 
-	if err := cleanSystemMemory(options.IgnoreCritical); err != nil {
-		return fmt.Errorf("failed to clean system memory: %v", err)
+// cleanRAM runs the stages of CleanRAM, recording them in r
+func cleanRAM(ctx context.Context, r *report.CleanReport, options CleanOptions) error {
+	// Processes that could not be trimmed don't stop the remaining stages,
+	// they are reported once the clean is over.
+	processErr := runStage(r, report.StageProcesses, func() (uint64, error) {
+		return cleanSystemMemory(ctx, r, options.IgnoreCritical)
+	})
+	if processErr != nil && !trim.IsPartial(processErr) {
+		return fmt.Errorf("failed to clean system memory: %w", processErr)
 	}
 
-	if err := cleanProcessMemory(); err != nil {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("clean canceled: %w", err)
+	}
+	if err := runStage(r, report.StageSelf, cleanProcessMemory); err != nil {
 		return fmt.Errorf("failed to clean process memory: %v", err)
 	}
 
-	if err := cleanSystemWorkingSet(); err != nil {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("clean canceled: %w", err)
+	}
+	if err := runStage(r, report.StageSystemWS, cleanSystemWorkingSet); err != nil {
 		return fmt.Errorf("failed to clean system working set: %v", err)
 	}
 
+	if processErr != nil {
+		return fmt.Errorf("failed to clean system memory: %w", processErr)
+	}
 	return nil
 }
 
+// TrimTargetContext empties the working sets of the processes selected by target, like a clean
+// does, and returns a report with the working set of every process before and after. Processes
+// denied by a process rule are skipped; the critical processes list doesn't apply, since the
+// user chose the processes. If no process matches, the error wraps inventory.ErrNoProcess.
+func TrimTargetContext(ctx context.Context, target inventory.Target) (*report.CleanReport, error) {
+	r := startReport(report.ModeTargeted)
+	r.Target = target.String()
+	err := trimTarget(ctx, r, target)
+	finishReport(r, snapshotOrZero(), err)
+	return r, err
+}
+
+// trimTarget trims the processes selected by target, recording them in r
+func trimTarget(ctx context.Context, r *report.CleanReport, target inventory.Target) error {
+	if err := target.Validate(); err != nil {
+		return err
+	}
+	processes, err := ProcessInventory()
+	if err != nil {
+		return fmt.Errorf("failed to list processes: %v", err)
+	}
+	selected := inventory.Select(processes, target)
+	if len(selected) == 0 {
+		return fmt.Errorf("%w: %s", inventory.ErrNoProcess, target)
+	}
+
+	enumerator := trim.EnumeratorFunc(func() ([]trim.Process, error) {
+		var allowed []trim.Process
+		for _, p := range selected {
+			if p.Excluded {
+				decision := processDecision(procrules.Process{
+					PID: p.PID, ParentPID: p.ParentPID, Name: p.Name, Path: p.Path, ParentName: p.ParentName,
+				}, true)
+				r.AddProcess(report.ProcessResult{PID: p.PID, Name: p.Name, Status: report.ProcessSkipped, Reason: decision.Reason()})
+				continue
+			}
+			allowed = append(allowed, trim.Process{PID: p.PID, Name: p.Name})
+		}
+		return allowed, nil
+	})
+
+	// Workers run concurrently, the sizes are read back by onResult
+	var mu sync.Mutex
+	sizes := make(map[uint32][2]uint64)
+	trimmer := trim.TrimmerFunc(func(ctx context.Context, p trim.Process) (uint64, error) {
+		before, after, err := emptyWorkingSet(ctx, p)
+		if err != nil {
+			return 0, err
+		}
+		mu.Lock()
+		sizes[p.PID] = [2]uint64{before, after}
+		mu.Unlock()
+		return sizeDelta(before, after), nil
+	})
+
+	return runStage(r, report.StageProcesses, func() (uint64, error) {
+		return trim.Run(ctx, enumerator, trimmer, *trimOptions.Load(), func(res trim.Result) {
+			result := report.ProcessResult{PID: res.Process.PID, Name: res.Process.Name, FreedBytes: res.Freed}
+			result.Status, result.Reason = processStatus(res)
+			if result.Status == report.ProcessTrimmed {
+				mu.Lock()
+				size := sizes[res.Process.PID]
+				mu.Unlock()
+				result.WorkingSetBefore, result.WorkingSetAfter = size[0], size[1]
+			}
+			r.AddProcess(result)
+		})
+	})
+}
+
 // CleanStandbyList purges standby list
 func CleanStandbyList() error {
+	_, err := CleanStandbyListReport()
+	return err
+}
+
+// CleanStandbyListReport purges the standby list and returns a report of what was achieved.
+// The report is returned even if the purge failed.
+func CleanStandbyListReport() (*report.CleanReport, error) {
+	return CleanStandbyListContext(context.Background())
+}
+
+// CleanStandbyListContext purges the standby list like CleanStandbyListReport unless ctx is
+// already done. The purge is a single system call: once started it can't be canceled.
+func CleanStandbyListContext(ctx context.Context) (*report.CleanReport, error) {
+	r := startReport(report.ModeStandby)
+	if err := ctx.Err(); err != nil {
+		err = fmt.Errorf("clean canceled: %w", err)
+		finishReport(r, r.Before, err)
+		return r, err
+	}
+
+	start := time.Now()
+	err := purgeStandbyList()
+	after := snapshotOrZero()
+	r.AddStage(report.StageStandby, sizeDelta(r.Before.StandbySize, after.StandbySize), time.Since(start), err)
+
+	finishReport(r, after, err)
+	return r, err
+}
+
+// RunMemoryListCommand runs a memory list command and returns a report of what was achieved,
+// unless ctx is already done. The report is returned even if the command failed.
+func RunMemoryListCommand(ctx context.Context, command memlist.Command) (*report.CleanReport, error) {
+	r := startReport(report.ModeMemoryList)
+	if err := ctx.Err(); err != nil {
+		err = fmt.Errorf("command canceled: %w", err)
+		finishReport(r, r.Before, err)
+		return r, err
+	}
+
+	start := time.Now()
+	freed, err := setMemoryList(command)
+	after := snapshotOrZero()
+	if command != memlist.CombinePageLists {
+		freed = command.Freed(r.Before, after)
+	}
+	r.AddStage(command.String(), freed, time.Since(start), err)
+
+	finishReport(r, after, err)
+	return r, err
+}
+
+// purgeStandbyList asks the memory manager to empty the standby list
+func purgeStandbyList() error {
+	_, err := setMemoryList(memlist.PurgeStandbyList)
+	return err
+}
+
+// setMemoryList sends a memory list command to the memory manager. For CombinePageLists
+// it returns the size of the combined pages.
+func setMemoryList(command memlist.Command) (uint64, error) {
 	if err := GrantPrivileges(); err != nil {
-		return fmt.Errorf("failed to grant privileges: %v", err)
+		return 0, fmt.Errorf("failed to grant privileges: %v", err)
 	}
 
-	memoryPurgeStandbyList := uint32(MemoryPurgeStandbyList)
+	class, buf, err := command.Request(int(unsafe.Sizeof(uintptr(0))))
+	if err != nil {
+		return 0, err
+	}
 	r1, _, err := NtSetSystemInformation.Call(
-		uintptr(SystemMemoryListInformationClass),
-		uintptr(unsafe.Pointer(&memoryPurgeStandbyList)),
-		unsafe.Sizeof(memoryPurgeStandbyList),
+		uintptr(class),
+		uintptr(unsafe.Pointer(&buf[0])),
+		uintptr(len(buf)),
 	)
 	if r1 != 0 {
-		return fmt.Errorf("NtSetSystemInformation call failed: %v", err)
+		return 0, fmt.Errorf("NtSetSystemInformation call failed: %v", err)
 	}
-	return nil
+
+	if command != memlist.CombinePageLists {
+		return 0, nil
+	}
+	pages, err := memlist.PagesCombined(buf, int(unsafe.Sizeof(uintptr(0))))
+	if err != nil {
+		return 0, err
+	}
+	return pages * uint64(os.Getpagesize()), nil
 }
 
 // cleanProcessMemory sets working set size to min/max
-func cleanProcessMemory() error {
+func cleanProcessMemory() (uint64, error) {
 	hProcess := windows.CurrentProcess()
+	before, _ := workingSetSize(hProcess)
 	ret, _, err := ProcSetProcessWorkingSetSize.Call(uintptr(hProcess), uintptr(^uint32(0)), uintptr(^uint32(0)))
 	if ret == 0 {
-		return fmt.Errorf("failed to set process working set size: %v", err)
+		return 0, fmt.Errorf("failed to set process working set size: %v", err)
 	}
-	return nil
+	after, _ := workingSetSize(hProcess)
+	return sizeDelta(before, after), nil
 }
 
 // cleanSystemWorkingSet empties working set
-func cleanSystemWorkingSet() error {
+func cleanSystemWorkingSet() (uint64, error) {
 	hProcess := windows.CurrentProcess()
+	before, _ := workingSetSize(hProcess)
 	ret, _, err := ProcEmptyWorkingSet.Call(uintptr(hProcess))
 	if ret == 0 {
-		return fmt.Errorf("failed to empty working set: %v", err)
+		return 0, fmt.Errorf("failed to empty working set: %v", err)
+	}
+	after, _ := workingSetSize(hProcess)
+	return sizeDelta(before, after), nil
+}
+
+// cleanSystemMemory frees memory of processes allowed by the process rules and
+// returns the total working set reduction. Every allowed process is attempted: a process
+// that can't be trimmed is recorded in the report and in the returned *trim.Errors
+// instead of stopping the clean. When ctx is canceled no new process is trimmed.
+func cleanSystemMemory(ctx context.Context, r *report.CleanReport, ignoreCritical bool) (uint64, error) {
+	enumerator := trim.EnumeratorFunc(func() ([]trim.Process, error) {
+		return allowedProcesses(r, ignoreCritical)
+	})
+
+	return trim.Run(ctx, enumerator, trim.TrimmerFunc(trimProcess), *trimOptions.Load(), func(res trim.Result) {
+		result := report.ProcessResult{PID: res.Process.PID, Name: res.Process.Name, FreedBytes: res.Freed}
+		result.Status, result.Reason = processStatus(res)
+		r.AddProcess(result)
+	})
+}
+
+// processStatus returns the report status of a trimmed process and the reason it was not trimmed
+func processStatus(res trim.Result) (report.ProcessStatus, string) {
+	switch {
+	case res.Skipped != "":
+		return report.ProcessSkipped, res.Skipped
+	case res.Err != nil:
+		return report.ProcessFailed, fmt.Sprintf("failed to %s: %v", res.Err.Op, res.Err.Err)
+	default:
+		return report.ProcessTrimmed, ""
 	}
-	return nil
 }
 
-// cleanSystemMemory frees memory of non-critical processes
-func cleanSystemMemory(ignoreCritical bool) error {
-	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+// allowedProcesses lists the processes allowed by the process rules and records the others
+// as skipped in r
+func allowedProcesses(r *report.CleanReport, ignoreCritical bool) ([]trim.Process, error) {
+	processes, err := listProcesses(processRules.Load().NeedsPath())
 	if err != nil {
-		return fmt.Errorf("failed to create snapshot: %v", err)
-	}
-	defer windows.CloseHandle(snapshot)
-
-	var pe windows.ProcessEntry32
-	pe.Size = uint32(unsafe.Sizeof(pe))
-	if err := windows.Process32First(snapshot, &pe); err != nil {
-		return fmt.Errorf("failed to get first process: %v", err)
-	}
-
-	for {
-		// Если процесс критический — пропускаем
-		if ignoreCritical || !isCriticalProcess(pe) {
-			hProcess, err := windows.OpenProcess(windows.PROCESS_QUERY_INFORMATION|windows.PROCESS_SET_QUOTA, false, pe.ProcessID)
-			if err == nil {
-				ret, _, err := ProcEmptyWorkingSet.Call(uintptr(hProcess))
-				if ret == 0 {
-					return err
-				}
-				err = windows.CloseHandle(hProcess)
-				if err != nil {
-					return err
-				}
-				time.Sleep(10 * time.Millisecond)
-			}
+		return nil, err
+	}
+
+	var allowed []trim.Process
+	for _, p := range processes {
+		// Если процесс защищён правилами — пропускаем
+		if decision := processDecision(p, ignoreCritical); !decision.Allowed {
+			r.AddProcess(report.ProcessResult{
+				PID:    p.PID,
+				Name:   p.Name,
+				Status: report.ProcessSkipped,
+				Reason: decision.Reason(),
+			})
+			continue
 		}
+		allowed = append(allowed, trim.Process{PID: p.PID, Name: p.Name})
+	}
+	return allowed, nil
+}
+
+// trimProcess empties the working set of one process and returns the working set reduction.
+func trimProcess(ctx context.Context, p trim.Process) (uint64, error) {
+	before, after, err := emptyWorkingSet(ctx, p)
+	if err != nil {
+		return 0, err
+	}
+	return sizeDelta(before, after), nil
+}
 
-		if err := windows.Process32Next(snapshot, &pe); err != nil {
-			break
+// emptyWorkingSet empties the working set of one process and returns its size before and after.
+// A process that can't be opened because it is protected or has exited is skipped.
+// The process handle is always released. EmptyWorkingSet can't be interrupted, so ctx is
+// only checked before the process is opened.
+func emptyWorkingSet(ctx context.Context, p trim.Process) (before, after uint64, err error) {
+	if err := ctx.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	hProcess, err := windows.OpenProcess(windows.PROCESS_QUERY_INFORMATION|windows.PROCESS_SET_QUOTA, false, p.PID)
+	switch {
+	// Protected processes such as System, Registry or antivirus services can't be opened
+	case errors.Is(err, windows.ERROR_ACCESS_DENIED):
+		return 0, 0, trim.Skip("protected process")
+	// The process exited after it was listed
+	case errors.Is(err, windows.ERROR_INVALID_PARAMETER):
+		return 0, 0, trim.Skip("process exited")
+	case err != nil:
+		return 0, 0, &trim.ProcessError{Op: "open process", Err: err}
+	}
+	defer func() {
+		if closeErr := windows.CloseHandle(hProcess); closeErr != nil && err == nil {
+			err = &trim.ProcessError{Op: "close process handle", Err: closeErr}
 		}
+	}()
+
+	before, _ = workingSetSize(hProcess)
+	ret, _, callErr := ProcEmptyWorkingSet.Call(uintptr(hProcess))
+	if ret == 0 {
+		return 0, 0, &trim.ProcessError{Op: "empty working set", Err: callErr}
 	}
+	after, _ = workingSetSize(hProcess)
+	return before, after, nil
+}
 
-	return nil
+// startReport creates a report with the current memory state
+func startReport(mode report.Mode) *report.CleanReport {
+	return report.New(mode, time.Now(), snapshotOrZero())
+}
+
+// finishReport finishes r and logs the outcome of the clean
+func finishReport(r *report.CleanReport, after memory.Snapshot, err error) {
+	r.Finish(time.Now(), after, err)
+
+	attrs := []any{"mode", r.Mode, "freed", r.FreedBytes(), "duration", r.Duration}
+	if r.Target != "" {
+		attrs = append(attrs, "target", r.Target)
+	}
+	switch {
+	case err == nil:
+		slog.Info("clean done", attrs...)
+	case errors.Is(err, context.Canceled):
+		slog.Info("clean canceled", attrs...)
+	case trim.IsPartial(err):
+		slog.Warn("clean done with failures", append(attrs, "err", err)...)
+	default:
+		slog.Error("clean failed", append(attrs, "err", err)...)
+	}
+}
+
+// runStage runs one stage of a clean and records its result in r
+func runStage(r *report.CleanReport, name string, stage func() (uint64, error)) error {
+	start := time.Now()
+	freed, err := stage()
+	r.AddStage(name, freed, time.Since(start), err)
+	return err
+}
+
+// snapshotOrZero returns the current memory state, or an empty snapshot if it can't be queried,
+// so that a failing query never prevents a clean
+func snapshotOrZero() memory.Snapshot {
+	s, err := memoryProvider.Snapshot()
+	if err != nil {
+		slog.Warn("failed to get memory statistics for the clean report", "err", err)
+	}
+	return s
+}
+
+// sizeDelta returns how much a size shrank, zero if it grew
+func sizeDelta(before, after uint64) uint64 {
+	if after >= before {
+		return 0
+	}
+	return before - after
 }
 
 // IsTaskbarVisible checks taskbar visibility
@@ -0,0 +1,101 @@
+//go:build windows
+
+package windowsapi
+
+import (
+	"errors"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Balloon icons for ShowBalloon
+const (
+	NiifNone    = 0x00000000
+	NiifInfo    = 0x00000001
+	NiifWarning = 0x00000002
+	NiifError   = 0x00000003
+)
+
+const (
+	nimModify = 0x00000001
+	nifInfo   = 0x00000010
+
+	// trayWindowClass and trayIconID identify the icon created by the systray package
+	trayWindowClass = "SystrayClass"
+	trayIconID      = 100
+)
+
+// notifyIconData for Shell_NotifyIconW, matches the layout used by the systray package
+type notifyIconData struct {
+	Size                       uint32
+	Wnd                        windows.Handle
+	ID, Flags, CallbackMessage uint32
+	Icon                       windows.Handle
+	Tip                        [128]uint16
+	State, StateMask           uint32
+	Info                       [256]uint16
+	Timeout, Version           uint32
+	InfoTitle                  [64]uint16
+	InfoFlags                  uint32
+	GuidItem                   windows.GUID
+	BalloonIcon                windows.Handle
+}
+
+// ShowBalloon shows a non-blocking balloon notification on the tray icon of this process.
+// It fails if the tray icon has not been created yet.
+func ShowBalloon(title, text string, icon uint32) error {
+	hwnd := findTrayWindow()
+	if hwnd == 0 {
+		return errors.New("tray icon window not found")
+	}
+
+	nid := notifyIconData{
+		Wnd:       hwnd,
+		ID:        trayIconID,
+		Flags:     nifInfo,
+		InfoFlags: icon,
+	}
+	nid.Size = uint32(unsafe.Sizeof(nid))
+	copyUTF16(nid.InfoTitle[:], title)
+	copyUTF16(nid.Info[:], text)
+
+	ret, _, err := ProcShellNotifyIconW.Call(nimModify, uintptr(unsafe.Pointer(&nid)))
+	if ret == 0 {
+		return err
+	}
+	return nil
+}
+
+// findTrayWindow returns the hidden window owning the tray icon of this process
+func findTrayWindow() windows.Handle {
+	className := utf16PtrFromString(trayWindowClass)
+	pid := windows.GetCurrentProcessId()
+
+	var hwnd uintptr
+	for {
+		hwnd, _, _ = ProcFindWindowExW.Call(0, hwnd, uintptr(unsafe.Pointer(className)), 0)
+		if hwnd == 0 {
+			return 0
+		}
+		var windowPID uint32
+		ProcGetWindowThreadProcessId.Call(hwnd, uintptr(unsafe.Pointer(&windowPID)))
+		if windowPID == pid {
+			return windows.Handle(hwnd)
+		}
+	}
+}
+
+// copyUTF16 copies s into a fixed-size, null-terminated UTF-16 buffer, truncating it if needed.
+// Strings containing NUL are left out
+func copyUTF16(dst []uint16, s string) {
+	src, err := windows.UTF16FromString(s)
+	if err != nil {
+		return
+	}
+	if len(src) > len(dst) {
+		src = src[:len(dst)]
+		src[len(src)-1] = 0
+	}
+	copy(dst, src)
+}
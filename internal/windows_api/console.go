@@ -0,0 +1,32 @@
+//go:build windows
+
+package windowsapi
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+const attachParentProcess = ^uint32(0) // ATTACH_PARENT_PROCESS
+
+// AttachParentConsole connects stdout and stderr to the console of the parent process.
+// The application is built as a GUI binary, so without it the command-line output is lost
+// unless it is redirected. Redirected handles are left untouched.
+func AttachParentConsole() {
+	out, err := windows.GetStdHandle(windows.STD_OUTPUT_HANDLE)
+	if err == nil && out != 0 && out != windows.InvalidHandle {
+		return
+	}
+
+	if ret, _, _ := ProcAttachConsole.Call(uintptr(attachParentProcess)); ret == 0 {
+		return
+	}
+
+	conout, err := os.OpenFile("CONOUT$", os.O_WRONLY, 0)
+	if err != nil {
+		return
+	}
+	os.Stdout = conout
+	os.Stderr = conout
+}
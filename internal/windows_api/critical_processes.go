@@ -1,48 +1,42 @@
+//go:build windows
+
 package windowsapi
 
-import "golang.org/x/sys/windows"
-
-// CriticalProcesses is a map of critical processes that should not be cleaned
-// Use empty struct as value to save memory
-var CriticalProcesses = map[string]struct{}{
-	"csrss.exe":                   {},
-	"wininit.exe":                 {},
-	"services.exe":                {},
-	"lsass.exe":                   {},
-	"winlogon.exe":                {},
-	"explorer.exe":                {},
-	"smss.exe":                    {},
-	"svchost.exe":                 {},
-	"System":                      {},
-	"System Idle Process":         {},
-	"conhost.exe":                 {},
-	"dwm.exe":                     {},
-	"taskhost.exe":                {},
-	"taskhostw.exe":               {},
-	"spoolsv.exe":                 {},
-	"msmpeng.exe":                 {},
-	"audiodg.exe":                 {},
-	"fontdrvhost.exe":             {},
-	"sihost.exe":                  {},
-	"dllhost.exe":                 {},
-	"logonui.exe":                 {},
-	"lsm.exe":                     {},
-	"SearchIndexer.exe":           {},
-	"SecurityHealthService.exe":   {},
-	"ShellExperienceHost.exe":     {},
-	"StartMenuExperienceHost.exe": {},
-	"SystemSettings.exe":          {},
-	"taskeng.exe":                 {},
-	"taskhostex.exe":              {},
-	"TrustedInstaller.exe":        {},
-	"userinit.exe":                {},
-	"WmiPrvSE.exe":                {},
-	"WUDFHost.exe":                {},
+import (
+	"sync/atomic"
+
+	"golang.org/x/sys/windows"
+
+	"windows-ram-cleaner/internal/procrules"
+)
+
+// processRules decides which processes may be trimmed
+var processRules atomic.Pointer[procrules.Engine]
+
+func init() {
+	processRules.Store(procrules.Default())
+}
+
+// SetProcessRules replaces the user rules and the list of critical processes
+func SetProcessRules(rules []procrules.Rule, critical []string) error {
+	engine, err := procrules.New(rules, critical)
+	if err != nil {
+		return err
+	}
+	processRules.Store(engine)
+	return nil
+}
+
+// processDecision evaluates the process rules, ignoreCritical skips the critical processes
+func processDecision(p procrules.Process, ignoreCritical bool) procrules.Decision {
+	return processRules.Load().Evaluate(p, ignoreCritical)
 }
 
-// isCriticalProcess checks if a process is critical and should not be cleaned
-func isCriticalProcess(pe windows.ProcessEntry32) bool {
-	appExeFilename := windows.UTF16ToString(pe.ExeFile[:])
-	_, isCritical := CriticalProcesses[appExeFilename]
-	return isCritical
+// processFromEntry converts a toolhelp entry to the information used by the rules
+func processFromEntry(pe windows.ProcessEntry32) procrules.Process {
+	return procrules.Process{
+		PID:       pe.ProcessID,
+		ParentPID: pe.ParentProcessID,
+		Name:      windows.UTF16ToString(pe.ExeFile[:]),
+	}
 }
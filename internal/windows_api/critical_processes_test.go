@@ -1,16 +1,21 @@
+//go:build windows
+
 package windowsapi
 
 import (
 	"testing"
 
 	"golang.org/x/sys/windows"
+
+	"windows-ram-cleaner/internal/procrules"
 )
 
-func TestIsCriticalProcess(t *testing.T) {
+func TestProcessDecision(t *testing.T) {
 	tests := []struct {
-		name     string
-		exeFile  string
-		expected bool
+		name           string
+		exeFile        string
+		ignoreCritical bool
+		expected       bool
 	}{
 		{
 			name:     "critical process",
@@ -22,6 +27,11 @@ func TestIsCriticalProcess(t *testing.T) {
 			exeFile:  "lsass.exe",
 			expected: true,
 		},
+		{
+			name:     "critical process with different case",
+			exeFile:  "MsMpEng.exe",
+			expected: true,
+		},
 		{
 			name:     "non-critical process",
 			exeFile:  "notepad.exe",
@@ -32,6 +42,12 @@ func TestIsCriticalProcess(t *testing.T) {
 			exeFile:  "",
 			expected: false,
 		},
+		{
+			name:           "deep clean ignores critical process",
+			exeFile:        "explorer.exe",
+			ignoreCritical: true,
+			expected:       false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -41,18 +57,37 @@ func TestIsCriticalProcess(t *testing.T) {
 			copy(pe.ExeFile[:], windows.StringToUTF16(tt.exeFile))
 
 			// Call the function
-			result := isCriticalProcess(pe)
+			result := !processDecision(processFromEntry(pe), tt.ignoreCritical).Allowed
 
 			// Check the result
 			if result != tt.expected {
-				t.Errorf("isCriticalProcess() = %v, want %v", result, tt.expected)
+				t.Errorf("processDecision() protected = %v, want %v", result, tt.expected)
 			}
 		})
 	}
 }
 
-func TestCriticalProcessesMap(t *testing.T) {
-	// Test that some expected critical processes are in the map
+func TestSetProcessRules(t *testing.T) {
+	defer processRules.Store(procrules.Default())
+
+	rules := []procrules.Rule{{Action: procrules.Deny, Name: "game*.exe"}}
+	if err := SetProcessRules(rules, []string{"lsass.exe"}); err != nil {
+		t.Fatalf("SetProcessRules() error = %v", err)
+	}
+
+	if processDecision(procrules.Process{Name: "GameClient.exe"}, true).Allowed {
+		t.Errorf("user rule should protect the process during a deep clean")
+	}
+	if !processDecision(procrules.Process{Name: "explorer.exe"}, false).Allowed {
+		t.Errorf("explorer.exe is no longer critical")
+	}
+	if err := SetProcessRules([]procrules.Rule{{Action: procrules.Deny}}, nil); err == nil {
+		t.Errorf("SetProcessRules() expected an error for an invalid rule")
+	}
+}
+
+func TestDefaultCriticalProcesses(t *testing.T) {
+	// Some expected critical processes are protected by a basic clean
 	expectedCritical := []string{
 		"csrss.exe",
 		"wininit.exe",
@@ -62,12 +97,12 @@ func TestCriticalProcessesMap(t *testing.T) {
 	}
 
 	for _, procName := range expectedCritical {
-		if _, exists := CriticalProcesses[procName]; !exists {
-			t.Errorf("Expected %s to be in CriticalProcesses map", procName)
+		if processDecision(procrules.Process{Name: procName}, false).Allowed {
+			t.Errorf("Expected %s to be a critical process", procName)
 		}
 	}
 
-	// Test that the map doesn't contain some non-critical processes
+	// Non-critical processes are trimmed
 	nonCritical := []string{
 		"notepad.exe",
 		"calc.exe",
@@ -75,8 +110,8 @@ func TestCriticalProcessesMap(t *testing.T) {
 	}
 
 	for _, procName := range nonCritical {
-		if _, exists := CriticalProcesses[procName]; exists {
-			t.Errorf("Did not expect %s to be in CriticalProcesses map", procName)
+		if !processDecision(procrules.Process{Name: procName}, false).Allowed {
+			t.Errorf("Did not expect %s to be a critical process", procName)
 		}
 	}
 }
@@ -0,0 +1,72 @@
+//go:build windows
+
+package windowsapi
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"windows-ram-cleaner/internal/inventory"
+)
+
+// PROCESS_MEMORY_COUNTERS_EX for GetProcessMemoryInfo
+type processMemoryCountersEx struct {
+	ProcessMemoryCounters
+	PrivateUsage uintptr
+}
+
+// ProcessInventory returns the memory usage of every running process, with the critical and
+// excluded flags of the current process rules. Processes whose counters can't be read, such as
+// protected ones, are included with their error.
+func ProcessInventory() ([]inventory.Process, error) {
+	processes, err := listProcesses(false)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]inventory.Process, 0, len(processes))
+	for _, p := range processes {
+		item := inventory.Process{
+			PID:        p.PID,
+			ParentPID:  p.ParentPID,
+			Name:       p.Name,
+			ParentName: p.ParentName,
+		}
+
+		counters, path, err := processMemory(p.PID)
+		if err != nil {
+			item.Error = err.Error()
+		} else {
+			item.Path = path
+			item.WorkingSet = uint64(counters.WorkingSetSize)
+			item.PrivateBytes = uint64(counters.PrivateUsage)
+			item.PageFaults = uint64(counters.PageFaultCount)
+		}
+
+		// Path rules can only be evaluated with the path
+		p.Path = item.Path
+		item.Critical = processDecision(p, false).Critical
+		item.Excluded = !processDecision(p, true).Allowed
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// processMemory returns the memory counters and the executable path of a process.
+func processMemory(pid uint32) (processMemoryCountersEx, string, error) {
+	var counters processMemoryCountersEx
+	hProcess, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return counters, "", fmt.Errorf("failed to open process: %v", err)
+	}
+	defer windows.CloseHandle(hProcess)
+
+	counters.Cb = uint32(unsafe.Sizeof(counters))
+	ret, _, err := ProcGetProcessMemoryInfo.Call(uintptr(hProcess), uintptr(unsafe.Pointer(&counters)), uintptr(counters.Cb))
+	if ret == 0 {
+		return counters, "", fmt.Errorf("GetProcessMemoryInfo failed: %v", err)
+	}
+	return counters, imagePath(hProcess), nil
+}
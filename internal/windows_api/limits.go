@@ -0,0 +1,73 @@
+//go:build windows
+
+package windowsapi
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"windows-ram-cleaner/internal/procrules"
+	"windows-ram-cleaner/internal/wslimit"
+)
+
+// increaseWorkingSetPrivilege is needed to raise the limits of a process above its current ones
+var increaseWorkingSetPrivilege = sync.OnceValue(func() error {
+	err := enablePrivilege("SeIncreaseWorkingSetPrivilege")
+	if err != nil {
+		slog.Warn("failed to enable SeIncreaseWorkingSetPrivilege", "err", err)
+	}
+	return err
+})
+
+// WorkingSetLimits implements wslimit.System with Get/SetProcessWorkingSetSizeEx
+type WorkingSetLimits struct{}
+
+// Processes lists the running processes
+func (WorkingSetLimits) Processes(withPath bool) ([]procrules.Process, error) {
+	return listProcesses(withPath)
+}
+
+// Quota returns the working set limits of a process
+func (WorkingSetLimits) Quota(pid uint32) (wslimit.Quota, error) {
+	hProcess, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return wslimit.Quota{}, fmt.Errorf("failed to open process: %v", err)
+	}
+	defer windows.CloseHandle(hProcess)
+
+	var minSize, maxSize uintptr
+	var flags uint32
+	ret, _, err := ProcGetWorkingSetSizeEx.Call(
+		uintptr(hProcess),
+		uintptr(unsafe.Pointer(&minSize)),
+		uintptr(unsafe.Pointer(&maxSize)),
+		uintptr(unsafe.Pointer(&flags)),
+	)
+	if ret == 0 {
+		return wslimit.Quota{}, fmt.Errorf("GetProcessWorkingSetSizeEx failed: %v", err)
+	}
+	return wslimit.Quota{Min: uint64(minSize), Max: uint64(maxSize), Flags: flags}, nil
+}
+
+// SetQuota sets the working set limits of a process
+func (WorkingSetLimits) SetQuota(pid uint32, q wslimit.Quota) error {
+	if err := increaseWorkingSetPrivilege(); err != nil {
+		return fmt.Errorf("failed to enable privilege: %v", err)
+	}
+
+	hProcess, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA, false, pid)
+	if err != nil {
+		return fmt.Errorf("failed to open process: %v", err)
+	}
+	defer windows.CloseHandle(hProcess)
+
+	ret, _, err := ProcSetWorkingSetSizeEx.Call(uintptr(hProcess), uintptr(q.Min), uintptr(q.Max), uintptr(q.Flags))
+	if ret == 0 {
+		return fmt.Errorf("SetProcessWorkingSetSizeEx failed: %v", err)
+	}
+	return nil
+}
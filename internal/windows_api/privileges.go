@@ -1,8 +1,11 @@
+//go:build windows
+
 // Package windowsapi Description: This package provides functions to elevate the current process to run as administrator.
 package windowsapi
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
 	"strings"
 	"syscall"
@@ -14,10 +17,14 @@ import (
 // It uses the Windows API function ShellExecute to execute the executable
 // with the "runas" verb, which prompts the user for consent to elevate
 // the process. The function takes no arguments and returns no values.
-// If an error occurs during the execution, it will be printed to the console.
+// If an error occurs during the execution, it is logged.
 func RequestAdminRights() {
 	verb := "runas"
-	exe, _ := os.Executable()
+	exe, err := os.Executable()
+	if err != nil {
+		slog.Error("failed to get executable path", "err", err)
+		return
+	}
 	cwd, _ := os.Getwd()
 	args := strings.Join(os.Args[1:], " ")
 
@@ -28,9 +35,9 @@ func RequestAdminRights() {
 
 	var showCmd int32 = 1 // SW_NORMAL
 
-	err := windows.ShellExecute(0, verbPtr, exePtr, argPtr, cwdPtr, showCmd)
+	err = windows.ShellExecute(0, verbPtr, exePtr, argPtr, cwdPtr, showCmd)
 	if err != nil {
-		fmt.Println(err)
+		slog.Error("failed to request admin rights", "err", err)
 	}
 }
 
@@ -0,0 +1,94 @@
+//go:build windows
+
+package windowsapi
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"windows-ram-cleaner/internal/procrules"
+)
+
+// listProcesses returns all running processes with their parent names.
+// The executable path is queried only when withPath is set, because it opens every process.
+func listProcesses(withPath bool) ([]procrules.Process, error) {
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot: %v", err)
+	}
+	defer windows.CloseHandle(snapshot)
+
+	var pe windows.ProcessEntry32
+	pe.Size = uint32(unsafe.Sizeof(pe))
+	if err := windows.Process32First(snapshot, &pe); err != nil {
+		return nil, fmt.Errorf("failed to get first process: %v", err)
+	}
+
+	var processes []procrules.Process
+	names := make(map[uint32]string)
+	for {
+		p := processFromEntry(pe)
+		processes = append(processes, p)
+		names[p.PID] = p.Name
+
+		if err := windows.Process32Next(snapshot, &pe); err != nil {
+			break
+		}
+	}
+
+	for i := range processes {
+		processes[i].ParentName = names[processes[i].ParentPID]
+		if withPath {
+			processes[i].Path = processPath(processes[i].PID)
+		}
+	}
+
+	return processes, nil
+}
+
+// processPath returns the full executable path of a process, or an empty string if it can't be queried
+func processPath(pid uint32) string {
+	hProcess, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return ""
+	}
+	defer windows.CloseHandle(hProcess)
+	return imagePath(hProcess)
+}
+
+// imagePath returns the full executable path of an open process, or an empty string if it can't be queried
+func imagePath(hProcess windows.Handle) string {
+	buf := make([]uint16, windows.MAX_LONG_PATH)
+	size := uint32(len(buf))
+	if err := windows.QueryFullProcessImageName(hProcess, 0, &buf[0], &size); err != nil {
+		return ""
+	}
+	return windows.UTF16ToString(buf[:size])
+}
+
+// PROCESS_MEMORY_COUNTERS for GetProcessMemoryInfo
+type ProcessMemoryCounters struct {
+	Cb                         uint32
+	PageFaultCount             uint32
+	PeakWorkingSetSize         uintptr
+	WorkingSetSize             uintptr
+	QuotaPeakPagedPoolUsage    uintptr
+	QuotaPagedPoolUsage        uintptr
+	QuotaPeakNonPagedPoolUsage uintptr
+	QuotaNonPagedPoolUsage     uintptr
+	PagefileUsage              uintptr
+	PeakPagefileUsage          uintptr
+}
+
+// workingSetSize returns the working set size of a process in bytes
+func workingSetSize(hProcess windows.Handle) (uint64, error) {
+	var counters ProcessMemoryCounters
+	counters.Cb = uint32(unsafe.Sizeof(counters))
+	ret, _, err := ProcGetProcessMemoryInfo.Call(uintptr(hProcess), uintptr(unsafe.Pointer(&counters)), uintptr(counters.Cb))
+	if ret == 0 {
+		return 0, fmt.Errorf("GetProcessMemoryInfo failed: %v", err)
+	}
+	return uint64(counters.WorkingSetSize), nil
+}
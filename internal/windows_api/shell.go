@@ -0,0 +1,41 @@
+//go:build windows
+
+package windowsapi
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"golang.org/x/sys/windows"
+)
+
+// ShellOpen opens a file or URL with its default application, e.g. a web page in the browser
+func ShellOpen(target string) error {
+	verb, err := windows.UTF16PtrFromString("open")
+	if err != nil {
+		return err
+	}
+	file, err := windows.UTF16PtrFromString(target)
+	if err != nil {
+		return err
+	}
+	if err := windows.ShellExecute(0, verb, file, nil, nil, windows.SW_SHOWNORMAL); err != nil {
+		return fmt.Errorf("failed to open %s: %v", target, err)
+	}
+	return nil
+}
+
+// ShellOpenUnelevated opens a file or URL like ShellOpen, but from the running Explorer of the user,
+// so that an elevated process doesn't start the default application elevated
+func ShellOpenUnelevated(target string) error {
+	explorer := filepath.Join(os.Getenv("SystemRoot"), "explorer.exe")
+	cmd := exec.Command(explorer, target)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to open %s: %v", target, err)
+	}
+	// Explorer hands the target to the running shell and exits, its exit code is meaningless
+	go cmd.Wait()
+	return nil
+}
@@ -0,0 +1,165 @@
+// Description: This file contains the manager applying the limits to the running processes and restoring them.
+
+package wslimit
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"windows-ram-cleaner/internal/procrules"
+)
+
+// System reads and sets the working set limits of processes.
+type System interface {
+	// Processes lists the running processes; the path is only needed when withPath is set.
+	Processes(withPath bool) ([]procrules.Process, error)
+	Quota(pid uint32) (Quota, error)
+	SetQuota(pid uint32, q Quota) error
+}
+
+// applied is a process whose limits were changed.
+type applied struct {
+	name     string
+	original Quota
+}
+
+// Manager applies a Set to the running processes and remembers their original limits,
+// so that they can be restored when a limit is removed or the application exits.
+// It is safe for concurrent use.
+type Manager struct {
+	system System
+
+	mu      sync.Mutex
+	set     *Set
+	applied map[uint32]applied
+	// failed holds the processes that could not be limited, by PID with their name.
+	// They are not retried, so a protected process reports its error only once.
+	failed map[uint32]string
+}
+
+// NewManager creates a Manager without limits.
+func NewManager(system System) *Manager {
+	return &Manager{system: system, applied: make(map[uint32]applied), failed: make(map[uint32]string)}
+}
+
+// SetLimits replaces the limits. They take effect on the next Apply.
+func (m *Manager) SetLimits(set *Set) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.set = set
+	// A process that failed with the old limits may work with the new ones.
+	m.failed = make(map[uint32]string)
+}
+
+// Applied returns the number of processes whose limits are changed.
+func (m *Manager) Applied() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.applied)
+}
+
+// Apply sets the limits of every matching process, including processes started since the last
+// call and processes that changed their own limits. Processes that no longer match a limit get
+// their original limits back. It returns the processes that could not be limited for the first time.
+func (m *Manager) Apply() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.set.Len() == 0 && len(m.applied) == 0 {
+		return nil
+	}
+	processes, err := m.system.Processes(m.set.NeedsPath())
+	if err != nil {
+		return fmt.Errorf("failed to list processes: %v", err)
+	}
+
+	var errs []error
+	running := make(map[uint32]bool, len(processes))
+	for _, p := range processes {
+		running[p.PID] = true
+		prev, ok := m.applied[p.PID]
+		if ok && prev.name != p.Name {
+			// The PID was reused by another process
+			delete(m.applied, p.PID)
+			ok = false
+		}
+		if name, failed := m.failed[p.PID]; failed && name == p.Name {
+			continue
+		}
+		delete(m.failed, p.PID)
+
+		limit, limited := m.set.Find(p)
+		switch {
+		case limited:
+			if err := m.limit(p, limit, prev, ok); err != nil {
+				m.failed[p.PID] = p.Name
+				errs = append(errs, fmt.Errorf("%s (%d): %v", p.Name, p.PID, err))
+			}
+		case ok:
+			if err := m.system.SetQuota(p.PID, prev.original); err != nil {
+				errs = append(errs, fmt.Errorf("%s (%d): failed to restore limits: %v", p.Name, p.PID, err))
+			}
+			delete(m.applied, p.PID)
+		}
+	}
+
+	// Exited processes need no restore
+	for pid := range m.applied {
+		if !running[pid] {
+			delete(m.applied, pid)
+		}
+	}
+	for pid := range m.failed {
+		if !running[pid] {
+			delete(m.failed, pid)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// limit applies limit to p, reading its original limits the first time.
+func (m *Manager) limit(p procrules.Process, limit Limit, prev applied, ok bool) error {
+	if !ok {
+		original, err := m.system.Quota(p.PID)
+		if err != nil {
+			return fmt.Errorf("failed to read limits: %v", err)
+		}
+		prev = applied{name: p.Name, original: original}
+	}
+	if err := m.system.SetQuota(p.PID, limit.Quota(prev.original)); err != nil {
+		return fmt.Errorf("failed to set limits: %v", err)
+	}
+	m.applied[p.PID] = prev
+	return nil
+}
+
+// Restore gives every limited process its original limits back, e.g. when the application exits.
+func (m *Manager) Restore() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.applied) == 0 {
+		return nil
+	}
+
+	// Skip processes that exited since the last Apply, their PID may have been reused.
+	// If the processes can't be listed, every process is restored.
+	names := make(map[uint32]string)
+	processes, listErr := m.system.Processes(false)
+	for _, p := range processes {
+		names[p.PID] = p.Name
+	}
+
+	var errs []error
+	for pid, a := range m.applied {
+		if name := names[pid]; listErr == nil && name != a.name {
+			delete(m.applied, pid)
+			continue
+		}
+		if err := m.system.SetQuota(pid, a.original); err != nil {
+			errs = append(errs, fmt.Errorf("%s (%d): failed to restore limits: %v", a.name, pid, err))
+		}
+		delete(m.applied, pid)
+	}
+	return errors.Join(errs...)
+}
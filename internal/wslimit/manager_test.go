@@ -0,0 +1,152 @@
+package wslimit
+
+import (
+	"errors"
+	"testing"
+
+	"windows-ram-cleaner/internal/procrules"
+)
+
+var defaultQuota = Quota{Min: 200 * 1024, Max: 1380 * 1024, Flags: HardMinDisable | HardMaxDisable}
+
+type fakeSystem struct {
+	processes []procrules.Process
+	quotas    map[uint32]Quota
+	denied    map[uint32]bool
+	sets      int
+}
+
+func newFakeSystem(processes ...procrules.Process) *fakeSystem {
+	s := &fakeSystem{processes: processes, quotas: make(map[uint32]Quota), denied: make(map[uint32]bool)}
+	for _, p := range processes {
+		s.quotas[p.PID] = defaultQuota
+	}
+	return s
+}
+
+func (s *fakeSystem) Processes(withPath bool) ([]procrules.Process, error) {
+	return s.processes, nil
+}
+
+func (s *fakeSystem) Quota(pid uint32) (Quota, error) {
+	return s.quotas[pid], nil
+}
+
+func (s *fakeSystem) SetQuota(pid uint32, q Quota) error {
+	s.sets++
+	if s.denied[pid] {
+		return errors.New("access denied")
+	}
+	s.quotas[pid] = q
+	return nil
+}
+
+func mustCompile(t *testing.T, limits ...Limit) *Set {
+	t.Helper()
+	set, err := Compile(limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return set
+}
+
+func TestApplyAndRestore(t *testing.T) {
+	system := newFakeSystem(
+		procrules.Process{PID: 10, Name: "chrome.exe"},
+		procrules.Process{PID: 11, Name: "chrome.exe"},
+		procrules.Process{PID: 20, Name: "code.exe"},
+	)
+	m := NewManager(system)
+	m.SetLimits(mustCompile(t, Limit{Name: "chrome.exe", MaxMB: 500, HardMax: true}))
+
+	if err := m.Apply(); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if m.Applied() != 2 || system.quotas[10].Max != 500*mb || system.quotas[20] != defaultQuota {
+		t.Errorf("after Apply() quotas = %+v, applied %d", system.quotas, m.Applied())
+	}
+
+	// A process resetting its own limits gets the limit again
+	system.quotas[10] = defaultQuota
+	if err := m.Apply(); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if system.quotas[10].Max != 500*mb {
+		t.Errorf("limit was not re-applied: %+v", system.quotas[10])
+	}
+
+	if err := m.Restore(); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	for pid, q := range system.quotas {
+		if q != defaultQuota {
+			t.Errorf("process %d was not restored: %+v", pid, q)
+		}
+	}
+	if m.Applied() != 0 {
+		t.Errorf("Applied() = %d after Restore(), want 0", m.Applied())
+	}
+}
+
+func TestRemovedLimitIsRestored(t *testing.T) {
+	system := newFakeSystem(procrules.Process{PID: 10, Name: "chrome.exe"})
+	m := NewManager(system)
+	m.SetLimits(mustCompile(t, Limit{Name: "chrome.exe", MaxMB: 500}))
+	if err := m.Apply(); err != nil {
+		t.Fatal(err)
+	}
+
+	m.SetLimits(nil)
+	if err := m.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if system.quotas[10] != defaultQuota || m.Applied() != 0 {
+		t.Errorf("quota = %+v, applied %d, want the original limits", system.quotas[10], m.Applied())
+	}
+}
+
+func TestExitedAndReusedProcesses(t *testing.T) {
+	system := newFakeSystem(procrules.Process{PID: 10, Name: "chrome.exe"})
+	m := NewManager(system)
+	m.SetLimits(mustCompile(t, Limit{Name: "chrome.exe", MaxMB: 500}))
+	if err := m.Apply(); err != nil {
+		t.Fatal(err)
+	}
+
+	// chrome.exe exited and its PID was reused by a process without a limit
+	system.processes = []procrules.Process{{PID: 10, Name: "notepad.exe"}}
+	system.quotas[10] = defaultQuota
+	if err := m.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Restore(); err != nil {
+		t.Fatal(err)
+	}
+	if system.quotas[10] != defaultQuota || m.Applied() != 0 {
+		t.Errorf("quota = %+v, applied %d", system.quotas[10], m.Applied())
+	}
+}
+
+func TestFailuresAreReportedOnce(t *testing.T) {
+	system := newFakeSystem(procrules.Process{PID: 4, Name: "protected.exe"})
+	system.denied[4] = true
+	m := NewManager(system)
+	m.SetLimits(mustCompile(t, Limit{Name: "*", MaxMB: 500}))
+
+	if err := m.Apply(); err == nil {
+		t.Fatal("Apply() expected an error")
+	}
+	sets := system.sets
+	if err := m.Apply(); err != nil {
+		t.Errorf("second Apply() error = %v, want the failure reported once", err)
+	}
+	if system.sets != sets {
+		t.Errorf("a failed process was retried")
+	}
+
+	// New limits retry failed processes
+	m.SetLimits(mustCompile(t, Limit{Name: "*", MaxMB: 400}))
+	if err := m.Apply(); err == nil {
+		t.Error("Apply() with new limits expected an error")
+	}
+}
@@ -0,0 +1,124 @@
+// Package wslimit Description: This package contains the working set limits of processes: which processes
+// are capped, the limits applied to them and the bookkeeping needed to restore their original limits.
+// The system calls are behind the System interface, so the logic does not depend on WinAPI.
+package wslimit
+
+import (
+	"errors"
+	"fmt"
+
+	"windows-ram-cleaner/internal/procrules"
+)
+
+const mb = 1024 * 1024
+
+// Flags of SetProcessWorkingSetSizeEx.
+const (
+	HardMinEnable  uint32 = 0x1 // QUOTA_LIMITS_HARDWS_MIN_ENABLE
+	HardMinDisable uint32 = 0x2 // QUOTA_LIMITS_HARDWS_MIN_DISABLE
+	HardMaxEnable  uint32 = 0x4 // QUOTA_LIMITS_HARDWS_MAX_ENABLE
+	HardMaxDisable uint32 = 0x8 // QUOTA_LIMITS_HARDWS_MAX_DISABLE
+)
+
+// Limit caps the working set of the processes matching Name and Path.
+type Limit struct {
+	// Name is a case-insensitive glob matched against the executable name, e.g. "chrome.exe".
+	Name string `json:"name,omitempty"`
+	// Path is a case-insensitive glob matched against the full executable path.
+	Path string `json:"path,omitempty"`
+	// MinMB is the minimum working set. Zero keeps the minimum of the process.
+	MinMB uint64 `json:"min_mb,omitempty"`
+	// MaxMB is the maximum working set.
+	MaxMB uint64 `json:"max_mb"`
+	// HardMin and HardMax make the memory manager enforce the limits. Soft limits are only
+	// hints used when memory is low.
+	HardMin bool `json:"hard_min,omitempty"`
+	HardMax bool `json:"hard_max,omitempty"`
+}
+
+// Quota is the working set limits of a process, as used by SetProcessWorkingSetSizeEx.
+type Quota struct {
+	Min   uint64 // Bytes
+	Max   uint64 // Bytes
+	Flags uint32 // HardMin* and HardMax* flags
+}
+
+// Quota returns the limits to apply to a process whose limits were original before.
+func (l Limit) Quota(original Quota) Quota {
+	q := Quota{Min: original.Min, Max: l.MaxMB * mb, Flags: HardMinDisable | HardMaxDisable}
+	if l.MinMB > 0 {
+		q.Min = l.MinMB * mb
+	}
+	// The minimum of the process may be above the cap, the memory manager rejects min > max.
+	if q.Min > q.Max {
+		q.Min = q.Max
+	}
+	if l.HardMin {
+		q.Flags = q.Flags&^HardMinDisable | HardMinEnable
+	}
+	if l.HardMax {
+		q.Flags = q.Flags&^HardMaxDisable | HardMaxEnable
+	}
+	return q
+}
+
+// Set is a compiled list of limits. The first matching limit applies to a process.
+type Set struct {
+	limits   []Limit
+	matchers []*procrules.Matcher
+}
+
+// Compile validates the limits and compiles their conditions. It returns all problems at once.
+func Compile(limits []Limit) (*Set, error) {
+	s := &Set{}
+	var errs []error
+	for i, l := range limits {
+		switch {
+		case l.MaxMB == 0:
+			errs = append(errs, fmt.Errorf("limit %d: max_mb is required", i))
+			continue
+		case l.MinMB >= l.MaxMB:
+			errs = append(errs, fmt.Errorf("limit %d: min_mb must be less than max_mb, got %d", i, l.MinMB))
+			continue
+		}
+		m, err := procrules.NewMatcher(procrules.Rule{Name: l.Name, Path: l.Path})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("limit %d: %w", i, err))
+			continue
+		}
+		s.limits = append(s.limits, l)
+		s.matchers = append(s.matchers, m)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Len returns the number of limits.
+func (s *Set) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.limits)
+}
+
+// Find returns the limit applying to p.
+func (s *Set) Find(p procrules.Process) (Limit, bool) {
+	for i := 0; i < s.Len(); i++ {
+		if s.matchers[i].Match(p) {
+			return s.limits[i], true
+		}
+	}
+	return Limit{}, false
+}
+
+// NeedsPath reports whether any limit looks at the executable path, which is expensive to query.
+func (s *Set) NeedsPath() bool {
+	for i := 0; i < s.Len(); i++ {
+		if s.matchers[i].NeedsPath() {
+			return true
+		}
+	}
+	return false
+}
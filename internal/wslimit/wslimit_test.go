@@ -0,0 +1,103 @@
+package wslimit
+
+import (
+	"testing"
+
+	"windows-ram-cleaner/internal/procrules"
+)
+
+func TestQuota(t *testing.T) {
+	original := Quota{Min: 200 * 1024, Max: 1380 * 1024, Flags: HardMinDisable | HardMaxDisable}
+	tests := []struct {
+		name     string
+		limit    Limit
+		original Quota
+		expected Quota
+	}{
+		{
+			name:     "soft max",
+			limit:    Limit{MaxMB: 500},
+			original: original,
+			expected: Quota{Min: 200 * 1024, Max: 500 * mb, Flags: HardMinDisable | HardMaxDisable},
+		},
+		{
+			name:     "hard min and max",
+			limit:    Limit{MinMB: 100, MaxMB: 500, HardMin: true, HardMax: true},
+			original: original,
+			expected: Quota{Min: 100 * mb, Max: 500 * mb, Flags: HardMinEnable | HardMaxEnable},
+		},
+		{
+			name:     "hard max only",
+			limit:    Limit{MaxMB: 500, HardMax: true},
+			original: original,
+			expected: Quota{Min: 200 * 1024, Max: 500 * mb, Flags: HardMinDisable | HardMaxEnable},
+		},
+		{
+			name:     "minimum above the cap",
+			limit:    Limit{MaxMB: 100},
+			original: Quota{Min: 300 * mb, Max: 400 * mb},
+			expected: Quota{Min: 100 * mb, Max: 100 * mb, Flags: HardMinDisable | HardMaxDisable},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.limit.Quota(tt.original); got != tt.expected {
+				t.Errorf("Quota() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  []Limit
+		wantErr bool
+	}{
+		{name: "valid", limits: []Limit{{Name: "chrome.exe", MaxMB: 500}, {Path: `C:\Apps\*`, MinMB: 10, MaxMB: 50}}},
+		{name: "empty", limits: nil},
+		{name: "missing max", limits: []Limit{{Name: "a.exe"}}, wantErr: true},
+		{name: "min above max", limits: []Limit{{Name: "a.exe", MinMB: 50, MaxMB: 50}}, wantErr: true},
+		{name: "no condition", limits: []Limit{{MaxMB: 50}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Compile(tt.limits); (err != nil) != tt.wantErr {
+				t.Errorf("Compile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	set, err := Compile([]Limit{{Name: "chrome.exe", MaxMB: 500}, {Name: "*.exe", MaxMB: 100}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set.NeedsPath() {
+		t.Error("NeedsPath() = true, want false")
+	}
+
+	tests := []struct {
+		name     string
+		expected uint64
+		found    bool
+	}{
+		{name: "Chrome.exe", expected: 500, found: true},
+		{name: "code.exe", expected: 100, found: true},
+		{name: "System", found: false},
+	}
+	for _, tt := range tests {
+		limit, found := set.Find(procrules.Process{Name: tt.name})
+		if found != tt.found || limit.MaxMB != tt.expected {
+			t.Errorf("Find(%q) = %d, %v, want %d, %v", tt.name, limit.MaxMB, found, tt.expected, tt.found)
+		}
+	}
+
+	var empty *Set
+	if _, found := empty.Find(procrules.Process{Name: "a.exe"}); found || empty.Len() != 0 {
+		t.Error("a nil Set should have no limits")
+	}
+}